@@ -0,0 +1,362 @@
+package main
+
+import "context"
+import "encoding/json"
+import "fmt"
+import "io/ioutil"
+import "net/http"
+import "net/url"
+import "os"
+import "os/exec"
+import "runtime"
+import "strconv"
+import "strings"
+
+//==============================
+// Slack Web API
+//==============================
+
+// common fields of Web API responses
+type SlackResponse struct {
+	Ok       bool
+	Error    string
+	Needed   string // for "missing_scope"
+	Provided string // for "missing_scope"
+}
+
+// error response of Web API
+type SlackApiError struct {
+	Method   string
+	Code     string // "missing_scope", "channel_not_found", etc
+	Needed   string
+	Provided string
+}
+
+func (e *SlackApiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Method, e.Code)
+}
+
+// call Web API method and decode response into result
+//
+// The user token or the bot token in config is used unless query has "token",
+// and the other one is tried if the scope is missing.
+func callApi(method string, query url.Values, result interface{}) error {
+	if len(query.Get("token")) > 0 {
+		_, err := requestApi(method, query, result)
+		return err
+	}
+
+	token := tokenFor(method, g_TokenScopes, g_Config.General.Token, g_Config.General.BotToken)
+	query.Set("token", token)
+	_, err := requestApi(method, query, result)
+	if other := otherToken(token); len(other) > 0 && isApiError(err, "missing_scope") {
+		query.Set("token", other)
+		_, err = requestApi(method, query, result)
+	}
+	return err
+}
+
+// POST query to Web API method, and decode response into result
+func requestApi(method string, query url.Values, result interface{}) (http.Header, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), API_TIMEOUT)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		"https://slack.com/api/"+method,
+		strings.NewReader(query.Encode()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := g_HttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	slackResponse := SlackResponse{}
+	if err := json.Unmarshal(data, &slackResponse); err != nil {
+		return nil, err
+	}
+	if !slackResponse.Ok {
+		return response.Header, &SlackApiError{
+			Method:   method,
+			Code:     slackResponse.Error,
+			Needed:   slackResponse.Needed,
+			Provided: slackResponse.Provided,
+		}
+	}
+
+	if result == nil {
+		return response.Header, nil
+	}
+	return response.Header, json.Unmarshal(data, result)
+}
+
+func isApiError(err error, code string) bool {
+	apiErr, ok := err.(*SlackApiError)
+	return ok && apiErr.Code == code
+}
+
+//==============================
+// Web API methods
+//==============================
+
+type SlackChatPostMessageResponse struct {
+	Ok      bool
+	Channel string
+	Ts      string
+}
+
+// @see https://api.slack.com/methods/conversations.history
+type SlackConversationsHistoryResponse struct {
+	Ok       bool
+	Messages []map[string]interface{}
+	HasMore  bool `json:"has_more"`
+
+	ResponseMetadata SlackResponseMetadata `json:"response_metadata"`
+}
+
+// post text to channel (and thread if threadTs is not empty)
+//
+// Returns ts of the posted message.
+func postMessage(channel string, threadTs string, text string) (string, error) {
+	query := url.Values{}
+	query.Set("channel", channel)
+	query.Set("text", text)
+	if len(threadTs) > 0 {
+		query.Set("thread_ts", threadTs)
+	}
+
+	postResponse := SlackChatPostMessageResponse{}
+	if err := callApi("chat.postMessage", query, &postResponse); err != nil {
+		return "", err
+	}
+
+	return postResponse.Ts, nil
+}
+
+// fetch messages of channel newer than oldest (newest first)
+func fetchHistory(channel string, oldest string, limit int) ([]map[string]interface{}, error) {
+	query := url.Values{}
+	query.Set("channel", channel)
+	if len(oldest) > 0 {
+		query.Set("oldest", oldest)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	historyResponse := SlackConversationsHistoryResponse{}
+	if err := callApi("conversations.history", query, &historyResponse); err != nil {
+		return nil, err
+	}
+
+	return historyResponse.Messages, nil
+}
+
+// fetch all messages of channel posted after oldest by pages of limit (newest first)
+func fetchHistorySince(channel string, oldest string, limit int) ([]map[string]interface{}, error) {
+	messages := []map[string]interface{}{}
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("channel", channel)
+		query.Set("oldest", oldest)
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("cursor", cursor)
+
+		historyResponse := SlackConversationsHistoryResponse{}
+		if err := callApi("conversations.history", query, &historyResponse); err != nil {
+			return messages, err
+		}
+		messages = append(messages, historyResponse.Messages...)
+
+		cursor = historyResponse.ResponseMetadata.NextCursor
+		if !historyResponse.HasMore || len(cursor) == 0 {
+			return messages, nil
+		}
+	}
+}
+
+// fetch a message of channel by ts
+func fetchMessage(channel string, ts string) (Message, error) {
+	query := url.Values{}
+	query.Set("channel", channel)
+	query.Set("latest", ts)
+	query.Set("inclusive", "true")
+	query.Set("limit", "1")
+
+	historyResponse := SlackConversationsHistoryResponse{}
+	if err := callApi("conversations.history", query, &historyResponse); err != nil {
+		return Message{}, err
+	}
+	if len(historyResponse.Messages) == 0 || getTs(historyResponse.Messages[0]) != ts {
+		return Message{}, fmt.Errorf("message not found: %s", ts)
+	}
+
+	msg := historyResponse.Messages[0]
+	msg["channel"] = channel
+	message := newMessage(msg)
+	message.Text = getText(msg)
+	return message, nil
+}
+
+// @see https://api.slack.com/methods/pins.add
+func addPin(channel string, ts string) error {
+	query := url.Values{}
+	query.Set("channel", channel)
+	query.Set("timestamp", ts)
+	return callApi("pins.add", query, nil)
+}
+
+// @see https://api.slack.com/methods/pins.remove
+func removePin(channel string, ts string) error {
+	query := url.Values{}
+	query.Set("channel", channel)
+	query.Set("timestamp", ts)
+	return callApi("pins.remove", query, nil)
+}
+
+// @see https://api.slack.com/methods/pins.list
+type SlackPinsListResponse struct {
+	Ok    bool
+	Items []map[string]interface{}
+}
+
+// @see https://api.slack.com/methods/pins.list
+func fetchPins(channel string) ([]map[string]interface{}, error) {
+	query := url.Values{}
+	query.Set("channel", channel)
+
+	pinsResponse := SlackPinsListResponse{}
+	if err := callApi("pins.list", query, &pinsResponse); err != nil {
+		return nil, err
+	}
+	return pinsResponse.Items, nil
+}
+
+//==============================
+// permissions upgrade
+//==============================
+
+// scopes which the user was already asked to add
+var g_AskedScopes = map[string]struct{}{}
+
+// call fn, and if it fails by missing scope, guide re-authorization and retry once
+//
+// Only for commands, since the prompt blocks the receiving loop.
+func withScopeUpgrade(fn func() error) error {
+	err := fn()
+	if !isApiError(err, "missing_scope") {
+		return err
+	}
+	if !upgradeScope(err.(*SlackApiError)) {
+		return err
+	}
+	return fn()
+}
+
+// guide re-authorization with additional scope
+//
+// Returns true if the new token is given.
+func upgradeScope(apiErr *SlackApiError) bool {
+	if _, asked := g_AskedScopes[apiErr.Needed]; asked {
+		return false
+	}
+	g_AskedScopes[apiErr.Needed] = struct{}{}
+
+	fmt.Fprintf(os.Stderr, "%s needs the scope \"%s\" which the token lacks.\n", apiErr.Method, apiErr.Needed)
+	if len(g_Config.General.ClientId) > 0 {
+		authorizeUrl := makeAuthorizeUrl(apiErr.Provided, apiErr.Needed)
+		fmt.Fprintf(os.Stderr, "Authorize the app again with the scope:\n%s\n", authorizeUrl)
+		if answer := prompt("Open the URL in browser? [y/N] "); answer == "y" || answer == "Y" {
+			if err := openBrowser(authorizeUrl); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, "Add it to \"User Token Scopes\" on https://api.slack.com/apps and reinstall the app.")
+	}
+
+	token := prompt("Paste the new token to retry (empty to skip): ")
+	if len(token) == 0 {
+		return false
+	}
+
+	// keep the current token unless the new one works
+	scopes, err := fetchTokenScopes(token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The token is not valid: %v\n", err)
+		return false
+	}
+	if len(scopes) > 0 && !hasAnyScope(scopes, strings.Split(apiErr.Needed, ",")) {
+		fmt.Fprintf(os.Stderr, "The token lacks the scope \"%s\" too.\n", apiErr.Needed)
+		return false
+	}
+
+	// the stored token is replaced as login does, but the config file can't be
+	if g_Config.General.Token == loadStoredToken() {
+		saveToken(token)
+	} else {
+		fmt.Fprintf(os.Stderr, "Update the token in %s (or %s) to keep using it.\n", g_ConfigPath, TOKEN_ENV)
+	}
+	g_Config.General.Token = token
+	g_TokenScopes.User = scopes
+	return true
+}
+
+// make OAuth URL to authorize with provided scopes and needed scopes
+func makeAuthorizeUrl(provided string, needed string) string {
+	scopes := []string{}
+	for _, scope := range strings.Split(provided+","+needed, ",") {
+		if len(scope) > 0 && !equalsAnyKeywords(scope, scopes) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	query := url.Values{}
+	query.Set("client_id", g_Config.General.ClientId)
+	query.Set("user_scope", strings.Join(scopes, ","))
+	return "https://slack.com/oauth/v2/authorize?" + query.Encode()
+}
+
+// print message, and read a line from stdin
+//
+// Returns empty string if stdin is not available.
+func prompt(message string) string {
+	if g_InputLines == nil {
+		return ""
+	}
+	if g_Tui != nil {
+		g_Tui.SetStatus(message)
+	} else {
+		fmt.Fprint(os.Stderr, message)
+	}
+	line := readInputLine()
+	if g_Tui != nil {
+		g_Tui.DrawPrompt()
+	}
+	return strings.TrimSpace(line)
+}
+
+func openBrowser(target string) error {
+	switch runtime.GOOS {
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", target).Start()
+	case "darwin":
+		return exec.Command("open", target).Start()
+	default:
+		return exec.Command("xdg-open", target).Start()
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "net/http"
+import "net/url"
+import "testing"
+
+func TestMakeAuthorizeUrl(t *testing.T) {
+	g_Config.General.ClientId = "123.456"
+	expected := "https://slack.com/oauth/v2/authorize?client_id=123.456&user_scope=users%3Aread%2Cusergroups%3Aread"
+	result := makeAuthorizeUrl("users:read", "usergroups:read")
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestMakeLoginUrl(t *testing.T) {
+	g_Config = defaultConfig()
+	g_Config.General.ClientId = "1.2"
+
+	result, err := url.Parse(makeLoginUrl("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := result.Query()
+	if query.Get("client_id") != "1.2" || query.Get("state") != "abc" ||
+		query.Get("redirect_uri") != "http://localhost:8976/callback" {
+		t.Errorf("unexpected URL %s\n", result)
+	}
+}
+
+func TestUpgradeScopeWithInvalidToken(t *testing.T) {
+	savedClient, savedConfig, savedLines := g_HttpClient, g_Config, g_InputLines
+	defer func() {
+		g_HttpClient, g_Config, g_InputLines = savedClient, savedConfig, savedLines
+		delete(g_AskedScopes, "pins:read")
+	}()
+
+	g_Config = defaultConfig()
+	g_Config.General.Token = "xoxp-current"
+	g_HttpClient = &http.Client{Transport: apiTransport(func(method string, query url.Values) string {
+		return `{"ok":false,"error":"invalid_auth"}`
+	})}
+	g_InputLines = make(chan string, 1)
+	g_InputLines <- "xoxp-typo"
+
+	if upgradeScope(&SlackApiError{Method: "pins.list", Code: "missing_scope", Needed: "pins:read"}) {
+		t.Errorf("expected the invalid token rejected\n")
+	}
+	if g_Config.General.Token != "xoxp-current" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "xoxp-current", g_Config.General.Token)
+	}
+}
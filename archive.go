@@ -0,0 +1,156 @@
+package main
+
+import "bufio"
+import "encoding/json"
+import "flag"
+import "fmt"
+import "log"
+import "os"
+import "strings"
+import "time"
+
+//==============================
+// local message archive
+//==============================
+
+var g_ArchiveFile *os.File
+
+// append message to the archive
+func archiveMessage(message Message) {
+	if len(g_Config.Archive.Path) == 0 {
+		return
+	}
+
+	if g_ArchiveFile == nil {
+		file, err := os.OpenFile(g_Config.Archive.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		g_ArchiveFile = file
+	}
+
+	data, err := json.Marshal(newJsonlMessage(message))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if _, err := g_ArchiveFile.Write(append(data, '\n')); err != nil {
+		log.Print(err)
+	}
+}
+
+// call fn for each archived message in order until fn returns false
+func readArchive(path string, fn func(JsonlMessage) bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		archived := JsonlMessage{}
+		if err := json.Unmarshal(scanner.Bytes(), &archived); err != nil {
+			// skip broken line (e.g. written while crashing)
+			continue
+		}
+		if !fn(archived) {
+			break
+		}
+	}
+
+	return scanner.Err()
+}
+
+// convert archived message to display
+func (m JsonlMessage) toMessage() Message {
+	return Message{
+		Ts:        m.Ts,
+		ThreadTs:  m.ThreadTs,
+		ChannelId: m.ChannelId,
+		Channel:   m.Channel,
+		UserType:  m.UserType,
+		User:      m.User,
+		Text:      m.Text,
+		Subtype:   m.Subtype,
+		Highlight: m.Highlight,
+	}
+}
+
+//==============================
+// subcommand "search"
+//==============================
+
+// conditions to search the archive
+type ArchiveQuery struct {
+	Words   []string // all words must be contained (case insensitive)
+	Channel string
+	User    string
+	Since   time.Time
+}
+
+func (q ArchiveQuery) Match(m JsonlMessage) bool {
+	if len(q.Channel) > 0 && strings.TrimPrefix(q.Channel, "#") != m.Channel {
+		return false
+	}
+	if len(q.User) > 0 && strings.TrimPrefix(q.User, "@") != m.User {
+		return false
+	}
+	if !q.Since.IsZero() && parseTs(m.Ts).Before(q.Since) {
+		return false
+	}
+	text := strings.ToLower(m.Text)
+	for _, word := range q.Words {
+		if !strings.Contains(text, strings.ToLower(word)) {
+			return false
+		}
+	}
+	return true
+}
+
+// parse "2006-01-02" or duration before now like "48h"
+func parseSince(since string) (time.Time, error) {
+	if date, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return date, nil
+	}
+	duration, err := time.ParseDuration(since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since: %s", since)
+	}
+	return time.Now().Add(-duration), nil
+}
+
+func searchArchive(args []string) error {
+	query := ArchiveQuery{}
+	since := ""
+
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	flags.StringVar(&query.Channel, "channel", "", "search only in the channel")
+	flags.StringVar(&query.User, "user", "", "search only messages from the user")
+	flags.StringVar(&since, "since", "", "search messages since date (2006-01-02) or duration (48h)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: slackv search [options] <query>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if len(g_Config.Archive.Path) == 0 {
+		return fmt.Errorf("[archive] path is not configured")
+	}
+	if len(since) > 0 {
+		var err error
+		if query.Since, err = parseSince(since); err != nil {
+			return err
+		}
+	}
+	query.Words = flags.Args()
+
+	return readArchive(g_Config.Archive.Path, func(archived JsonlMessage) bool {
+		if query.Match(archived) {
+			renderMessage(archived.toMessage())
+		}
+		return true
+	})
+}
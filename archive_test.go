@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestArchiveQueryMatch(t *testing.T) {
+	archived := JsonlMessage{Ts: "1600000000.000100", Channel: "general", User: "alice", Text: "Deploy failed on prod"}
+
+	query := ArchiveQuery{Words: []string{"deploy", "FAILED"}, Channel: "#general"}
+	if !query.Match(archived) {
+		t.Errorf("expected to match %+v\n", query)
+	}
+	query = ArchiveQuery{Words: []string{"deploy"}, User: "@bob"}
+	if query.Match(archived) {
+		t.Errorf("expected not to match %+v\n", query)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import "bufio"
+import "fmt"
+import "log"
+import "os"
+import "strings"
+
+import "slackv/console"
+
+//==============================
+// token in the OS credential store
+//==============================
+
+const KEYCHAIN_SERVICE = "slackv"
+
+// account in the credential store for the profile
+func keychainAccount() string {
+	if len(g_ProfileName) > 0 {
+		return "token-" + g_ProfileName
+	}
+	return "token"
+}
+
+// token saved by "slackv auth store" ("" if none)
+func loadStoredToken() string {
+	token, err := console.LoadSecret(KEYCHAIN_SERVICE, keychainAccount())
+	if err != nil {
+		log.Print(err)
+		return ""
+	}
+	return token
+}
+
+// token from the credential store if not given otherwise
+//
+// Looked up only to connect, not to touch the store for subcommands.
+// configErr of missing config file is returned if no token is found.
+func requireToken(configErr error) error {
+	if len(g_Config.General.Token) == 0 {
+		g_Config.General.Token = loadStoredToken()
+	}
+	if configErr != nil && len(g_Config.General.Token) == 0 {
+		return configErr
+	}
+	return nil
+}
+
+// slackv auth store
+//
+// The token is read from stdin, not from arguments which are visible in ps and shell history.
+func runAuth(args []string) error {
+	if len(args) != 1 || args[0] != "store" {
+		return fmt.Errorf("usage: slackv auth store (and paste the token)")
+	}
+
+	console.Initialize()
+	noEcho := console.DisableEcho() == nil
+	fmt.Fprint(os.Stderr, "Paste the token: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if noEcho {
+		console.DisableRawInput()
+		// the newline is not echoed either
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil && len(line) == 0 {
+		return err
+	}
+	token := strings.TrimSpace(line)
+	if len(token) == 0 {
+		return fmt.Errorf("empty token")
+	}
+
+	if err := console.StoreSecret(KEYCHAIN_SERVICE, keychainAccount(), token); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "The token is stored in the credential store. Remove it from the config file.")
+	return nil
+}
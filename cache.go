@@ -0,0 +1,126 @@
+package main
+
+import "strings"
+import "sync"
+import "time"
+
+//==============================
+// caches shared between goroutines
+//==============================
+
+// not to retry failed lookups (deleted users, restricted channels, etc) on every message
+const NAME_FAILURE_COOLDOWN = 10 * time.Minute
+
+// names of users, channels, user groups, bots, apps and teams keyed by id
+type NameCache struct {
+	mutex    sync.RWMutex
+	names    map[string]cachedName
+	failures map[string]time.Time // failed to look up at
+
+	// names older than this are stale to refresh (0 for never)
+	Ttl time.Duration
+}
+
+type cachedName struct {
+	Name string
+	Time time.Time // cached at
+}
+
+func NewNameCache(names map[string]string) *NameCache {
+	now := time.Now()
+	cache := &NameCache{names: map[string]cachedName{}, failures: map[string]time.Time{}}
+	for id, name := range names {
+		cache.names[id] = cachedName{name, now}
+	}
+	return cache
+}
+
+func (c *NameCache) Get(id string) (string, bool) {
+	name, exist, _ := c.Lookup(id)
+	return name, exist
+}
+
+// name of id, whether cached, and whether older than Ttl
+//
+// Stale name is still returned to display until refreshed.
+func (c *NameCache) Lookup(id string) (string, bool, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	cached, exist := c.names[id]
+	stale := exist && c.Ttl > 0 && time.Since(cached.Time) > c.Ttl
+	return cached.Name, exist, stale
+}
+
+// name of id ("" if not cached)
+func (c *NameCache) Name(id string) string {
+	name, _ := c.Get(id)
+	return name
+}
+
+func (c *NameCache) Set(id string, name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.names[id] = cachedName{name, time.Now()}
+	delete(c.failures, id)
+}
+
+// remember failure of looking up id not to retry for NAME_FAILURE_COOLDOWN
+func (c *NameCache) SetFailed(id string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.failures[id] = time.Now()
+}
+
+// whether looking up id has failed recently
+func (c *NameCache) IsFailing(id string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	failed, exist := c.failures[id]
+	return exist && time.Since(failed) < NAME_FAILURE_COOLDOWN
+}
+
+// reverse lookup for ids starting with any of prefixes
+func (c *NameCache) FindId(name string, prefixes string) (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for id, cached := range c.names {
+		if cached.Name == name && len(id) > 0 && strings.ContainsRune(prefixes, rune(id[0])) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// channel, user and thread of the last displayed message to omit repeated headers
+type HeaderState struct {
+	mutex    sync.Mutex
+	channel  string
+	user     string
+	threadTs string
+}
+
+// whether message needs a channel change (and header) or a header, and remember message
+func (h *HeaderState) Next(message Message) (bool, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	newChannel := message.Channel != h.channel
+	newHeader := newChannel || message.User != h.user || message.ThreadTs != h.threadTs
+	h.channel = message.Channel
+	h.user = message.User
+	h.threadTs = message.ThreadTs
+	return newChannel, newHeader
+}
+
+// display header on next message
+func (h *HeaderState) Reset() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.user = ""
+}
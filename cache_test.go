@@ -0,0 +1,77 @@
+package main
+
+import "fmt"
+import "sync"
+import "testing"
+import "time"
+
+// run with -race
+func TestNameCacheConcurrent(t *testing.T) {
+	cache := NewNameCache(nil)
+	wait := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			id := fmt.Sprintf("U%02d", i)
+			cache.Set(id, fmt.Sprintf("user%d", i))
+			cache.Get(id)
+			cache.FindId("user0", "U")
+		}(i)
+	}
+	wait.Wait()
+
+	if name := cache.Name("U03"); name != "user3" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "user3", name)
+	}
+	if id, exist := cache.FindId("user3", "U"); !exist || id != "U03" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "U03", id)
+	}
+	if _, exist := cache.FindId("user3", "C"); exist {
+		t.Errorf("expected not found for other prefixes\n")
+	}
+}
+
+func TestHeaderState(t *testing.T) {
+	header := &HeaderState{}
+	cases := []struct {
+		message    Message
+		newChannel bool
+		newHeader  bool
+	}{
+		{Message{Channel: "dev", User: "alice"}, true, true},
+		{Message{Channel: "dev", User: "alice"}, false, false},
+		{Message{Channel: "dev", User: "bob"}, false, true},
+		{Message{Channel: "dev", User: "bob", ThreadTs: "1.000001"}, false, true},
+		{Message{Channel: "random", User: "bob", ThreadTs: "1.000001"}, true, true},
+	}
+	for _, c := range cases {
+		newChannel, newHeader := header.Next(c.message)
+		if newChannel != c.newChannel || newHeader != c.newHeader {
+			t.Errorf("%+v: expected %v %v, but %v %v\n", c.message, c.newChannel, c.newHeader, newChannel, newHeader)
+		}
+	}
+
+	header.Reset()
+	if _, newHeader := header.Next(Message{Channel: "random", User: "bob", ThreadTs: "1.000001"}); !newHeader {
+		t.Errorf("expected header after reset\n")
+	}
+}
+
+func TestNameCacheTtl(t *testing.T) {
+	cache := NewNameCache(map[string]string{"U01": "alice"})
+	cache.Ttl = time.Hour
+	if _, exist, stale := cache.Lookup("U01"); !exist || stale {
+		t.Errorf("expected fresh name\n")
+	}
+
+	cache.names["U01"] = cachedName{"alice", time.Now().Add(-2 * time.Hour)}
+	if name, exist, stale := cache.Lookup("U01"); !exist || !stale || name != "alice" {
+		t.Errorf("expected stale \"alice\", but %v %v \"%s\"\n", exist, stale, name)
+	}
+
+	cache.Set("U01", "alice2")
+	if name, _, stale := cache.Lookup("U01"); stale || name != "alice2" {
+		t.Errorf("expected refreshed \"alice2\", but %v \"%s\"\n", stale, name)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import "fmt"
+import "strings"
+import "time"
+
+//==============================
+// calls and huddles
+//==============================
+
+// text of call block or huddle room in msg, and whether found
+//
+// Displays "call started", "call joined: names" or "call ended (duration): names".
+func getCallText(msg map[string]interface{}) (string, bool) {
+	if room, exist := msg["room"].(map[string]interface{}); exist {
+		return formatCall("huddle", room, "participants", "participant_history"), true
+	}
+
+	blocks, _ := msg["blocks"].([]interface{})
+	for _, block := range blocks {
+		block, _ := block.(map[string]interface{})
+		if block["type"] != "call" {
+			continue
+		}
+		call, _ := block["call"].(map[string]interface{})
+		v1, exist := call["v1"].(map[string]interface{})
+		if !exist {
+			continue
+		}
+		return formatCall("call", v1, "active_participants", "all_participants"), true
+	}
+	return "", false
+}
+
+func formatCall(kind string, call map[string]interface{}, activeKey string, allKey string) string {
+	label := kind
+	if name, _ := call["name"].(string); len(name) > 0 {
+		label = label + " \"" + name + "\""
+	}
+
+	dateStart, _ := call["date_start"].(float64)
+	dateEnd, _ := call["date_end"].(float64)
+	hasEnded, _ := call["has_ended"].(bool)
+	if dateEnd > 0 || hasEnded {
+		if dateStart > 0 && dateEnd > dateStart {
+			duration := time.Duration(dateEnd-dateStart) * time.Second
+			label = fmt.Sprintf("%s ended (%s)", label, duration)
+		} else {
+			label = label + " ended"
+		}
+		return formatCallLine(label, callParticipants(call[allKey]))
+	}
+
+	active := callParticipants(call[activeKey])
+	if len(active) == 0 {
+		return formatCallLine(label+" started", nil)
+	}
+	return formatCallLine(label+" joined", active)
+}
+
+func formatCallLine(label string, names []string) string {
+	if len(names) > 0 {
+		label = label + ": " + strings.Join(names, ", ")
+	}
+	return "\033[44m" + label + "\033[0m"
+}
+
+// names of participants by user ids or objects
+func callParticipants(value interface{}) []string {
+	participants, _ := value.([]interface{})
+	names := []string{}
+	for _, participant := range participants {
+		switch participant := participant.(type) {
+		case string:
+			names = append(names, getUser(participant))
+		case map[string]interface{}:
+			if id, exist := participant["slack_id"].(string); exist {
+				names = append(names, getUser(id))
+			} else if name, exist := participant["display_name"].(string); exist {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestGetCallText(t *testing.T) {
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "U02": "bob"})
+
+	call := func(v1 map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"blocks": []interface{}{
+				map[string]interface{}{"type": "call", "call": map[string]interface{}{"v1": v1}},
+			},
+		}
+	}
+
+	cases := []struct {
+		msg      map[string]interface{}
+		expected string
+	}{
+		{
+			call(map[string]interface{}{"date_start": 1600000000.0}),
+			"\033[44mcall started\033[0m",
+		},
+		{
+			call(map[string]interface{}{
+				"date_start":          1600000000.0,
+				"active_participants": []interface{}{map[string]interface{}{"slack_id": "U01"}},
+			}),
+			"\033[44mcall joined: alice\033[0m",
+		},
+		{
+			call(map[string]interface{}{
+				"date_start": 1600000000.0,
+				"date_end":   1600000090.0,
+				"all_participants": []interface{}{
+					map[string]interface{}{"slack_id": "U01"},
+					map[string]interface{}{"external_id": "x", "display_name": "carol"},
+				},
+			}),
+			"\033[44mcall ended (1m30s): alice, carol\033[0m",
+		},
+		{
+			map[string]interface{}{"room": map[string]interface{}{
+				"participants": []interface{}{"U01", "U02"},
+			}},
+			"\033[44mhuddle joined: alice, bob\033[0m",
+		},
+	}
+	for _, c := range cases {
+		result, isCall := getCallText(c.msg)
+		if !isCall || result != c.expected {
+			t.Errorf("expected %q, but %q\n", c.expected, result)
+		}
+	}
+
+	if _, isCall := getCallText(map[string]interface{}{"text": "hi"}); isCall {
+		t.Errorf("expected not call\n")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import "encoding/json"
+import "io/ioutil"
+import "log"
+import "os"
+import "sort"
+import "sync"
+
+//==============================
+// catch-up of missed messages
+//==============================
+
+// persistent state across sessions
+type State struct {
+	LastTs map[string]string `json:"last_ts"` // last seen ts keyed by channel id
+}
+
+var g_State = State{LastTs: map[string]string{}}
+
+// whether g_State is updated after saved
+var g_StateModified = false
+
+// g_State is also saved by the goroutine of exit signals
+var g_StateMutex sync.Mutex
+
+const CATCH_UP_LIMIT = 200
+
+func loadState(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	state := State{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.LastTs == nil {
+		state.LastTs = map[string]string{}
+	}
+	g_State = state
+	return nil
+}
+
+func saveState(path string) error {
+	data, err := json.Marshal(g_State)
+	if err != nil {
+		return err
+	}
+
+	// write atomically not to lose state on crash
+	temp := path + ".tmp"
+	if err := ioutil.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// remember ts of received message
+func updateLastTs(msg map[string]interface{}) {
+	if !g_Config.General.CatchUp {
+		return
+	}
+
+	channel, _ := msg["channel"].(string)
+	ts := getTs(msg)
+	if len(channel) == 0 || len(ts) == 0 {
+		return
+	}
+	if !isTsAfter(ts, g_State.LastTs[channel]) {
+		return
+	}
+
+	// saved by flushState, not to write the file on every message
+	g_StateMutex.Lock()
+	defer g_StateMutex.Unlock()
+	g_State.LastTs[channel] = ts
+	g_StateModified = true
+}
+
+// save state if updated (called periodically and on exit)
+func flushState() {
+	g_StateMutex.Lock()
+	defer g_StateMutex.Unlock()
+	if !g_StateModified {
+		return
+	}
+	if err := saveState(g_Config.General.StateFile); err != nil {
+		log.Print(err)
+		return
+	}
+	g_StateModified = false
+}
+
+// display messages posted after last seen
+//
+// Replies in threads are not fetched since conversations.history returns only top-level messages.
+func catchUp() {
+	if !g_Config.General.CatchUp {
+		return
+	}
+
+	channels := []string{}
+	for channel := range g_State.LastTs {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	for _, channel := range channels {
+		messages, err := fetchHistorySince(channel, g_State.LastTs[channel], CATCH_UP_LIMIT)
+		if err != nil {
+			log.Printf("%s: %s", channel, err)
+			continue
+		}
+
+		// oldest first
+		for i := len(messages) - 1; i >= 0; i-- {
+			msg := messages[i]
+			msg["channel"] = channel
+			onMessage(msg)
+		}
+	}
+}
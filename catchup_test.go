@@ -0,0 +1,84 @@
+package main
+
+import "io/ioutil"
+import "net/http"
+import "net/url"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+// fake Web API replying JSON by method and query
+type apiTransport func(method string, query url.Values) string
+
+func (t apiTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	data, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	query, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil, err
+	}
+	body := t(strings.TrimPrefix(request.URL.Path, "/api/"), query)
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+func TestFetchHistorySince(t *testing.T) {
+	savedClient := g_HttpClient
+	defer func() { g_HttpClient = savedClient }()
+
+	g_HttpClient = &http.Client{Transport: apiTransport(func(method string, query url.Values) string {
+		if query.Get("oldest") != "100.000000" {
+			return `{"ok":false,"error":"invalid_arguments"}`
+		}
+		switch query.Get("cursor") {
+		case "":
+			return `{"ok":true,"messages":[{"ts":"103.000000"},{"ts":"102.000000"}],"has_more":true,"response_metadata":{"next_cursor":"page2"}}`
+		case "page2":
+			return `{"ok":true,"messages":[{"ts":"101.000000"}],"has_more":false}`
+		}
+		return `{"ok":false,"error":"invalid_cursor"}`
+	})}
+
+	messages, err := fetchHistorySince("C01", "100.000000", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tss := []string{}
+	for _, msg := range messages {
+		tss = append(tss, getTs(msg))
+	}
+	if strings.Join(tss, " ") != "103.000000 102.000000 101.000000" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "103.000000 102.000000 101.000000", strings.Join(tss, " "))
+	}
+}
+
+func TestFlushState(t *testing.T) {
+	savedConfig, savedState := g_Config, g_State
+	defer func() { g_Config, g_State, g_StateModified = savedConfig, savedState, false }()
+
+	g_Config = defaultConfig()
+	g_Config.General.CatchUp = true
+	g_Config.General.StateFile = filepath.Join(t.TempDir(), "state.json")
+	g_State = State{LastTs: map[string]string{}}
+
+	// not written on every message
+	updateLastTs(map[string]interface{}{"channel": "C01", "ts": "100.000000"})
+	if _, err := os.Stat(g_Config.General.StateFile); !os.IsNotExist(err) {
+		t.Errorf("expected state not saved yet, but %v\n", err)
+	}
+
+	flushState()
+	g_State = State{}
+	if err := loadState(g_Config.General.StateFile); err != nil {
+		t.Fatal(err)
+	}
+	if g_State.LastTs["C01"] != "100.000000" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "100.000000", g_State.LastTs["C01"])
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import "fmt"
+import "log"
+import "net/url"
+import "regexp"
+import "strings"
+
+//==============================
+// channel management
+//==============================
+
+// @see https://api.slack.com/methods/users.list
+type SlackUsersListResponse struct {
+	Ok               bool
+	Members          []SlackUser
+	ResponseMetadata SlackResponseMetadata `json:"response_metadata"`
+}
+
+// @see https://api.slack.com/methods/conversations.list
+type SlackConversationsListResponse struct {
+	Ok               bool
+	Channels         []SlackChannel
+	ResponseMetadata SlackResponseMetadata `json:"response_metadata"`
+}
+
+// @see https://api.slack.com/methods/conversations.create
+type SlackConversationsCreateResponse struct {
+	Ok      bool
+	Channel SlackChannel
+}
+
+// @see https://api.slack.com/methods/conversations.members
+type SlackConversationsMembersResponse struct {
+	Ok               bool
+	Members          []string
+	ResponseMetadata SlackResponseMetadata `json:"response_metadata"`
+}
+
+type SlackResponseMetadata struct {
+	NextCursor string `json:"next_cursor"`
+}
+
+// friendly messages of errors by conversations.* methods
+var g_ChannelErrorMessages = map[string]string{
+	"already_in_channel": "%s is already in %s",
+	"cant_invite":        "%s cannot be invited to %s",
+	"cant_invite_self":   "%s is yourself, and cannot be invited to %s",
+	"not_in_channel":     "you are not in %[2]s, and cannot invite %[1]s",
+	"user_not_found":     "%[1]s is not found",
+	"channel_not_found":  "%[2]s is not found",
+	"is_archived":        "%[2]s is archived",
+}
+
+func init() {
+	g_Commands["invite"] = Command{"@user... [#channel]", "invite users to the channel", runInvite}
+	g_Commands["create-channel"] = Command{"<name> [--private] [--topic text]", "create a channel", runCreateChannel}
+}
+
+// find user id by name
+//
+// name can be "@name", "name", "<@U01234>" or "U01234".
+func findUserId(name string) (string, error) {
+	if match := g_MentionPattern.FindStringSubmatch(name); match != nil {
+		return match[1], nil
+	}
+	name = strings.TrimPrefix(name, "@")
+	if id, exist := findCachedId(name, "UW"); exist {
+		return id, nil
+	}
+	if _, exist := g_NameCache.Get(name); exist {
+		return name, nil
+	}
+
+	// cache all users, and retry
+	if err := cacheUsersList(); err != nil {
+		return "", err
+	}
+	if id, exist := findCachedId(name, "UW"); exist {
+		return id, nil
+	}
+	return "", fmt.Errorf("@%s is not found", name)
+}
+
+// find channel id by name
+//
+// name can be "#name", "name", "<#C01234>" or "C01234".
+func findChannelId(name string) (string, error) {
+	if match := g_ChannelPattern.FindStringSubmatch(name); match != nil {
+		return match[1], nil
+	}
+	name = strings.TrimPrefix(name, "#")
+	if id, exist := findCachedId(name, "CGD"); exist {
+		return id, nil
+	}
+	if _, exist := g_NameCache.Get(name); exist {
+		return name, nil
+	}
+
+	// cache all channels, and retry
+	if err := cacheConversationsList(); err != nil {
+		return "", err
+	}
+	if id, exist := findCachedId(name, "CGD"); exist {
+		return id, nil
+	}
+	return "", fmt.Errorf("#%s is not found", name)
+}
+
+// reverse lookup of g_NameCache for ids starting with any of prefixes
+func findCachedId(name string, prefixes string) (string, bool) {
+	return g_NameCache.FindId(name, prefixes)
+}
+
+func cacheUsersList() error {
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "1000")
+		query.Set("cursor", cursor)
+
+		usersResponse := SlackUsersListResponse{}
+		if err := callApi("users.list", query, &usersResponse); err != nil {
+			return err
+		}
+
+		for _, user := range usersResponse.Members {
+			if len(user.Profile.DisplayName) > 0 {
+				g_NameCache.Set(user.Id, user.Profile.DisplayName)
+			} else {
+				g_NameCache.Set(user.Id, user.Name)
+			}
+		}
+
+		cursor = usersResponse.ResponseMetadata.NextCursor
+		if len(cursor) == 0 {
+			return nil
+		}
+	}
+}
+
+func cacheConversationsList() error {
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "1000")
+		query.Set("cursor", cursor)
+		query.Set("types", "public_channel,private_channel")
+
+		conversationsResponse := SlackConversationsListResponse{}
+		if err := callApi("conversations.list", query, &conversationsResponse); err != nil {
+			return err
+		}
+
+		for _, channel := range conversationsResponse.Channels {
+			g_NameCache.Set(channel.Id, channel.Name)
+			rememberSharedChannel(channel)
+			rememberChannelTopic(channel)
+		}
+
+		cursor = conversationsResponse.ResponseMetadata.NextCursor
+		if len(cursor) == 0 {
+			return nil
+		}
+	}
+}
+
+// make friendly error of conversations.* methods
+func channelError(err error, user string, channel string) error {
+	if apiErr, ok := err.(*SlackApiError); ok {
+		if format, exist := g_ChannelErrorMessages[apiErr.Code]; exist {
+			return fmt.Errorf(format, user, channel)
+		}
+	}
+	return err
+}
+
+// "DM: alice, bob" for multiparty IM (except yourself)
+//
+// Names are taken from the internal name (mpdm-alice--bob--me-1) if members
+// cannot be fetched.
+func mpimName(channel SlackChannel) string {
+	names := []string{}
+	members, err := fetchMembers(channel.Id)
+	if err == nil {
+		for _, member := range members {
+			if member != g_Self.Id {
+				names = append(names, getUser(member))
+			}
+		}
+	} else {
+		log.Print(err)
+		for _, name := range parseMpimName(channel.Name) {
+			if name != g_Self.Name {
+				names = append(names, name)
+			}
+		}
+	}
+	return "DM: " + strings.Join(names, ", ")
+}
+
+var g_MpimNamePattern = regexp.MustCompile(`^mpdm-(.*)-[0-9]+$`)
+
+// user names in the internal name of multiparty IM
+func parseMpimName(name string) []string {
+	match := g_MpimNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return []string{name}
+	}
+	return strings.Split(match[1], "--")
+}
+
+func fetchMembers(channel string) ([]string, error) {
+	members := []string{}
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("channel", channel)
+		query.Set("cursor", cursor)
+
+		membersResponse := SlackConversationsMembersResponse{}
+		if err := callApi("conversations.members", query, &membersResponse); err != nil {
+			return nil, err
+		}
+		members = append(members, membersResponse.Members...)
+
+		cursor = membersResponse.ResponseMetadata.NextCursor
+		if len(cursor) == 0 {
+			return members, nil
+		}
+	}
+}
+
+//==============================
+// /invite
+//==============================
+
+func runInvite(args []string) error {
+	users := []string{}
+	channel := ""
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "#") || strings.HasPrefix(arg, "<#") {
+			channel = arg
+		} else {
+			users = append(users, arg)
+		}
+	}
+	if len(users) == 0 {
+		return fmt.Errorf("usage: /invite @user... [#channel]")
+	}
+
+	channelId := ""
+	if len(channel) > 0 {
+		var err error
+		if channelId, err = findChannelId(channel); err != nil {
+			return err
+		}
+	} else {
+		// channel of the last message
+		message, err := resolveRef("")
+		if err != nil {
+			return fmt.Errorf("no channel to invite")
+		}
+		channelId = message.ChannelId
+	}
+	channelName := "#" + getChannel(channelId)
+
+	// invite one by one to report errors per user
+	for _, user := range users {
+		userId, err := findUserId(user)
+		if err != nil {
+			return err
+		}
+		userName := "@" + getUser(userId)
+
+		query := url.Values{}
+		query.Set("channel", channelId)
+		query.Set("users", userId)
+		if err := callApi("conversations.invite", query, nil); err != nil {
+			return channelError(err, userName, channelName)
+		}
+		printStatus(fmt.Sprintf("invited %s to %s", userName, channelName))
+	}
+
+	return nil
+}
+
+//==============================
+// /create-channel
+//==============================
+
+func runCreateChannel(args []string) error {
+	name := ""
+	isPrivate := false
+	topic := ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--private":
+			isPrivate = true
+		case "--topic":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--topic needs text")
+			}
+			i++
+			topic = args[i]
+		default:
+			name = strings.TrimPrefix(args[i], "#")
+		}
+	}
+	if len(name) == 0 {
+		return fmt.Errorf("usage: /create-channel <name> [--private] [--topic text]")
+	}
+
+	query := url.Values{}
+	query.Set("name", name)
+	if isPrivate {
+		query.Set("is_private", "true")
+	}
+
+	createResponse := SlackConversationsCreateResponse{}
+	if err := callApi("conversations.create", query, &createResponse); err != nil {
+		if isApiError(err, "name_taken") {
+			return fmt.Errorf("#%s already exists", name)
+		}
+		return err
+	}
+	channel := createResponse.Channel
+
+	// the creator is joined, so display messages of the channel from now on
+	g_NameCache.Set(channel.Id, channel.Name)
+	unmuteChannel(channel.Name)
+	if len(g_Config.Notification.FollowChannels) > 0 {
+		followChannel(channel.Name)
+	}
+	printStatus(fmt.Sprintf("created #%s", channel.Name))
+
+	if len(topic) > 0 {
+		query := url.Values{}
+		query.Set("channel", channel.Id)
+		query.Set("topic", topic)
+		if err := callApi("conversations.setTopic", query, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove channel from mute-channels
+func unmuteChannel(name string) {
+	changeNotification(func(notification *ConfigNotification) {
+		notification.MuteChannels = removeKeyword(notification.MuteChannels, name)
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import "strings"
+import "testing"
+
+func TestChannelError(t *testing.T) {
+	err := &SlackApiError{Method: "conversations.invite", Code: "not_in_channel"}
+	expected := "you are not in #ops, and cannot invite @alice"
+	result := channelError(err, "@alice", "#ops").Error()
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestChannelErrorMessages(t *testing.T) {
+	// user and channel must fit every message (no "%!(EXTRA ...)")
+	for code := range g_ChannelErrorMessages {
+		err := channelError(&SlackApiError{Method: "conversations.invite", Code: code}, "@bob", "#ops")
+		if strings.Contains(err.Error(), "%!") {
+			t.Errorf("%s: unexpected \"%s\"\n", code, err.Error())
+		}
+	}
+
+	expected := "@bob is not found"
+	if err := channelError(&SlackApiError{Code: "user_not_found"}, "@bob", "#ops"); err.Error() != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, err.Error())
+	}
+}
+
+func TestFindUserId(t *testing.T) {
+	g_NameCache = NewNameCache(map[string]string{"C01234": "alice", "U01234": "alice"})
+	for _, name := range []string{"@alice", "alice", "<@U01234>", "U01234"} {
+		id, err := findUserId(name)
+		if err != nil || id != "U01234" {
+			t.Errorf("%s: expected \"U01234\", but \"%s\" (%v)\n", name, id, err)
+		}
+	}
+}
+
+func TestParseMpimName(t *testing.T) {
+	expected := "alice,bob,carol"
+	result := strings.Join(parseMpimName("mpdm-alice--bob--carol-1"), ",")
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
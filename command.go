@@ -0,0 +1,379 @@
+package main
+
+import "bufio"
+import "fmt"
+import "log"
+import "os"
+import "regexp"
+import "sort"
+import "strconv"
+import "strings"
+
+import "slackv/console"
+
+//==============================
+// runtime commands
+//==============================
+
+type Command struct {
+	Usage       string // arguments
+	Description string
+	Run         func(args []string) error
+}
+
+// registered commands keyed by name without "/"
+var g_Commands = map[string]Command{}
+
+// shortcuts typed without "/" and the command names
+var g_KeyCommands = map[string]string{
+	"r": "reply",
+}
+
+// text after the command name as typed, for commands taking free text
+// (args lose quotes and runs of spaces)
+var g_CommandText string
+
+// lines from stdin (nil until startInputReader)
+var g_InputLines chan string
+
+// keys from stdin in raw input mode (nil if reading lines)
+var g_InputKeys chan string
+
+// recently displayed messages (latest last)
+var g_RecentMessages []Message
+
+const MAX_RECENT_MESSAGES = 100
+
+// https://example.slack.com/archives/C01234/p1234567890123456
+var g_PermalinkPattern = regexp.MustCompile(`/archives/([A-Z0-9]+)/p([0-9]{10})([0-9]{6})`)
+
+func init() {
+	g_Commands["help"] = Command{"", "show commands", runHelp}
+	g_Commands["pin"] = Command{"[ref]", "pin the message", runPin}
+	g_Commands["unpin"] = Command{"[ref]", "unpin the message", runUnpin}
+	g_Commands["pins"] = Command{"[#channel]", "list pinned messages of the channel (default: channel in view or of the last message)", runPins}
+	g_Commands["reply"] = Command{"[text]", "reply to the last message (shortcut: r)", runReply}
+	g_Commands["copy"] = Command{"[ref]", "copy text of the message to clipboard", runCopy}
+}
+
+// read lines (or keys for TUI) from stdin in background
+func startInputReader() {
+	g_InputLines = make(chan string)
+
+	if g_UseTui && console.EnableRawInput() == nil {
+		g_InputKeys = make(chan string)
+		go func() {
+			reader := console.NewKeyReader(os.Stdin)
+			for {
+				key, err := reader.ReadKey()
+				if err != nil {
+					break
+				}
+				g_InputKeys <- key
+			}
+			close(g_InputKeys)
+		}()
+		return
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			g_InputLines <- scanner.Text()
+		}
+		close(g_InputLines)
+	}()
+}
+
+// wait for a line entered (empty if stdin is closed)
+func readInputLine() string {
+	if g_InputKeys == nil || g_Tui == nil {
+		return <-g_InputLines
+	}
+	for key := range g_InputKeys {
+		if line, entered := g_Tui.HandleKey(key); entered && !strings.HasPrefix(line, "\033") {
+			return line
+		}
+	}
+	return ""
+}
+
+// run "/command args..."
+func runCommandLine(line string) {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "//") || isSearchLine(line) {
+		// "/pattern" like less
+		line = "search-back " + strings.TrimLeft(line, "/")
+	} else if strings.HasPrefix(line, "/") {
+		line = line[1:]
+	} else {
+		key := strings.SplitN(line, " ", 2)[0]
+		name, exist := g_KeyCommands[key]
+		if !exist {
+			return
+		}
+		line = name + line[len(key):]
+	}
+
+	args := splitCommandLine(line)
+	if len(args) == 0 {
+		return
+	}
+	g_CommandText = commandText(line)
+
+	command, exist := g_Commands[args[0]]
+	if !exist {
+		log.Printf("unknown command: /%s (see /help)", args[0])
+		return
+	}
+
+	err := withScopeUpgrade(func() error { return command.Run(args[1:]) })
+	if err != nil {
+		log.Printf("/%s: %s", args[0], err)
+	}
+}
+
+// line without the command name and following spaces
+func commandText(line string) string {
+	parts := strings.SplitN(line, " ", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimLeft(parts[1], " ")
+}
+
+// whether line is "/pattern" to search scrollback (not "/command")
+func isSearchLine(line string) bool {
+	if g_Tui == nil || !strings.HasPrefix(line, "/") || len(line) < 2 {
+		return false
+	}
+	name := strings.SplitN(line[1:], " ", 2)[0]
+	_, exist := g_Commands[name]
+	return !exist
+}
+
+// split command line by spaces except in double quotes
+func splitCommandLine(line string) []string {
+	args := []string{}
+	arg := strings.Builder{}
+	inArg := false
+	inQuote := false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inArg = true
+		case r == ' ' && !inQuote:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args
+}
+
+// remember displayed message to refer by commands
+func rememberMessage(message Message) {
+	g_RecentMessages = append(g_RecentMessages, message)
+	if len(g_RecentMessages) > MAX_RECENT_MESSAGES {
+		g_RecentMessages = g_RecentMessages[len(g_RecentMessages)-MAX_RECENT_MESSAGES:]
+	}
+}
+
+// find message referred by commands
+//
+// ref is one of:
+//   - "" or "1" for the last displayed message
+//   - "N" for N-th latest displayed message
+//   - permalink of the message
+func resolveRef(ref string) (Message, error) {
+	if match := g_PermalinkPattern.FindStringSubmatch(ref); match != nil {
+		ts := match[2] + "." + match[3]
+		for i := len(g_RecentMessages) - 1; i >= 0; i-- {
+			if g_RecentMessages[i].ChannelId == match[1] && g_RecentMessages[i].Ts == ts {
+				return g_RecentMessages[i], nil
+			}
+		}
+		return Message{ChannelId: match[1], Ts: ts}, nil
+	}
+
+	index := 1
+	if len(ref) > 0 {
+		var err error
+		if index, err = strconv.Atoi(ref); err != nil || index < 1 {
+			return Message{}, fmt.Errorf("invalid message reference: %s", ref)
+		}
+	}
+	if index > len(g_RecentMessages) {
+		return Message{}, fmt.Errorf("no such message: %s", ref)
+	}
+
+	return g_RecentMessages[len(g_RecentMessages)-index], nil
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+//==============================
+// /help
+//==============================
+
+func runHelp(args []string) error {
+	names := []string{}
+	for name := range g_Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		command := g_Commands[name]
+		printStatus(fmt.Sprintf("/%-30s %s", strings.TrimSpace(name+" "+command.Usage), command.Description))
+	}
+	printStatus("ref: N for N-th latest message (default 1), or permalink")
+	return nil
+}
+
+//==============================
+// /pin, /unpin
+//==============================
+
+func runPin(args []string) error {
+	message, err := resolveRef(firstArg(args))
+	if err != nil {
+		return err
+	}
+	if err := addPin(message.ChannelId, message.Ts); err != nil {
+		return err
+	}
+	printStatus("pinned")
+	return nil
+}
+
+func runUnpin(args []string) error {
+	message, err := resolveRef(firstArg(args))
+	if err != nil {
+		return err
+	}
+	if err := removePin(message.ChannelId, message.Ts); err != nil {
+		return err
+	}
+	printStatus("unpinned")
+	return nil
+}
+
+//==============================
+// /pins
+//==============================
+
+func runPins(args []string) error {
+	channelId, err := currentChannelId(firstArg(args))
+	if err != nil {
+		return err
+	}
+
+	items, err := fetchPins(channelId)
+	if err != nil {
+		return err
+	}
+
+	messages := pinnedMessages(channelId, items)
+	for _, message := range messages {
+		renderMessage(message)
+		rememberMessage(message)
+	}
+	printStatus(fmt.Sprintf("%d pinned messages in #%s", len(messages), getChannel(channelId)))
+	return nil
+}
+
+// channel of the argument, the view in TUI or the last message
+func currentChannelId(channel string) (string, error) {
+	if len(channel) > 0 {
+		return findChannelId(channel)
+	}
+	if g_Tui != nil && len(g_Tui.View) > 0 {
+		return findChannelId(g_Tui.View)
+	}
+	message, err := resolveRef("")
+	if err != nil {
+		return "", fmt.Errorf("no channel (specify #channel)")
+	}
+	return message.ChannelId, nil
+}
+
+// messages of pinned items (oldest first)
+func pinnedMessages(channelId string, items []map[string]interface{}) []Message {
+	messages := []Message{}
+	for _, item := range items {
+		msg, exist := item["message"].(map[string]interface{})
+		if !exist {
+			continue
+		}
+		msg["channel"] = channelId
+		message := newMessage(msg)
+		message.User = getUserByMessage(msg)
+		message.Text = unescape(getText(msg))
+		messages = append(messages, message)
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return isTsAfter(messages[j].Ts, messages[i].Ts)
+	})
+	return messages
+}
+
+//==============================
+// /reply
+//==============================
+
+func runReply(args []string) error {
+	message, err := resolveRef("")
+	if err != nil {
+		return err
+	}
+
+	target := "#" + message.Channel
+	if len(message.ThreadTs) > 0 {
+		target = target + " (thread)"
+	}
+
+	text := g_CommandText
+	if len(text) == 0 {
+		text = prompt("reply to " + target + ": ")
+		if len(text) == 0 {
+			printStatus("canceled")
+			return nil
+		}
+	}
+
+	_, err = postMessage(message.ChannelId, message.ThreadTs, text)
+	return err
+}
+
+//==============================
+// /copy
+//==============================
+
+func runCopy(args []string) error {
+	message, err := resolveRef(firstArg(args))
+	if err != nil {
+		return err
+	}
+	if err := console.SetClipboard(stripAnsi(message.Text)); err != nil {
+		return err
+	}
+	printStatus("copied")
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import "net/http"
+import "net/url"
+import "reflect"
+import "testing"
+
+func TestSplitCommandLine(t *testing.T) {
+	expected := []string{"create-channel", "ops", "--topic", "on call rotation"}
+	result := splitCommandLine(`create-channel  ops --topic "on call rotation"`)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
+
+func TestCommandTextKeepsQuotes(t *testing.T) {
+	savedClient := g_HttpClient
+	defer func() { g_HttpClient = savedClient }()
+
+	query := ""
+	g_HttpClient = &http.Client{Transport: apiTransport(func(method string, values url.Values) string {
+		query = values.Get("query")
+		return `{"ok":true,"messages":{"matches":[]}}`
+	})}
+
+	runCommandLine(`/search  "exact  phrase" in:#dev`)
+	if expected := `"exact  phrase" in:#dev`; query != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, query)
+	}
+}
+
+func TestReplyKeepsText(t *testing.T) {
+	savedClient, savedMessages := g_HttpClient, g_RecentMessages
+	defer func() { g_HttpClient, g_RecentMessages = savedClient, savedMessages }()
+
+	text := ""
+	g_HttpClient = &http.Client{Transport: apiTransport(func(method string, values url.Values) string {
+		text = values.Get("text")
+		return `{"ok":true,"channel":"C01","ts":"1600000000.000200"}`
+	})}
+	g_RecentMessages = []Message{{ChannelId: "C01", Channel: "dev", Ts: "1600000000.000100"}}
+
+	runCommandLine(`r say "hi"  twice`)
+	if expected := `say "hi"  twice`; text != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, text)
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	g_RecentMessages = []Message{
+		{ChannelId: "C01234", Ts: "1600000000.000100"},
+		{ChannelId: "C01234", Ts: "1600000000.000200"},
+	}
+
+	message, err := resolveRef("")
+	if err != nil || message.Ts != "1600000000.000200" {
+		t.Errorf("expected latest message, but %+v (%v)\n", message, err)
+	}
+	message, err = resolveRef("2")
+	if err != nil || message.Ts != "1600000000.000100" {
+		t.Errorf("expected 2nd latest message, but %+v (%v)\n", message, err)
+	}
+	message, err = resolveRef("https://example.slack.com/archives/C05678/p1600000000000300")
+	if err != nil || message.ChannelId != "C05678" || message.Ts != "1600000000.000300" {
+		t.Errorf("expected message of permalink, but %+v (%v)\n", message, err)
+	}
+	if _, err = resolveRef("3"); err == nil {
+		t.Errorf("expected error for out of range\n")
+	}
+}
+
+func TestPinnedMessages(t *testing.T) {
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "C01": "dev"})
+
+	items := []map[string]interface{}{
+		{"type": "message", "message": map[string]interface{}{"user": "U01", "ts": "1600000200.000100", "text": "newer"}},
+		{"type": "file", "file": map[string]interface{}{"name": "spec.pdf"}},
+		{"type": "message", "message": map[string]interface{}{"user": "U01", "ts": "1600000100.000100", "text": "&lt;older&gt;"}},
+	}
+	messages := pinnedMessages("C01", items)
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, but %d\n", len(messages))
+	}
+	expected := []string{"<older>", "newer"}
+	for i, message := range messages {
+		if message.Text != expected[i] || message.Channel != "dev" || message.User != "alice" {
+			t.Errorf("expected \"%s\" in #dev by @alice, but %+v\n", expected[i], message)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "strings"
+
+import "github.com/BurntSushi/toml"
+import "gopkg.in/yaml.v3"
+
+//==============================
+// config file formats
+//==============================
+
+// decoder of a config file format
+type ConfigDecoder interface {
+	// decode data into tree of keys and values
+	Decode(data []byte) (map[string]interface{}, error)
+}
+
+// decoders keyed by file extension
+var g_ConfigDecoders = map[string]ConfigDecoder{
+	".toml": TomlConfigDecoder{},
+	".yaml": YamlConfigDecoder{},
+	".yml":  YamlConfigDecoder{},
+	".json": JsonConfigDecoder{},
+}
+
+type TomlConfigDecoder struct{}
+
+func (d TomlConfigDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	_, err := toml.Decode(string(data), &tree)
+	return tree, err
+}
+
+// YAML with the same keys as TOML
+type YamlConfigDecoder struct{}
+
+func (d YamlConfigDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	err := yaml.Unmarshal(data, &tree)
+	return tree, err
+}
+
+// JSON with the same keys as TOML
+type JsonConfigDecoder struct{}
+
+func (d JsonConfigDecoder) Decode(data []byte) (map[string]interface{}, error) {
+	tree := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return convertJsonNumbers(tree).(map[string]interface{}), nil
+}
+
+// json.Number to int64 or float64 by the literal, not to be encoded as TOML float for integer keys
+func convertJsonNumbers(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = convertJsonNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range value {
+			value[i] = convertJsonNumbers(child)
+		}
+	}
+	return value
+}
+
+// decode tree into config by way of TOML to share keys and defaults among formats
+//
+// Keys not in tree are kept.
+func decodeConfigTree(tree map[string]interface{}, config *Config) error {
+	buffer := bytes.Buffer{}
+	if err := toml.NewEncoder(&buffer).Encode(tree); err != nil {
+		return err
+	}
+	_, err := toml.Decode(buffer.String(), config)
+	return err
+}
+
+//==============================
+// profiles
+//==============================
+
+// profile selected by --profile ("" for none)
+var g_ProfileName string
+
+// tree with values of [profile.<name>] over the others
+func selectProfile(tree map[string]interface{}, name string) (map[string]interface{}, error) {
+	profiles, _ := tree["profile"].(map[string]interface{})
+	delete(tree, "profile")
+	if len(name) == 0 {
+		return tree, nil
+	}
+
+	profile, exist := profiles[name].(map[string]interface{})
+	if !exist {
+		return nil, fmt.Errorf("unknown profile: %s", name)
+	}
+	return mergeTree(tree, profile), nil
+}
+
+// merge tables recursively, and replace other values by overlay
+func mergeTree(base map[string]interface{}, overlay map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		baseTable, isBaseTable := merged[key].(map[string]interface{})
+		table, isTable := value.(map[string]interface{})
+		if isBaseTable && isTable {
+			merged[key] = mergeTree(baseTable, table)
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+//==============================
+// config file
+//==============================
+
+// names of config file in order of precedence
+var g_ConfigFileNames = []string{"config.toml", "config.yaml", "config.yml", "config.json"}
+
+// directories to search config file in order of precedence
+//
+// The working directory, $XDG_CONFIG_HOME/slackv and ~/.config/slackv.
+func configDirectories() []string {
+	directories := []string{"."}
+	if xdgConfigHome := os.Getenv("XDG_CONFIG_HOME"); len(xdgConfigHome) > 0 {
+		directories = append(directories, filepath.Join(xdgConfigHome, "slackv"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		directories = append(directories, filepath.Join(home, ".config", "slackv"))
+	}
+	return directories
+}
+
+// path of existing config file in directories (config.toml if none exists)
+func findConfigFile(directories []string) string {
+	for _, directory := range directories {
+		for _, name := range g_ConfigFileNames {
+			path := filepath.Join(directory, name)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return g_ConfigFileNames[0]
+}
+
+// decode config file by the decoder for the extension
+func decodeConfigFile(path string, config *Config) error {
+	decoder, exist := g_ConfigDecoders[strings.ToLower(filepath.Ext(path))]
+	if !exist {
+		return fmt.Errorf("%s: unknown config format (toml, yaml or json)", path)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tree, err := decoder.Decode(data)
+	if err == nil {
+		tree, err = selectProfile(tree, g_ProfileName)
+	}
+	if err == nil {
+		err = decodeConfigTree(tree, config)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestDecodeConfigFile(t *testing.T) {
+	directory := t.TempDir()
+	files := map[string]string{
+		"config.toml": "[general]\ntoken = \"xoxp-1\"\nclient-id = \"1.2\"\n[notification]\npatterns = [\"@alice\"]\n[display]\nmax-message-length = 500\n",
+		"config.yaml": "general:\n  token: xoxp-1\n  client-id: \"1.2\"\nnotification:\n  patterns:\n    - \"@alice\"\ndisplay:\n  max-message-length: 500\n",
+		"config.json": `{"general": {"token": "xoxp-1", "client-id": "1.2"}, "notification": {"patterns": ["@alice"]}, "display": {"max-message-length": 500}}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(directory, name)
+		ioutil.WriteFile(path, []byte(content), 0644)
+
+		config := defaultConfig()
+		if err := decodeConfigFile(path, &config); err != nil {
+			t.Errorf("%s: %v\n", name, err)
+			continue
+		}
+		if config.General.Token != "xoxp-1" || config.General.ClientId != "1.2" ||
+			len(config.Notification.Patterns) != 1 || config.Notification.Patterns[0] != "@alice" ||
+			config.Display.MaxMessageLength != 500 {
+			t.Errorf("%s: unexpected config %+v\n", name, config)
+		}
+		if config.General.StateFile != "state.json" {
+			t.Errorf("%s: expected default \"state.json\", but \"%s\"\n", name, config.General.StateFile)
+		}
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	ioutil.WriteFile(filepath.Join(second, "config.yaml"), []byte(""), 0644)
+
+	expected := filepath.Join(second, "config.yaml")
+	if result := findConfigFile([]string{first, second}); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	ioutil.WriteFile(filepath.Join(first, "config.json"), []byte(""), 0644)
+	expected = filepath.Join(first, "config.json")
+	if result := findConfigFile([]string{first, second}); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestLoadConfigTokenEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	ioutil.WriteFile(path, []byte("[general]\ntoken = \"xoxp-file\"\n"), 0644)
+
+	t.Setenv(TOKEN_ENV, "xoxp-env")
+	if err := loadConfig(path); err != nil || g_Config.General.Token != "xoxp-env" {
+		t.Errorf("expected \"xoxp-env\", but \"%s\" (%v)\n", g_Config.General.Token, err)
+	}
+
+	// config file is optional
+	if err := loadConfig(path + ".missing.toml"); err != nil || g_Config.General.Token != "xoxp-env" {
+		t.Errorf("expected \"xoxp-env\", but \"%s\" (%v)\n", g_Config.General.Token, err)
+	}
+
+	t.Setenv(TOKEN_ENV, "")
+	if err := loadConfig(path); err != nil || g_Config.General.Token != "xoxp-file" {
+		t.Errorf("expected \"xoxp-file\", but \"%s\" (%v)\n", g_Config.General.Token, err)
+	}
+}
+
+func TestLoadConfigWithoutToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	ioutil.WriteFile(path, []byte("[display]\nmax-message-length = 500\n"), 0644)
+	t.Setenv(TOKEN_ENV, "")
+
+	// the credential store is left to requireToken
+	if err := loadConfig(path); err != nil || len(g_Config.General.Token) != 0 {
+		t.Errorf("expected no token, but \"%s\" (%v)\n", g_Config.General.Token, err)
+	}
+	if err := loadConfig(path + ".missing.toml"); !os.IsNotExist(err) || g_HttpClient == nil {
+		t.Errorf("expected config set up and error of missing file, but %v\n", err)
+	}
+}
+
+func TestDecodeConfigProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	ioutil.WriteFile(path, []byte(`[general]
+token = "xoxp-base"
+catch-up = true
+[notification]
+mute-users = ["slackbot"]
+[profile.oss.general]
+token = "xoxp-oss"
+[profile.oss.notification]
+patterns = ["release"]
+`), 0644)
+	defer func() { g_ProfileName = "" }()
+
+	g_ProfileName = "oss"
+	config := defaultConfig()
+	if err := decodeConfigFile(path, &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.General.Token != "xoxp-oss" || !config.General.CatchUp ||
+		len(config.Notification.MuteUsers) != 1 || len(config.Notification.Patterns) != 1 {
+		t.Errorf("unexpected config %+v\n", config)
+	}
+	if config.General.LockFile != "slackv-oss.lock" {
+		t.Errorf("expected \"slackv-oss.lock\", but \"%s\"\n", config.General.LockFile)
+	}
+
+	g_ProfileName = "unknown"
+	if err := decodeConfigFile(path, &config); err == nil {
+		t.Errorf("expected error for unknown profile\n")
+	}
+}
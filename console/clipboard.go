@@ -0,0 +1,35 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import "errors"
+import "os/exec"
+import "runtime"
+import "strings"
+
+// commands to write stdin to clipboard in order of preference
+var g_ClipboardCommands = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+// copy text to system clipboard
+func SetClipboard(text string) error {
+	commands := g_ClipboardCommands
+	if runtime.GOOS == "darwin" {
+		commands = [][]string{{"pbcopy"}}
+	}
+
+	for _, command := range commands {
+		if _, err := exec.LookPath(command[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	return errors.New("no clipboard command (wl-copy, xclip or xsel) is found")
+}
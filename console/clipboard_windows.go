@@ -0,0 +1,62 @@
+package console
+
+import "syscall"
+import "unsafe"
+
+const CF_UNICODETEXT = 13
+const GMEM_MOVEABLE = 0x0002
+
+var g_User32 = syscall.NewLazyDLL("user32")
+var g_OpenClipboard = g_User32.NewProc("OpenClipboard")
+var g_CloseClipboard = g_User32.NewProc("CloseClipboard")
+var g_EmptyClipboard = g_User32.NewProc("EmptyClipboard")
+var g_SetClipboardData = g_User32.NewProc("SetClipboardData")
+
+var g_ClipboardKernel32 = syscall.NewLazyDLL("kernel32")
+var g_GlobalAlloc = g_ClipboardKernel32.NewProc("GlobalAlloc")
+var g_GlobalFree = g_ClipboardKernel32.NewProc("GlobalFree")
+var g_GlobalLock = g_ClipboardKernel32.NewProc("GlobalLock")
+var g_GlobalUnlock = g_ClipboardKernel32.NewProc("GlobalUnlock")
+var g_RtlMoveMemory = g_ClipboardKernel32.NewProc("RtlMoveMemory")
+
+// copy text to system clipboard
+func SetClipboard(text string) error {
+	data, err := syscall.UTF16FromString(text)
+	if err != nil {
+		return err
+	}
+	size := uintptr(len(data) * 2)
+
+	rc, _, err := g_OpenClipboard.Call(0)
+	if rc == 0 {
+		return err
+	}
+	defer g_CloseClipboard.Call()
+
+	rc, _, err = g_EmptyClipboard.Call()
+	if rc == 0 {
+		return err
+	}
+
+	memory, _, err := g_GlobalAlloc.Call(GMEM_MOVEABLE, size)
+	if memory == 0 {
+		return err
+	}
+
+	pointer, _, err := g_GlobalLock.Call(memory)
+	if pointer == 0 {
+		g_GlobalFree.Call(memory)
+		return err
+	}
+	g_RtlMoveMemory.Call(pointer, uintptr(unsafe.Pointer(&data[0])), size)
+	g_GlobalUnlock.Call(memory)
+
+	// the clipboard owns memory on success
+	rc, _, err = g_SetClipboardData.Call(CF_UNICODETEXT, memory)
+	if rc == 0 {
+		g_GlobalFree.Call(memory)
+		return err
+	}
+
+	return nil
+}
@@ -1,10 +1,46 @@
-// +build !windows
+//go:build !windows && !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !windows,!linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
 
 package console
 
+import "errors"
+
 func Initialize() error {
 	return nil
 }
 
 func Finalize() {
 }
+
+// whether ANSI escape sequences can be written
+func SupportsAnsi() bool {
+	return true
+}
+
+// whether stdout is a terminal
+func IsTTY() bool {
+	return false
+}
+
+// terminal size (columns and lines)
+func Size() (int, int, error) {
+	return 0, 0, errors.New("terminal size is not available")
+}
+
+// send to resized when the terminal is resized (never on this platform)
+func NotifyResize(resized chan<- struct{}) {
+}
+
+// read stdin by each key press without echo (not available on this platform)
+func EnableRawInput() error {
+	return errors.New("raw input is not available")
+}
+
+// read lines from stdin without echo (not available on this platform)
+func DisableEcho() error {
+	return errors.New("echo cannot be disabled")
+}
+
+// back to line input
+func DisableRawInput() {
+}
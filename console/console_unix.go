@@ -0,0 +1,119 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package console
+
+import "errors"
+import "os"
+import "os/signal"
+import "syscall"
+import "unsafe"
+
+// terminal state of stdin to restore on exit
+var g_SavedTermios *syscall.Termios
+
+// struct winsize
+type winsize struct {
+	Row    uint16
+	Col    uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+func Initialize() error {
+	termios := syscall.Termios{}
+	if err := ioctl(syscall.Stdin, ioctlGetTermios, unsafe.Pointer(&termios)); err != nil {
+		// not a terminal
+		return nil
+	}
+	g_SavedTermios = &termios
+	return nil
+}
+
+func Finalize() {
+	if g_SavedTermios == nil {
+		return
+	}
+	ioctl(syscall.Stdin, ioctlSetTermios, unsafe.Pointer(g_SavedTermios))
+}
+
+// whether ANSI escape sequences can be written
+func SupportsAnsi() bool {
+	return os.Getenv("TERM") != "dumb"
+}
+
+// whether stdout is a terminal
+func IsTTY() bool {
+	termios := syscall.Termios{}
+	return ioctl(syscall.Stdout, ioctlGetTermios, unsafe.Pointer(&termios)) == nil
+}
+
+// terminal size (columns and lines)
+func Size() (int, int, error) {
+	size := winsize{}
+	err := ioctl(syscall.Stdout, syscall.TIOCGWINSZ, unsafe.Pointer(&size))
+	if err != nil {
+		// stdout may be redirected
+		err = ioctl(syscall.Stdin, syscall.TIOCGWINSZ, unsafe.Pointer(&size))
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(size.Col), int(size.Row), nil
+}
+
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// send to resized when the terminal is resized (SIGWINCH)
+//
+// Sending doesn't block, so resized should be buffered.
+func NotifyResize(resized chan<- struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH)
+
+	go func() {
+		for range signals {
+			select {
+			case resized <- struct{}{}:
+			default:
+			}
+		}
+	}()
+}
+
+// read stdin by each key press without echo (non-canonical mode)
+//
+// Ctrl-C still raises SIGINT. Use KeyReader to read keys.
+func EnableRawInput() error {
+	if g_SavedTermios == nil {
+		return errors.New("stdin is not a terminal")
+	}
+	termios := *g_SavedTermios
+	termios.Lflag &^= syscall.ICANON | syscall.ECHO
+	termios.Cc[syscall.VMIN] = 1
+	termios.Cc[syscall.VTIME] = 0
+	return ioctl(syscall.Stdin, ioctlSetTermios, unsafe.Pointer(&termios))
+}
+
+// read lines from stdin without echo (e.g. secrets)
+//
+// Call DisableRawInput to echo again.
+func DisableEcho() error {
+	if g_SavedTermios == nil {
+		return errors.New("stdin is not a terminal")
+	}
+	termios := *g_SavedTermios
+	termios.Lflag &^= syscall.ECHO
+	return ioctl(syscall.Stdin, ioctlSetTermios, unsafe.Pointer(&termios))
+}
+
+// back to line input
+func DisableRawInput() {
+	Finalize()
+}
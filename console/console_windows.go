@@ -1,6 +1,7 @@
 package console
 
 import "syscall"
+import "time"
 import "unsafe"
 
 const STD_INPUT_HANDLE = uintptr(1) + ^uintptr(10)
@@ -16,21 +17,25 @@ var g_SetConsoleMode *syscall.LazyProc
 var g_Console uintptr
 var g_CurrentMode uintptr
 
-func Initialize() error {
-	const STD_INPUT_HANDLE = uintptr(1) + ^uintptr(10)
-	const STD_OUTPUT_HANDLE = uintptr(1) + ^uintptr(11)
-	const STD_ERROR_HANDLE = uintptr(1) + ^uintptr(12)
-	const ENABLE_VIRTUAL_TERMINAL_PROCESSING uintptr = 0x0004
+// whether the console interprets ANSI escape sequences
+var g_SupportsAnsi bool
 
+// enable ANSI escape sequences (virtual terminal processing)
+//
+// Legacy consoles (before Windows 10) don't support it, and an error is
+// returned. SupportsAnsi tells it.
+func Initialize() error {
 	g_Kernel32 = syscall.NewLazyDLL("kernel32")
 	g_GetStdHandle = g_Kernel32.NewProc("GetStdHandle")
 	g_GetConsoleMode = g_Kernel32.NewProc("GetConsoleMode")
 	g_SetConsoleMode = g_Kernel32.NewProc("SetConsoleMode")
 
-	g_Console, _, _ := g_GetStdHandle.Call(STD_OUTPUT_HANDLE)
+	g_Console, _, _ = g_GetStdHandle.Call(STD_OUTPUT_HANDLE)
 
 	rc, _, err := g_GetConsoleMode.Call(g_Console, uintptr(unsafe.Pointer(&g_CurrentMode)))
 	if rc == 0 {
+		// not a console (redirected), so escape sequences are written as is
+		g_SupportsAnsi = true
 		return err
 	}
 
@@ -39,12 +44,144 @@ func Initialize() error {
 		return err
 	}
 
+	g_SupportsAnsi = true
 	return nil
 }
 
+// whether ANSI escape sequences can be written (valid after Initialize)
+func SupportsAnsi() bool {
+	return g_SupportsAnsi
+}
+
 func Finalize() {
 	if g_SetConsoleMode == nil {
 		return
 	}
 	g_SetConsoleMode.Call(g_Console, g_CurrentMode)
 }
+
+var g_GetConsoleScreenBufferInfo = syscall.NewLazyDLL("kernel32").NewProc("GetConsoleScreenBufferInfo")
+
+// CONSOLE_SCREEN_BUFFER_INFO
+type consoleScreenBufferInfo struct {
+	SizeX              int16
+	SizeY              int16
+	CursorX            int16
+	CursorY            int16
+	Attributes         uint16
+	WindowLeft         int16
+	WindowTop          int16
+	WindowRight        int16
+	WindowBottom       int16
+	MaximumWindowSizeX int16
+	MaximumWindowSizeY int16
+}
+
+func screenBufferInfo() (consoleScreenBufferInfo, error) {
+	info := consoleScreenBufferInfo{}
+	handle, err := syscall.GetStdHandle(syscall.STD_OUTPUT_HANDLE)
+	if err != nil {
+		return info, err
+	}
+	rc, _, err := g_GetConsoleScreenBufferInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&info)))
+	if rc == 0 {
+		return info, err
+	}
+	return info, nil
+}
+
+// whether stdout is a terminal
+func IsTTY() bool {
+	_, err := screenBufferInfo()
+	return err == nil
+}
+
+// terminal size (columns and lines) of the visible window
+func Size() (int, int, error) {
+	info, err := screenBufferInfo()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(info.WindowRight-info.WindowLeft) + 1, int(info.WindowBottom-info.WindowTop) + 1, nil
+}
+
+// interval to check the window size, since resize is notified only to console input
+const RESIZE_POLL_INTERVAL = 500 * time.Millisecond
+
+// send to resized when the console window is resized
+//
+// Sending doesn't block, so resized should be buffered.
+func NotifyResize(resized chan<- struct{}) {
+	go func() {
+		width, height, _ := Size()
+		for range time.Tick(RESIZE_POLL_INTERVAL) {
+			newWidth, newHeight, err := Size()
+			if err != nil || newWidth == width && newHeight == height {
+				continue
+			}
+			width, height = newWidth, newHeight
+			select {
+			case resized <- struct{}{}:
+			default:
+			}
+		}
+	}()
+}
+
+const ENABLE_PROCESSED_INPUT = 0x0001
+const ENABLE_LINE_INPUT = 0x0002
+const ENABLE_ECHO_INPUT = 0x0004
+const ENABLE_VIRTUAL_TERMINAL_INPUT = 0x0200
+
+var g_Input uintptr
+var g_InputMode uintptr
+
+// read stdin by each key press without echo
+//
+// Ctrl-C is still processed. Special keys are read as VT sequences, so use
+// KeyReader as on Unix.
+func EnableRawInput() error {
+	if g_GetConsoleMode == nil {
+		return syscall.EINVAL
+	}
+	g_Input, _, _ = g_GetStdHandle.Call(STD_INPUT_HANDLE)
+
+	rc, _, err := g_GetConsoleMode.Call(g_Input, uintptr(unsafe.Pointer(&g_InputMode)))
+	if rc == 0 {
+		return err
+	}
+	mode := g_InputMode&^(ENABLE_LINE_INPUT|ENABLE_ECHO_INPUT) | ENABLE_PROCESSED_INPUT | ENABLE_VIRTUAL_TERMINAL_INPUT
+	rc, _, err = g_SetConsoleMode.Call(g_Input, mode)
+	if rc == 0 {
+		return err
+	}
+	return nil
+}
+
+// read lines from stdin without echo (e.g. secrets)
+//
+// Call DisableRawInput to echo again.
+func DisableEcho() error {
+	if g_GetConsoleMode == nil {
+		return syscall.EINVAL
+	}
+	g_Input, _, _ = g_GetStdHandle.Call(STD_INPUT_HANDLE)
+
+	rc, _, err := g_GetConsoleMode.Call(g_Input, uintptr(unsafe.Pointer(&g_InputMode)))
+	if rc == 0 {
+		return err
+	}
+	rc, _, err = g_SetConsoleMode.Call(g_Input, g_InputMode&^ENABLE_ECHO_INPUT)
+	if rc == 0 {
+		return err
+	}
+	return nil
+}
+
+// back to line input
+func DisableRawInput() {
+	if g_Input == 0 {
+		return
+	}
+	g_SetConsoleMode.Call(g_Input, g_InputMode)
+}
@@ -0,0 +1,51 @@
+package console
+
+import "bufio"
+import "io"
+import "strings"
+
+// reader of key presses from raw input
+type KeyReader struct {
+	reader *bufio.Reader
+}
+
+func NewKeyReader(reader io.Reader) *KeyReader {
+	return &KeyReader{bufio.NewReader(reader)}
+}
+
+// read a key: a character, or an escape sequence of a special key (e.g. "\033[5~" for PageUp)
+//
+// A lone "\033" is returned for Esc key, which is told from escape sequences
+// by whether the following bytes have arrived together.
+func (k *KeyReader) ReadKey() (string, error) {
+	r, _, err := k.reader.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	if r != '\033' || k.reader.Buffered() == 0 {
+		return string(r), nil
+	}
+
+	next, _ := k.reader.Peek(1)
+	if next[0] != '[' && next[0] != 'O' {
+		// Alt + key
+		return "\033", nil
+	}
+
+	key := strings.Builder{}
+	key.WriteRune(r)
+	key.WriteByte(next[0])
+	k.reader.ReadByte()
+	for k.reader.Buffered() > 0 {
+		b, err := k.reader.ReadByte()
+		if err != nil {
+			return key.String(), nil
+		}
+		key.WriteByte(b)
+		if 0x40 <= b && b <= 0x7e {
+			// final byte
+			break
+		}
+	}
+	return key.String(), nil
+}
@@ -0,0 +1,21 @@
+package console
+
+import "strings"
+import "testing"
+
+func TestReadKey(t *testing.T) {
+	reader := NewKeyReader(strings.NewReader("a\033[5~あ\033OA\r"))
+	expected := []string{"a", "\033[5~", "あ", "\033OA", "\r"}
+	for _, key := range expected {
+		actual, err := reader.ReadKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != key {
+			t.Errorf("expected %q, but %q\n", key, actual)
+		}
+	}
+	if _, err := reader.ReadKey(); err == nil {
+		t.Errorf("expected EOF\n")
+	}
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import "bytes"
+import "errors"
+import "os/exec"
+import "runtime"
+import "strings"
+
+// save secret in the OS credential store (macOS Keychain or libsecret)
+func StoreSecret(service string, account string, secret string) error {
+	if runtime.GOOS == "darwin" {
+		// by a command on stdin of interactive mode, not to show the secret in arguments to ps
+		cmd := exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader("add-generic-password -U -s " + shellQuote(service) + " -a " + shellQuote(account) + " -w " + shellQuote(secret) + "\n")
+		stderr := bytes.Buffer{}
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+		if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+			// interactive mode does not fail by errors of commands
+			return errors.New(message)
+		}
+		return nil
+	}
+
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return errors.New("secret-tool (libsecret) is not found")
+	}
+	cmd := exec.Command("secret-tool", "store", "--label="+service, "service", service, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	return cmd.Run()
+}
+
+// get secret from the OS credential store ("" if not stored)
+func LoadSecret(service string, account string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	} else {
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return "", nil
+		}
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	}
+
+	output, err := cmd.Output()
+	if _, isExitError := err.(*exec.ExitError); isExitError {
+		// not found
+		return "", nil
+	}
+	return strings.TrimSpace(string(output)), err
+}
+
+// 'text' in single quotes for the command line of security -i
+func shellQuote(text string) string {
+	return "'" + strings.Replace(text, "'", `'"'"'`, -1) + "'"
+}
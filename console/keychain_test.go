@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	expected := `'xoxp-1'"'"'; rm -rf ~'`
+	if result := shellQuote("xoxp-1'; rm -rf ~"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
@@ -0,0 +1,82 @@
+package console
+
+import "syscall"
+import "unsafe"
+
+const CRED_TYPE_GENERIC = 1
+const CRED_PERSIST_LOCAL_MACHINE = 2
+const ERROR_NOT_FOUND = 1168
+
+var g_Advapi32 = syscall.NewLazyDLL("advapi32")
+var g_CredWrite = g_Advapi32.NewProc("CredWriteW")
+var g_CredRead = g_Advapi32.NewProc("CredReadW")
+var g_CredFree = g_Advapi32.NewProc("CredFree")
+
+// CREDENTIALW
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// save secret in Windows Credential Manager
+func StoreSecret(service string, account string, secret string) error {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return err
+	}
+	userName, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	blob := []byte(secret)
+
+	cred := credential{
+		Type:               CRED_TYPE_GENERIC,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		Persist:            CRED_PERSIST_LOCAL_MACHINE,
+		UserName:           userName,
+	}
+	if len(blob) > 0 {
+		cred.CredentialBlob = &blob[0]
+	}
+
+	rc, _, err := g_CredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if rc == 0 {
+		return err
+	}
+	return nil
+}
+
+// get secret from Windows Credential Manager ("" if not stored)
+func LoadSecret(service string, account string) (string, error) {
+	target, err := syscall.UTF16PtrFromString(service + ":" + account)
+	if err != nil {
+		return "", err
+	}
+
+	var cred *credential
+	rc, _, err := g_CredRead.Call(uintptr(unsafe.Pointer(target)), CRED_TYPE_GENERIC, 0, uintptr(unsafe.Pointer(&cred)))
+	if rc == 0 {
+		if errno, ok := err.(syscall.Errno); ok && errno == ERROR_NOT_FOUND {
+			return "", nil
+		}
+		return "", err
+	}
+	defer g_CredFree.Call(uintptr(unsafe.Pointer(cred)))
+
+	if cred.CredentialBlobSize == 0 {
+		return "", nil
+	}
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+// +build !windows
+
+package console
+
+import "errors"
+
+var errServiceNotSupported = errors.New("service is available only on Windows (use systemd, launchd, etc)")
+
+// run as Windows service until stopped (blocks)
+func RunService(name string, onStop func()) error {
+	return errServiceNotSupported
+}
+
+// report that the service has stopped after onStop of RunService
+func StopService() {
+}
+
+// register the service started automatically
+func InstallService(name string, displayName string, command string) error {
+	return errServiceNotSupported
+}
+
+func UninstallService(name string) error {
+	return errServiceNotSupported
+}
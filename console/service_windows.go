@@ -0,0 +1,190 @@
+package console
+
+import "syscall"
+import "unsafe"
+
+const SC_MANAGER_ALL_ACCESS = 0xf003f
+const SERVICE_ALL_ACCESS = 0xf01ff
+const SERVICE_WIN32_OWN_PROCESS = 0x10
+const SERVICE_AUTO_START = 2
+const SERVICE_ERROR_NORMAL = 1
+const DELETE = 0x10000
+
+const SERVICE_STOPPED = 1
+const SERVICE_STOP_PENDING = 3
+const SERVICE_RUNNING = 4
+
+const SERVICE_ACCEPT_STOP = 1
+const SERVICE_ACCEPT_SHUTDOWN = 4
+
+const SERVICE_CONTROL_STOP = 1
+const SERVICE_CONTROL_INTERROGATE = 4
+const SERVICE_CONTROL_SHUTDOWN = 5
+
+const ERROR_CALL_NOT_IMPLEMENTED = 120
+
+var g_ServiceAdvapi32 = syscall.NewLazyDLL("advapi32")
+var g_StartServiceCtrlDispatcher = g_ServiceAdvapi32.NewProc("StartServiceCtrlDispatcherW")
+var g_RegisterServiceCtrlHandlerEx = g_ServiceAdvapi32.NewProc("RegisterServiceCtrlHandlerExW")
+var g_SetServiceStatus = g_ServiceAdvapi32.NewProc("SetServiceStatus")
+var g_OpenSCManager = g_ServiceAdvapi32.NewProc("OpenSCManagerW")
+var g_CreateService = g_ServiceAdvapi32.NewProc("CreateServiceW")
+var g_OpenService = g_ServiceAdvapi32.NewProc("OpenServiceW")
+var g_DeleteService = g_ServiceAdvapi32.NewProc("DeleteService")
+var g_CloseServiceHandle = g_ServiceAdvapi32.NewProc("CloseServiceHandle")
+
+// SERVICE_TABLE_ENTRYW
+type serviceTableEntry struct {
+	ServiceName *uint16
+	ServiceProc uintptr
+}
+
+// SERVICE_STATUS
+type serviceStatus struct {
+	ServiceType             uint32
+	CurrentState            uint32
+	ControlsAccepted        uint32
+	Win32ExitCode           uint32
+	ServiceSpecificExitCode uint32
+	CheckPoint              uint32
+	WaitHint                uint32
+}
+
+var g_ServiceName *uint16
+var g_ServiceStatusHandle uintptr
+var g_OnServiceStop func()
+var g_ServiceStopped = make(chan struct{})
+
+// run as Windows service until stopped (blocks)
+//
+// onStop is called by the service control manager to stop, and must call
+// StopService after cleaning up.
+func RunService(name string, onStop func()) error {
+	serviceName, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	g_ServiceName = serviceName
+	g_OnServiceStop = onStop
+
+	table := []serviceTableEntry{
+		{serviceName, syscall.NewCallback(serviceMain)},
+		{nil, 0},
+	}
+	rc, _, err := g_StartServiceCtrlDispatcher.Call(uintptr(unsafe.Pointer(&table[0])))
+	if rc == 0 {
+		return err
+	}
+	return nil
+}
+
+// report that the service has stopped after onStop of RunService
+func StopService() {
+	close(g_ServiceStopped)
+}
+
+// ServiceMain called by the dispatcher in another thread
+func serviceMain(argc uintptr, argv uintptr) uintptr {
+	g_ServiceStatusHandle, _, _ = g_RegisterServiceCtrlHandlerEx.Call(
+		uintptr(unsafe.Pointer(g_ServiceName)),
+		syscall.NewCallback(serviceHandler),
+		0,
+	)
+	setServiceStatus(SERVICE_RUNNING, SERVICE_ACCEPT_STOP|SERVICE_ACCEPT_SHUTDOWN)
+
+	<-g_ServiceStopped
+	setServiceStatus(SERVICE_STOPPED, 0)
+	return 0
+}
+
+// HandlerEx
+func serviceHandler(control uintptr, eventType uintptr, eventData uintptr, context uintptr) uintptr {
+	switch control {
+	case SERVICE_CONTROL_STOP, SERVICE_CONTROL_SHUTDOWN:
+		setServiceStatus(SERVICE_STOP_PENDING, 0)
+		go g_OnServiceStop()
+		return 0
+	case SERVICE_CONTROL_INTERROGATE:
+		return 0
+	}
+	return ERROR_CALL_NOT_IMPLEMENTED
+}
+
+func setServiceStatus(state uint32, accepts uint32) {
+	status := serviceStatus{
+		ServiceType:      SERVICE_WIN32_OWN_PROCESS,
+		CurrentState:     state,
+		ControlsAccepted: accepts,
+	}
+	if state == SERVICE_STOP_PENDING {
+		status.WaitHint = 10000
+	}
+	g_SetServiceStatus.Call(g_ServiceStatusHandle, uintptr(unsafe.Pointer(&status)))
+}
+
+// register the service started automatically
+//
+// command is the full command line to start the service.
+func InstallService(name string, displayName string, command string) error {
+	manager, _, err := g_OpenSCManager.Call(0, 0, SC_MANAGER_ALL_ACCESS)
+	if manager == 0 {
+		return err
+	}
+	defer g_CloseServiceHandle.Call(manager)
+
+	serviceName, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	serviceDisplayName, err := syscall.UTF16PtrFromString(displayName)
+	if err != nil {
+		return err
+	}
+	binaryPath, err := syscall.UTF16PtrFromString(command)
+	if err != nil {
+		return err
+	}
+
+	service, _, err := g_CreateService.Call(
+		manager,
+		uintptr(unsafe.Pointer(serviceName)),
+		uintptr(unsafe.Pointer(serviceDisplayName)),
+		SERVICE_ALL_ACCESS,
+		SERVICE_WIN32_OWN_PROCESS,
+		SERVICE_AUTO_START,
+		SERVICE_ERROR_NORMAL,
+		uintptr(unsafe.Pointer(binaryPath)),
+		0, 0, 0,
+		0, // LocalSystem
+		0,
+	)
+	if service == 0 {
+		return err
+	}
+	g_CloseServiceHandle.Call(service)
+	return nil
+}
+
+func UninstallService(name string) error {
+	manager, _, err := g_OpenSCManager.Call(0, 0, SC_MANAGER_ALL_ACCESS)
+	if manager == 0 {
+		return err
+	}
+	defer g_CloseServiceHandle.Call(manager)
+
+	serviceName, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	service, _, err := g_OpenService.Call(manager, uintptr(unsafe.Pointer(serviceName)), DELETE)
+	if service == 0 {
+		return err
+	}
+	defer g_CloseServiceHandle.Call(service)
+
+	rc, _, err := g_DeleteService.Call(service)
+	if rc == 0 {
+		return err
+	}
+	return nil
+}
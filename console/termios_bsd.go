@@ -0,0 +1,9 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+// +build darwin freebsd netbsd openbsd dragonfly
+
+package console
+
+import "syscall"
+
+const ioctlGetTermios = syscall.TIOCGETA
+const ioctlSetTermios = syscall.TIOCSETA
@@ -0,0 +1,6 @@
+package console
+
+import "syscall"
+
+const ioctlGetTermios = syscall.TCGETS
+const ioctlSetTermios = syscall.TCSETS
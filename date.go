@@ -0,0 +1,122 @@
+package main
+
+import "fmt"
+import "regexp"
+import "strconv"
+import "strings"
+import "time"
+
+//==============================
+// date formatting tokens
+//==============================
+
+// <!date^1392734382^Posted {date_num} {time_secs}^optional-link|fallback>
+//
+// @see https://api.slack.com/reference/surfaces/formatting#date-formatting
+var g_DatePattern = regexp.MustCompile(`<!date\^([0-9]+)\^([^^|>]*)(\^[^|>]*)?(\|([^>]*))?>`)
+
+var g_DateTokenPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// replace date tokens with local time
+func unescapeDates(text string, now time.Time) string {
+	return g_DatePattern.ReplaceAllStringFunc(text, func(token string) string {
+		match := g_DatePattern.FindStringSubmatch(token)
+		epoch, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return match[5]
+		}
+		return formatSlackDate(time.Unix(epoch, 0).In(now.Location()), match[2], now, match[5])
+	})
+}
+
+// expand {date_num}, {time}, etc in format
+//
+// fallback is used if format has unknown tokens.
+func formatSlackDate(t time.Time, format string, now time.Time, fallback string) string {
+	unknown := false
+	result := g_DateTokenPattern.ReplaceAllStringFunc(format, func(token string) string {
+		switch token {
+		case "{date_num}":
+			return t.Format("2006-01-02")
+		case "{date}":
+			return t.Format("January ") + ordinal(t.Day()) + t.Format(", 2006")
+		case "{date_short}":
+			return t.Format("Jan 2, 2006")
+		case "{date_long}":
+			return t.Format("Monday, January ") + ordinal(t.Day()) + t.Format(", 2006")
+		case "{date_pretty}", "{date_short_pretty}", "{date_long_pretty}":
+			if day := relativeDay(t, now); len(day) > 0 {
+				return day
+			}
+			return formatSlackDate(t, strings.Replace(token, "_pretty", "", 1), now, fallback)
+		case "{time}":
+			return t.Format("3:04 PM")
+		case "{time_secs}":
+			return t.Format("3:04:05 PM")
+		case "{ago}":
+			return formatAgo(now.Sub(t))
+		}
+		unknown = true
+		return token
+	})
+
+	if unknown && len(fallback) > 0 {
+		return fallback
+	}
+	return result
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || 13 < n%100 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// "today", "yesterday" or "tomorrow" (or "" for other days)
+func relativeDay(t time.Time, now time.Time) string {
+	day := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+	switch day(t).Sub(day(now)).Round(time.Hour) / (24 * time.Hour) {
+	case 0:
+		return "today"
+	case -1:
+		return "yesterday"
+	case 1:
+		return "tomorrow"
+	}
+	return ""
+}
+
+func formatAgo(d time.Duration) string {
+	suffix := " ago"
+	if d < 0 {
+		d = -d
+		suffix = " from now"
+	}
+
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s%s", name, suffix)
+		}
+		return fmt.Sprintf("%d %ss%s", n, name, suffix)
+	}
+	switch {
+	case d < time.Minute:
+		return unit(int(d/time.Second), "second")
+	case d < time.Hour:
+		return unit(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return unit(int(d/time.Hour), "hour")
+	}
+	return unit(int(d/(24*time.Hour)), "day")
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+import "time"
+
+func TestUnescapeDates(t *testing.T) {
+	location := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2014, 2, 19, 12, 0, 0, 0, location)
+
+	cases := map[string]string{
+		"<!date^1392734382^Posted {date_num} {time_secs}|Posted 2014-02-18 6:39:42 AM PST>": "Posted 2014-02-18 11:39:42 PM",
+		"<!date^1392734382^{date}|x>":                           "February 18th, 2014",
+		"<!date^1392734382^{date_short}^https://example.com|x>": "Feb 18, 2014",
+		"<!date^1392734382^{date_long_pretty}|x>":               "yesterday",
+		"<!date^1392734382^{date_long}|x>":                      "Tuesday, February 18th, 2014",
+		"<!date^1392734382^{ago}|x>":                            "12 hours ago",
+		"<!date^1392734382^{unknown}|fallback>":                 "fallback",
+	}
+	for source, expected := range cases {
+		if result := unescapeDates(source, now); result != expected {
+			t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+		}
+	}
+}
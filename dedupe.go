@@ -0,0 +1,121 @@
+package main
+
+import "fmt"
+import "time"
+
+//==============================
+// suppression of repeated bot messages
+//==============================
+
+// identical messages from a bot in a row
+type RepeatRun struct {
+	Key        string // bot, thread and text
+	Channel    string
+	User       string
+	Count      int // including the displayed ones
+	Suppressed int
+	First      time.Time
+	Last       time.Time
+}
+
+// runs of repeated messages by channel id
+type Deduplicator struct {
+	runs map[string]*RepeatRun
+}
+
+var g_Deduplicator = NewDeduplicator()
+
+func NewDeduplicator() *Deduplicator {
+	return &Deduplicator{runs: map[string]*RepeatRun{}}
+}
+
+// rule of [dedupe] for the channel, overridden by [dedupe.channels.<name>]
+func dedupeRule(channel string) DedupeRule {
+	rule := DedupeRule{Threshold: g_Config.Dedupe.Threshold, Window: g_Config.Dedupe.Window}
+	if override, exist := g_Config.Dedupe.Channels[channel]; exist {
+		if override.Threshold != 0 {
+			rule.Threshold = override.Threshold
+		}
+		if override.Window != 0 {
+			rule.Window = override.Window
+		}
+	}
+	return rule
+}
+
+// whether to hide message as a repetition, and the run ended by message if any was hidden
+func (d *Deduplicator) Check(message Message, rule DedupeRule) (bool, *RepeatRun) {
+	if message.Subtype == "message_changed" {
+		return false, nil
+	}
+
+	key := message.UserType + message.User + "\x00" + message.ThreadTs + "\x00" + message.Text
+	at := parseTs(message.Ts)
+	run := d.runs[message.ChannelId]
+	if run != nil && run.Key == key && at.Sub(run.Last) <= time.Duration(rule.Window)*time.Second {
+		run.Count++
+		run.Last = at
+		if rule.Threshold > 0 && run.Count > rule.Threshold {
+			run.Suppressed++
+			return true, nil
+		}
+		return false, nil
+	}
+
+	delete(d.runs, message.ChannelId)
+	if rule.Threshold > 0 && len(message.UserType) > 0 {
+		// only bots flap
+		d.runs[message.ChannelId] = &RepeatRun{Key: key, Channel: message.Channel, User: message.User, Count: 1, First: at, Last: at}
+	}
+	if run != nil && run.Suppressed > 0 {
+		return false, run
+	}
+	return false, nil
+}
+
+// remove runs not repeated within the window, and return ones which hid any message
+func (d *Deduplicator) Expire(now time.Time, rule func(channel string) DedupeRule) []*RepeatRun {
+	expired := []*RepeatRun{}
+	for channelId, run := range d.runs {
+		if now.Sub(run.Last) > time.Duration(rule(run.Channel).Window)*time.Second {
+			delete(d.runs, channelId)
+			if run.Suppressed > 0 {
+				expired = append(expired, run)
+			}
+		}
+	}
+	return expired
+}
+
+// "#alerts @monitor …repeated 7× over 3m"
+func formatRepeatRun(run *RepeatRun) string {
+	span := run.Last.Sub(run.First).Round(time.Second)
+	return fmt.Sprintf("#%s @%s …repeated %d× over %s", run.Channel, run.User, run.Suppressed, formatSpan(span))
+}
+
+// "45s", "3m" or "2h"
+func formatSpan(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+	return fmt.Sprintf("%dh", int(d/time.Hour))
+}
+
+// hide repetitions of message, and report the end of repetitions before it
+func isRepeated(message Message) bool {
+	suppressed, ended := g_Deduplicator.Check(message, dedupeRule(message.Channel))
+	if ended != nil {
+		printStatus(formatRepeatRun(ended))
+	}
+	return suppressed
+}
+
+// report repetitions which stopped
+func flushRepeats(now time.Time) {
+	for _, run := range g_Deduplicator.Expire(now, dedupeRule) {
+		printStatus(formatRepeatRun(run))
+	}
+}
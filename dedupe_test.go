@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+import "time"
+
+func TestDeduplicator(t *testing.T) {
+	d := NewDeduplicator()
+	rule := DedupeRule{Threshold: 1, Window: 600}
+	alert := Message{ChannelId: "C01", Channel: "alerts", UserType: "[bot]", User: "monitor", Text: "disk full"}
+
+	expected := []bool{false, true, true}
+	for i, ts := range []string{"1600000000.000100", "1600000060.000100", "1600000180.000100"} {
+		alert.Ts = ts
+		if suppressed, _ := d.Check(alert, rule); suppressed != expected[i] {
+			t.Errorf("%d: expected %v, but %v\n", i, expected[i], suppressed)
+		}
+	}
+
+	suppressed, ended := d.Check(Message{ChannelId: "C01", Channel: "alerts", User: "alice", Ts: "1600000200.000100", Text: "fixed"}, rule)
+	if suppressed || ended == nil {
+		t.Fatalf("expected the run ended\n")
+	}
+	expectedLine := "#alerts @monitor …repeated 2× over 3m"
+	if line := formatRepeatRun(ended); line != expectedLine {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expectedLine, line)
+	}
+}
+
+func TestDeduplicatorExpire(t *testing.T) {
+	d := NewDeduplicator()
+	rule := DedupeRule{Threshold: 1, Window: 60}
+	alert := Message{ChannelId: "C01", Channel: "alerts", UserType: "[bot]", User: "monitor", Text: "disk full", Ts: "1600000000.000100"}
+	d.Check(alert, rule)
+
+	// not repeated within the window
+	alert.Ts = "1600000100.000100"
+	if suppressed, _ := d.Check(alert, rule); suppressed {
+		t.Errorf("expected displayed after the window\n")
+	}
+	alert.Ts = "1600000110.000100"
+	if suppressed, _ := d.Check(alert, rule); !suppressed {
+		t.Errorf("expected suppressed within the window\n")
+	}
+
+	expired := d.Expire(parseTs("1600000180.000100"), func(channel string) DedupeRule { return rule })
+	if len(expired) != 1 || expired[0].Suppressed != 1 {
+		t.Errorf("unexpected expired runs %v\n", expired)
+	}
+	if len(d.runs) != 0 {
+		t.Errorf("expected no run left\n")
+	}
+}
+
+func TestFormatSpan(t *testing.T) {
+	for d, expected := range map[time.Duration]string{45 * time.Second: "45s", 3 * time.Minute: "3m", 2 * time.Hour: "2h"} {
+		if result := formatSpan(d); result != expected {
+			t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+		}
+	}
+}
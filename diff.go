@@ -0,0 +1,105 @@
+package main
+
+import "regexp"
+import "strings"
+
+//==============================
+// word diff of edited messages
+//==============================
+
+// words and spaces between them
+var g_WordPattern = regexp.MustCompile(`\s+|[^\s]+`)
+
+// max number of words to compare (the diff takes words^2)
+const MAX_DIFF_WORDS = 2000
+
+// markers of changes where styles are stripped (no-color, screen reader, legacy console)
+const (
+	DIFF_DELETED_START  = "[-"
+	DIFF_DELETED_END    = "-]"
+	DIFF_INSERTED_START = "{+"
+	DIFF_INSERTED_END   = "+}"
+)
+
+// new text with deleted words struck through and inserted words underlined
+//
+// If not styled, runs of changed words are enclosed in [-deleted-] and {+inserted+} instead.
+func wordDiff(oldText string, newText string, styled bool) string {
+	oldWords := g_WordPattern.FindAllString(oldText, -1)
+	newWords := g_WordPattern.FindAllString(newText, -1)
+	if len(oldWords) > MAX_DIFF_WORDS || len(newWords) > MAX_DIFF_WORDS {
+		return newText
+	}
+
+	// lengths of longest common subsequences of oldWords[i:] and newWords[j:]
+	lcs := make([][]int, len(oldWords)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newWords)+1)
+	}
+	for i := len(oldWords) - 1; i >= 0; i-- {
+		for j := len(newWords) - 1; j >= 0; j-- {
+			if oldWords[i] == newWords[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	builder := strings.Builder{}
+	// kind of the run of changes not closed yet (only if not styled)
+	open := ""
+	closeRun := func() {
+		switch open {
+		case DIFF_DELETED_START:
+			builder.WriteString(DIFF_DELETED_END)
+		case DIFF_INSERTED_START:
+			builder.WriteString(DIFF_INSERTED_END)
+		}
+		open = ""
+	}
+	changed := func(word string, style string, start string) {
+		if styled {
+			builder.WriteString(style + word + "\033[0m")
+			return
+		}
+		if open != start {
+			closeRun()
+			builder.WriteString(start)
+			open = start
+		}
+		builder.WriteString(word)
+	}
+	deleted := func(word string) {
+		changed(word, "\033[9;31m", DIFF_DELETED_START)
+	}
+	inserted := func(word string) {
+		changed(word, "\033[4;32m", DIFF_INSERTED_START)
+	}
+	i, j := 0, 0
+	for i < len(oldWords) && j < len(newWords) {
+		switch {
+		case oldWords[i] == newWords[j]:
+			closeRun()
+			builder.WriteString(newWords[j])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			deleted(oldWords[i])
+			i++
+		default:
+			inserted(newWords[j])
+			j++
+		}
+	}
+	for ; i < len(oldWords); i++ {
+		deleted(oldWords[i])
+	}
+	for ; j < len(newWords); j++ {
+		inserted(newWords[j])
+	}
+	closeRun()
+	return builder.String()
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestWordDiff(t *testing.T) {
+	expected := "deploy \033[9;31mstarted\033[0m\033[4;32mfinished\033[0m at 10:00\033[4;32m \033[0m\033[4;32m:tada:\033[0m"
+	result := wordDiff("deploy started at 10:00", "deploy finished at 10:00 :tada:", true)
+	if result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
+
+func TestWordDiffText(t *testing.T) {
+	expected := "deploy [-started-]{+finished+} at 10:00{+ :tada:+}"
+	result := wordDiff("deploy started at 10:00", "deploy finished at 10:00 :tada:", false)
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	expected = "[-old words -]kept"
+	result = wordDiff("old words kept", "kept", false)
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import "fmt"
+import "log"
+import "net"
+import "net/smtp"
+import "strconv"
+import "strings"
+import "time"
+
+//==============================
+// email digest
+//==============================
+
+// highlights and DMs collected since the last digest
+type Digest struct {
+	Messages []Message
+	Since    time.Time
+	Sending  bool      // reset after sent in background
+	RetryAt  time.Time // not to retry on every tick after failed
+}
+
+// wait after failing to send digest
+const DIGEST_RETRY_INTERVAL = 10 * time.Minute
+
+var g_Digest = Digest{Since: time.Now()}
+
+// whether message is worth reading later
+func isDigestMessage(message Message) bool {
+	return message.Highlight || isMention(message) || isDirectMessage(message)
+}
+
+// collect message for the next digest if enabled
+func collectDigest(message Message) {
+	if g_Config.Digest.Hours <= 0 || len(g_Config.Digest.SmtpHost) == 0 {
+		return
+	}
+	if message.Subtype == "message_changed" || !isDigestMessage(message) {
+		return
+	}
+	message.Text = stripAnsi(message.Text)
+	g_Digest.Messages = append(g_Digest.Messages, message)
+}
+
+// send digest in background every [digest] hours
+func sendDigestIfDue(now time.Time) {
+	config := g_Config.Digest
+	if config.Hours <= 0 || len(config.SmtpHost) == 0 {
+		return
+	}
+	if g_Digest.Sending || now.Before(g_Digest.RetryAt) {
+		return
+	}
+	if now.Sub(g_Digest.Since) < time.Duration(config.Hours)*time.Hour {
+		return
+	}
+	if len(g_Digest.Messages) == 0 {
+		g_Digest = Digest{Since: now}
+		return
+	}
+
+	digest := g_Digest
+	g_Digest.Sending = true
+	go func() {
+		err := sendMail(config, formatDigestMail(config, digest, now))
+		// g_Digest is updated only by the receiving loop
+		g_Resolver.Resolved <- func() {
+			finishDigest(len(digest.Messages), now, err)
+		}
+	}()
+}
+
+// remove sent messages from digest, or keep them to retry later
+func finishDigest(sent int, now time.Time, err error) {
+	g_Digest.Sending = false
+	if err != nil {
+		log.Printf("failed to send digest: %v", err)
+		g_Digest.RetryAt = now.Add(DIGEST_RETRY_INTERVAL)
+		return
+	}
+
+	// messages collected while sending are for the next digest
+	g_Digest = Digest{Messages: g_Digest.Messages[sent:], Since: now}
+}
+
+// mail of digest including headers
+func formatDigestMail(config ConfigDigest, digest Digest, now time.Time) string {
+	var body strings.Builder
+	for _, message := range digest.Messages {
+		timestamp := parseTs(message.Ts).Format("2006/01/02 15:04")
+		text := strings.ReplaceAll(message.Text, "\n", "\n    ")
+		fmt.Fprintf(&body, "%s #%s @%s\n    %s\n\n", timestamp, message.Channel, message.User, text)
+	}
+
+	subject := fmt.Sprintf("slackv digest: %d messages since %s", len(digest.Messages), digest.Since.Format("2006/01/02 15:04"))
+	headers := []string{
+		"From: " + config.From,
+		"To: " + strings.Join(config.To, ", "),
+		"Subject: " + subject,
+		"Date: " + now.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: 8bit",
+	}
+	// SMTP requires CRLF
+	mail := strings.Join(headers, "\r\n") + "\r\n\r\n" + body.String()
+	return strings.ReplaceAll(strings.ReplaceAll(mail, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// send mail by SMTP with STARTTLS if supported by the server
+func sendMail(config ConfigDigest, mail string) error {
+	if len(config.To) == 0 {
+		return fmt.Errorf("[digest] to is not configured")
+	}
+
+	var auth smtp.Auth
+	if len(config.Username) > 0 {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.SmtpHost)
+	}
+	address := net.JoinHostPort(config.SmtpHost, strconv.Itoa(config.SmtpPort))
+	return smtp.SendMail(address, auth, config.From, config.To, []byte(mail))
+}
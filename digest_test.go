@@ -0,0 +1,63 @@
+package main
+
+import "errors"
+import "strings"
+import "testing"
+import "time"
+
+func TestFormatDigestMail(t *testing.T) {
+	config := ConfigDigest{From: "slackv@example.com", To: []string{"alice@example.com", "bob@example.com"}}
+	since := time.Date(2020, 9, 13, 9, 0, 0, 0, time.Local)
+	digest := Digest{
+		Since: since,
+		Messages: []Message{
+			{Ts: "1600000000.000100", Channel: "general", User: "carol", Text: "deploy failed\nplease check"},
+		},
+	}
+
+	mail := formatDigestMail(config, digest, since.Add(8*time.Hour))
+	for _, expected := range []string{
+		"To: alice@example.com, bob@example.com\r\n",
+		"Subject: slackv digest: 1 messages since 2020/09/13 09:00\r\n",
+		"#general @carol\r\n    deploy failed\r\n    please check\r\n",
+	} {
+		if !strings.Contains(mail, expected) {
+			t.Errorf("expected \"%s\" in \"%s\"\n", expected, mail)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(mail, "\r\n", ""), "\n") {
+		t.Errorf("expected only CRLF in \"%s\"\n", mail)
+	}
+}
+
+func TestIsDigestMessage(t *testing.T) {
+	if !isDigestMessage(Message{ChannelId: "D01", Text: "hi"}) {
+		t.Errorf("expected DM to be collected\n")
+	}
+	if !isDigestMessage(Message{ChannelId: "C01", Text: "deploy", Highlight: true}) {
+		t.Errorf("expected highlight to be collected\n")
+	}
+	if isDigestMessage(Message{ChannelId: "C01", Text: "hi"}) {
+		t.Errorf("expected plain message not to be collected\n")
+	}
+}
+
+func TestFinishDigest(t *testing.T) {
+	defer func() { g_Digest = Digest{Since: time.Now()} }()
+	since := time.Date(2020, 9, 13, 9, 0, 0, 0, time.Local)
+	now := since.Add(8 * time.Hour)
+
+	// kept to retry after failed
+	g_Digest = Digest{Messages: []Message{{Text: "first"}}, Since: since, Sending: true}
+	finishDigest(1, now, errors.New("connection refused"))
+	if len(g_Digest.Messages) != 1 || g_Digest.Since != since || g_Digest.Sending || !g_Digest.RetryAt.After(now) {
+		t.Errorf("unexpected digest %+v\n", g_Digest)
+	}
+
+	// a message collected while sending remains
+	g_Digest.Messages = append(g_Digest.Messages, Message{Text: "second"})
+	finishDigest(1, now, nil)
+	if len(g_Digest.Messages) != 1 || g_Digest.Messages[0].Text != "second" || g_Digest.Since != now {
+		t.Errorf("unexpected digest %+v\n", g_Digest)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import "fmt"
+import "io"
+import "os"
+import "regexp"
+import "strings"
+
+import "slackv/console"
+
+//==============================
+// display profiles
+//==============================
+
+const DISPLAY_PROFILE_ASCII_ONLY = "ascii-only"
+const DISPLAY_PROFILE_SCREEN_READER = "screen-reader"
+
+// ASCII equivalents of frequently used non-ASCII characters
+var g_AsciiReplacements = map[rune]string{
+	'\u00a0': " ",   // no-break space
+	'·':      "*",   // middle dot
+	'–':      "-",   // en dash
+	'—':      "-",   // em dash
+	'―':      "-",   // horizontal bar
+	'‘':      "'",   // left single quotation mark
+	'’':      "'",   // right single quotation mark
+	'‚':      "'",   // single low-9 quotation mark
+	'“':      "\"",  // left double quotation mark
+	'”':      "\"",  // right double quotation mark
+	'„':      "\"",  // double low-9 quotation mark
+	'•':      "*",   // bullet
+	'…':      "...", // horizontal ellipsis
+	'←':      "<-",  // leftwards arrow
+	'↑':      "^",   // upwards arrow
+	'→':      "->",  // rightwards arrow
+	'↓':      "v",   // downwards arrow
+	'↳':      "->",  // downwards arrow with tip rightwards
+	'⇄':      "<->", // rightwards arrow over leftwards arrow
+	'─':      "-",   // box drawings light horizontal
+	'━':      "-",   // box drawings heavy horizontal
+	'│':      "|",   // box drawings light vertical
+	'┃':      "|",   // box drawings heavy vertical
+	'⏰':      "(!)", // alarm clock
+	'✓':      "v",   // check mark
+	'✗':      "x",   // ballot x
+}
+
+var g_AnsiPattern = regexp.MustCompile("\033\\[[0-9;]*[A-Za-z]")
+
+// colors and styles (Select Graphic Rendition)
+var g_SgrPattern = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// operating system commands (title, hyperlinks) and tmux window name
+var g_OscPattern = regexp.MustCompile("\033[\\]k][^\007\033]*(\007|\033\\\\)")
+
+// where messages are displayed (replaced by tests)
+var g_Output io.Writer = os.Stdout
+
+// print to console with the display profile applied
+// whether colors and styles are not displayed
+func isStyleStripped() bool {
+	return !console.SupportsAnsi() || g_Config.Display.Profile == DISPLAY_PROFILE_SCREEN_READER || g_Config.Display.NoColor
+}
+
+func printConsole(text string) {
+	if g_Config.Display.Profile == DISPLAY_PROFILE_ASCII_ONLY {
+		text = toAscii(text)
+	}
+	if !console.SupportsAnsi() || g_Config.Display.Profile == DISPLAY_PROFILE_SCREEN_READER {
+		// legacy console displays escape sequences as garbage, and screen readers read them aloud
+		text = stripEscapes(text)
+	} else if g_Config.Display.NoColor {
+		// keep cursor movements for TUI
+		text = g_SgrPattern.ReplaceAllString(text, "")
+	}
+	fmt.Fprint(g_Output, text)
+}
+
+// a message as a sentence for screen readers and braille displays
+//
+// Every message has its sender, channel and time without padding,
+// because headers grouping messages are hard to follow by ear.
+func formatForScreenReader(message Message, text string) string {
+	var builder strings.Builder
+
+	if message.Highlight {
+		builder.WriteString("highlighted, ")
+	}
+	fmt.Fprintf(&builder, "from %s in channel %s at %s", message.User, message.Channel, parseTs(message.Ts).Format("15:04, January 2"))
+	if len(message.ThreadTs) > 0 {
+		fmt.Fprintf(&builder, ", reply to thread at %s", parseTs(message.ThreadTs).Format("15:04, January 2"))
+	}
+	builder.WriteString(":\n")
+	builder.WriteString(stripEscapes(text))
+	if annotation := strings.TrimSpace(stripEscapes(message.Annotation)); len(annotation) > 0 {
+		builder.WriteString("\n" + annotation)
+	}
+	builder.WriteString("\n")
+	return builder.String()
+}
+
+// replace non-ASCII characters with ASCII equivalents
+//
+// Unknown characters (emoji, CJK, etc) are replaced with "?".
+func toAscii(text string) string {
+	var builder strings.Builder
+
+	for _, r := range text {
+		if r < 0x80 {
+			builder.WriteRune(r)
+		} else if replacement, exist := g_AsciiReplacements[r]; exist {
+			builder.WriteString(replacement)
+		} else if 0x2500 <= r && r <= 0x257f {
+			// other box drawings
+			builder.WriteString("+")
+		} else if 0xfe00 <= r && r <= 0xfe0f || r == 0x200d {
+			// variation selectors and zero width joiner of emoji
+		} else {
+			builder.WriteString("?")
+		}
+	}
+
+	return builder.String()
+}
+
+// cut text to maxLength characters with "..." (0 for unlimited)
+func truncateText(text string, maxLength int) string {
+	if maxLength <= 0 {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	return string(runes[:maxLength]) + "..."
+}
+
+// shorten URLs in text longer than maxLength (0 for unlimited)
+func shortenUrls(text string, maxLength int) string {
+	if maxLength <= 0 {
+		return text
+	}
+	return g_UrlPattern.ReplaceAllStringFunc(text, func(url string) string {
+		return shortenUrl(url, maxLength)
+	})
+}
+
+// "host/…/tail" if url is longer than maxLength (0 for unlimited)
+func shortenUrl(url string, maxLength int) string {
+	runes := []rune(url)
+	if maxLength <= 0 || len(runes) <= maxLength {
+		return url
+	}
+
+	rest := strings.SplitN(url, "://", 2)[1]
+	host := strings.SplitN(rest, "/", 2)[0]
+	if strings.Count(rest, "/") == 0 {
+		return string(runes[:maxLength-1]) + "…"
+	}
+	tail := rest[strings.LastIndex(rest, "/")+1:]
+
+	// keep host, and cut tail to fit
+	tailRunes := []rune(tail)
+	if room := maxLength - len([]rune(host)) - 3; room < len(tailRunes) {
+		if room < 1 {
+			return host + "/…"
+		}
+		tailRunes = tailRunes[len(tailRunes)-room:]
+	}
+	return host + "/…/" + string(tailRunes)
+}
+
+// remove ANSI escape sequences
+func stripAnsi(text string) string {
+	return g_AnsiPattern.ReplaceAllString(text, "")
+}
+
+// remove all escape sequences including OSC
+func stripEscapes(text string) string {
+	return stripAnsi(g_OscPattern.ReplaceAllString(text, ""))
+}
+
+// number of columns to display text (without ANSI escape sequences)
+func displayWidth(text string) int {
+	width := 0
+	for _, r := range stripAnsi(text) {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// number of columns to display r
+//
+// East Asian wide characters and most emoji take 2 columns.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x200d || 0x300 <= r && r <= 0x36f || 0xfe00 <= r && r <= 0xfe0f:
+		// control, zero width joiner, combining marks and variation selectors
+		return 0
+	case 0x1100 <= r && r <= 0x115f,
+		0x2e80 <= r && r <= 0xa4cf && r != 0x303f,
+		0xac00 <= r && r <= 0xd7a3,
+		0xf900 <= r && r <= 0xfaff,
+		0xfe30 <= r && r <= 0xfe4f,
+		0xff00 <= r && r <= 0xff60,
+		0xffe0 <= r && r <= 0xffe6,
+		0x1f300 <= r && r <= 0x1f64f,
+		0x1f900 <= r && r <= 0x1f9ff,
+		0x20000 <= r && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
+
+// cut text (without ANSI escape sequences) into lines of width
+func wrapText(text string, width int) []string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		current := strings.Builder{}
+		currentWidth := 0
+		for _, r := range line {
+			w := runeWidth(r)
+			if currentWidth+w > width && currentWidth > 0 {
+				lines = append(lines, current.String())
+				current.Reset()
+				currentWidth = 0
+			}
+			current.WriteRune(r)
+			currentWidth += w
+		}
+		lines = append(lines, current.String())
+	}
+	return lines
+}
+
+// cut or pad text (without ANSI escape sequences) to width
+func fitText(text string, width int) string {
+	result := strings.Builder{}
+	resultWidth := 0
+	for _, r := range text {
+		w := runeWidth(r)
+		if resultWidth+w > width {
+			break
+		}
+		result.WriteRune(r)
+		resultWidth += w
+	}
+	return result.String() + strings.Repeat(" ", width-resultWidth)
+}
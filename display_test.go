@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+func TestToAscii(t *testing.T) {
+	expected := "\"quoted\" - a -> b... ?"
+	result := toAscii("“quoted” — a → b… 🍣")
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestStripAnsi(t *testing.T) {
+	expected := "title\nbody"
+	result := stripAnsi("\033[44mtitle\033[0m\n\033[5;95mbody\033[0m")
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	expected := []string{"abcd", "ef", "日本", "語"}
+	result := wrapText("abcdef\n日本語", 4)
+	if len(result) != len(expected) {
+		t.Fatalf("expected %q, but %q\n", expected, result)
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Errorf("expected %q, but %q\n", expected, result)
+		}
+	}
+}
+
+func TestFitText(t *testing.T) {
+	expected := "日本 "
+	result := fitText("日本語", 5)
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestShortenUrls(t *testing.T) {
+	cases := map[string]string{
+		"see https://example.com/a":                                  "see https://example.com/a",
+		"see https://example.com/very/long/path/to/report.pdf":       "see example.com/…/report.pdf",
+		"see https://files.example.com/abc/def/ghi?sig=123456789012": "see files.example.com/…/3456789012",
+	}
+	for source, expected := range cases {
+		if result := shortenUrls(source, 30); result != expected {
+			t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	cases := []struct {
+		text      string
+		maxLength int
+		expected  string
+	}{
+		{"abc", 0, "abc"},
+		{"abc", 3, "abc"},
+		{"あいうえお", 2, "あい..."},
+	}
+	for _, c := range cases {
+		if result := truncateText(c.text, c.maxLength); result != c.expected {
+			t.Errorf("expected \"%s\", but \"%s\"\n", c.expected, result)
+		}
+	}
+}
+
+func TestStripEscapes(t *testing.T) {
+	text := "\033]2;slackv\007\033[93m@alice\033[0m \033]8;;https://example.com\033\\link\033]8;;\033\\ \033kname\033\\"
+	expected := "@alice link "
+	if actual := stripEscapes(text); actual != expected {
+		t.Errorf("expected %q, but %q\n", expected, actual)
+	}
+}
+
+func TestFormatForScreenReader(t *testing.T) {
+	message := Message{Ts: "1600000100.000100", ThreadTs: "1600000000.000100", Channel: "dev", User: "alice", Highlight: true}
+	at := parseTs(message.Ts).Format("15:04, January 2")
+	threadAt := parseTs(message.ThreadTs).Format("15:04, January 2")
+
+	expected := "highlighted, from alice in channel dev at " + at + ", reply to thread at " + threadAt + ":\ndeploy failed\n"
+	result := formatForScreenReader(message, "\033[5;95mdeploy\033[0m failed")
+	if result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
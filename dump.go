@@ -0,0 +1,49 @@
+package main
+
+import "bytes"
+import "encoding/json"
+import "log"
+import "os"
+
+//==============================
+// raw event dump
+//==============================
+
+// file to append raw websocket frames by --dump-events (nil if not dumping)
+var g_DumpFile *os.File
+var g_DumpEventsPath string
+
+func openDumpFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	g_DumpFile = file
+	return nil
+}
+
+func closeDumpFile() {
+	if g_DumpFile != nil {
+		g_DumpFile.Close()
+		g_DumpFile = nil
+	}
+}
+
+// append frame as a line of JSON
+func dumpEvent(frame []byte) {
+	if g_DumpFile == nil {
+		return
+	}
+
+	line := bytes.Buffer{}
+	if err := json.Compact(&line, frame); err != nil {
+		// keep invalid frame as is for reproduction
+		line.Reset()
+		line.Write(bytes.TrimSpace(frame))
+	}
+	line.WriteByte('\n')
+
+	if _, err := g_DumpFile.Write(line.Bytes()); err != nil {
+		log.Print(err)
+	}
+}
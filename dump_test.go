@@ -0,0 +1,20 @@
+package main
+
+import "io/ioutil"
+import "path/filepath"
+import "testing"
+
+func TestDumpEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := openDumpFile(path); err != nil {
+		t.Fatal(err)
+	}
+	dumpEvent([]byte("{\n  \"type\": \"hello\"\n}"))
+	dumpEvent([]byte("not json\n"))
+	closeDumpFile()
+
+	expected := "{\"type\":\"hello\"}\nnot json\n"
+	if data, _ := ioutil.ReadFile(path); string(data) != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, data)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+//==============================
+// event handler registry
+//==============================
+
+// handler of events from RTM
+type EventHandler interface {
+	Handle(msg map[string]interface{})
+}
+
+// function as EventHandler
+type EventHandlerFunc func(msg map[string]interface{})
+
+func (f EventHandlerFunc) Handle(msg map[string]interface{}) {
+	f(msg)
+}
+
+// handlers keyed by type and subtype
+type HandlerRegistry struct {
+	handlers map[string]EventHandler
+}
+
+var g_EventHandlers = NewHandlerRegistry()
+
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{handlers: map[string]EventHandler{}}
+}
+
+func eventKey(eventType string, subtype string) string {
+	if len(subtype) == 0 {
+		return eventType
+	}
+	return eventType + "/" + subtype
+}
+
+// register handler of the type and subtype ("" for events without subtype or with others)
+//
+// A handler registered already for them is replaced.
+func (r *HandlerRegistry) Register(eventType string, subtype string, handler EventHandler) {
+	r.handlers[eventKey(eventType, subtype)] = handler
+}
+
+func (r *HandlerRegistry) RegisterFunc(eventType string, subtype string, fn func(msg map[string]interface{})) {
+	r.Register(eventType, subtype, EventHandlerFunc(fn))
+}
+
+// handler of the subtype if registered, otherwise of the type
+func (r *HandlerRegistry) Lookup(eventType string, subtype string) (EventHandler, bool) {
+	if handler, exist := r.handlers[eventKey(eventType, subtype)]; exist {
+		return handler, true
+	}
+	handler, exist := r.handlers[eventType]
+	return handler, exist
+}
+
+// call the handler of msg, and whether any handled it
+func (r *HandlerRegistry) Dispatch(msg map[string]interface{}) bool {
+	eventType, _ := msg["type"].(string)
+	subtype, _ := msg["subtype"].(string)
+	handler, exist := r.Lookup(eventType, subtype)
+	if exist {
+		handler.Handle(msg)
+	}
+	return exist
+}
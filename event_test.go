@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestHandlerRegistry(t *testing.T) {
+	r := NewHandlerRegistry()
+	handled := ""
+	r.RegisterFunc("message", "", func(msg map[string]interface{}) { handled = "message" })
+	r.RegisterFunc("message", "bot_message", func(msg map[string]interface{}) { handled = "bot_message" })
+
+	tests := []struct {
+		msg      map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"type": "message"}, "message"},
+		{map[string]interface{}{"type": "message", "subtype": "bot_message"}, "bot_message"},
+		{map[string]interface{}{"type": "message", "subtype": "unknown"}, "message"},
+		{map[string]interface{}{"type": "unknown"}, ""},
+	}
+	for _, test := range tests {
+		handled = ""
+		r.Dispatch(test.msg)
+		if handled != test.expected {
+			t.Errorf("expected \"%s\", but \"%s\" for %v\n", test.expected, handled, test.msg)
+		}
+	}
+}
+
+func TestEventHandlersRegistered(t *testing.T) {
+	for _, eventType := range []string{"hello", "message", "reaction_added", "reconnect_url"} {
+		if _, exist := g_EventHandlers.Lookup(eventType, ""); !exist {
+			t.Errorf("expected handler of \"%s\"\n", eventType)
+		}
+	}
+}
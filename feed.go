@@ -0,0 +1,108 @@
+package main
+
+import "encoding/xml"
+import "net/http"
+import "strings"
+import "time"
+
+//==============================
+// Atom feed of archived channels
+//==============================
+
+// max entries in a feed (newest ones)
+const FEED_ENTRY_LIMIT = 50
+
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	Id      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  AtomAuthor  `xml:"author"`
+	Content AtomContent `xml:"content"`
+}
+
+type AtomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type AtomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// feed of archived messages in the channel (no entry if never archived)
+func makeAtomFeed(channel string, archived []JsonlMessage) AtomFeed {
+	feed := AtomFeed{
+		Id:      "tag:slackv,2020:" + channel,
+		Title:   "#" + channel,
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+	}
+	// newest first
+	for i := len(archived) - 1; i >= 0; i-- {
+		message := archived[i]
+		updated := parseTs(message.Ts).UTC().Format(time.RFC3339)
+		if i == len(archived)-1 {
+			feed.Updated = updated
+		}
+		feed.Entries = append(feed.Entries, AtomEntry{
+			Id:      feed.Id + "/" + message.Ts,
+			Title:   feedTitle(message.Text),
+			Updated: updated,
+			Author:  AtomAuthor{Name: message.User},
+			Content: AtomContent{Type: "text", Text: message.Text},
+		})
+	}
+	return feed
+}
+
+// first line of text as title of an entry
+func feedTitle(text string) string {
+	title := strings.SplitN(text, "\n", 2)[0]
+	if runes := []rune(title); len(runes) > 80 {
+		title = string(runes[:80]) + "..."
+	}
+	return title
+}
+
+// serve /feed/<channel>.atom for channels of [web] feed-channels
+func (s *WebServer) handleFeed(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/feed/")
+	if !strings.HasSuffix(name, ".atom") {
+		http.NotFound(w, r)
+		return
+	}
+	channel := strings.TrimSuffix(name, ".atom")
+	if len(s.archivePath) == 0 || !equalsAnyKeywords(channel, s.feedChannels) {
+		// not to expose channels not selected
+		http.NotFound(w, r)
+		return
+	}
+
+	archived := []JsonlMessage{}
+	err := readArchive(s.archivePath, func(message JsonlMessage) bool {
+		if message.Channel == channel && len(message.ThreadTs) == 0 {
+			archived = append(archived, message)
+			if len(archived) > FEED_ENTRY_LIMIT {
+				archived = archived[1:]
+			}
+		}
+		return true
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	encoder.Encode(makeAtomFeed(channel, archived))
+}
@@ -0,0 +1,64 @@
+package main
+
+import "encoding/xml"
+import "net/http"
+import "net/http/httptest"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestMakeAtomFeed(t *testing.T) {
+	feed := makeAtomFeed("announce", []JsonlMessage{
+		{Ts: "1600000000.000100", Channel: "announce", User: "alice", Text: "first"},
+		{Ts: "1600000100.000100", Channel: "announce", User: "bob", Text: "release v1.0\nchangelog"},
+	})
+
+	if len(feed.Entries) != 2 {
+		t.Fatalf("expected 2 entries, but %d\n", len(feed.Entries))
+	}
+	if feed.Entries[0].Title != "release v1.0" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "release v1.0", feed.Entries[0].Title)
+	}
+	if feed.Updated != "2020-09-13T12:28:20Z" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "2020-09-13T12:28:20Z", feed.Updated)
+	}
+	if feed.Entries[1].Id != "tag:slackv,2020:announce/1600000000.000100" {
+		t.Errorf("unexpected id \"%s\"\n", feed.Entries[1].Id)
+	}
+}
+
+func TestWebServerFeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	lines := `{"ts":"1600000000.000100","channel":"announce","user":"alice","text":"release"}
+{"ts":"1600000000.000200","channel":"general","user":"bob","text":"secret"}
+`
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(newWebServer(path, []string{"#announce"}))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/feed/announce.atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	feed := AtomFeed{}
+	if err := xml.NewDecoder(response.Body).Decode(&feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].Content.Text != "release" {
+		t.Errorf("unexpected entries %v\n", feed.Entries)
+	}
+
+	// not selected
+	response, err = http.Get(server.URL + "/feed/general.atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, but %d\n", response.StatusCode)
+	}
+}
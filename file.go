@@ -0,0 +1,177 @@
+package main
+
+import "fmt"
+import "io"
+import "io/ioutil"
+import "log"
+import "net/http"
+import "os"
+import "path/filepath"
+import "strings"
+import "unicode/utf8"
+
+//==============================
+// shared files
+//==============================
+
+func init() {
+	g_Commands["download"] = Command{"[ref]", "save files shared by the message into the download directory", runDownload}
+}
+
+// title bar and preview of file
+func formatFile(file SlackFile) string {
+	title := file.Title
+	if len(title) == 0 {
+		title = file.Name
+	}
+	text := "\033[44mfile: " + strings.TrimSpace(title) + "\033[0m"
+
+	if len(file.Preview) > 0 {
+		text = text + "\n" + file.Preview
+		if file.PreviewIsTruncated {
+			text = text + "..."
+		}
+	}
+	return text
+}
+
+// replace truncated preview of snippet with the content up to limit bytes
+func expandSnippet(file SlackFile, limit int) SlackFile {
+	if file.Mode != "snippet" || !file.PreviewIsTruncated || len(file.UrlPrivate) == 0 {
+		return file
+	}
+
+	body, err := openPrivateUrl(file.UrlPrivate)
+	if err != nil {
+		log.Print(err)
+		return file
+	}
+	defer body.Close()
+
+	content, truncated, err := readLimited(body, limit)
+	if err != nil {
+		log.Print(err)
+		return file
+	}
+	file.Preview = strings.TrimRight(content, "\n")
+	file.PreviewIsTruncated = truncated
+	return file
+}
+
+// read up to limit bytes (not cutting a UTF-8 character), and whether more remain
+func readLimited(reader io.Reader, limit int) (string, bool, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return "", false, err
+	}
+	if len(data) <= limit {
+		return string(data), false, nil
+	}
+
+	// drop incomplete character at the end
+	data = data[:limit]
+	for i := 1; i < utf8.UTFMax && len(data) > 0; i++ {
+		if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size > 1 {
+			break
+		}
+		data = data[:len(data)-1]
+	}
+	return string(data), true, nil
+}
+
+// GET url_private of file with the token
+//
+// Caller must close the returned body.
+func openPrivateUrl(url string) (io.ReadCloser, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+g_Config.General.Token)
+
+	response, err := g_HttpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, response.Status)
+	}
+	return response.Body, nil
+}
+
+// save file into directory, and return the path
+//
+// A number is added to the name if the file exists.
+func downloadFile(file SlackFile, directory string) (string, error) {
+	if len(file.UrlPrivate) == 0 {
+		return "", fmt.Errorf("%s: no URL to download", file.Name)
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return "", err
+	}
+
+	body, err := openPrivateUrl(file.UrlPrivate)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	out, path, err := createNewFile(directory, sanitizeFileName(file.Name))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, body); err != nil {
+		out.Close()
+		os.Remove(path)
+		return "", err
+	}
+	return path, out.Close()
+}
+
+// create name in directory, or "name-N.ext" if it exists
+func createNewFile(directory string, name string) (*os.File, string, error) {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for i := 0; ; i++ {
+		path := filepath.Join(directory, name)
+		if i > 0 {
+			path = filepath.Join(directory, fmt.Sprintf("%s-%d%s", base, i, ext))
+		}
+		out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		return out, path, err
+	}
+}
+
+//==============================
+// /download
+//==============================
+
+func runDownload(args []string) error {
+	message, err := resolveRef(firstArg(args))
+	if err != nil {
+		return err
+	}
+	if len(message.Files) == 0 && len(message.Text) == 0 {
+		// permalink of message not displayed
+		if message, err = fetchMessage(message.ChannelId, message.Ts); err != nil {
+			return err
+		}
+	}
+	if len(message.Files) == 0 {
+		return fmt.Errorf("no files in the message")
+	}
+
+	for _, file := range message.Files {
+		path, err := downloadFile(file, g_Config.Files.Directory)
+		if err != nil {
+			return err
+		}
+		printStatus("saved: " + path)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+
+func TestGetFiles(t *testing.T) {
+	msg := map[string]interface{}{
+		"file": map[string]interface{}{"id": "F01", "name": "a.txt"},
+		"files": []interface{}{
+			map[string]interface{}{"id": "F01", "name": "a.txt"},
+			map[string]interface{}{"id": "F02", "name": "b.png", "url_private": "https://files.slack.com/b.png"},
+		},
+	}
+
+	files := getFiles(msg)
+	if len(files) != 2 || files[0].Name != "a.txt" || files[1].UrlPrivate != "https://files.slack.com/b.png" {
+		t.Errorf("unexpected files %+v\n", files)
+	}
+}
+
+func TestCreateNewFile(t *testing.T) {
+	directory := t.TempDir()
+
+	expected := []string{"a.txt", "a-1.txt", "a-2.txt"}
+	for _, name := range expected {
+		out, path, err := createNewFile(directory, "a.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Close()
+		if path != filepath.Join(directory, name) {
+			t.Errorf("expected \"%s\", but \"%s\"\n", name, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	cases := []struct {
+		text      string
+		limit     int
+		expected  string
+		truncated bool
+	}{
+		{"abc", 3, "abc", false},
+		{"abcd", 3, "abc", true},
+		{"aあい", 5, "aあ", true},
+	}
+	for _, c := range cases {
+		result, truncated, err := readLimited(strings.NewReader(c.text), c.limit)
+		if err != nil || result != c.expected || truncated != c.truncated {
+			t.Errorf("expected \"%s\" (%v), but \"%s\" (%v)\n", c.expected, c.truncated, result, truncated)
+		}
+	}
+}
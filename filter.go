@@ -0,0 +1,199 @@
+package main
+
+import "fmt"
+import "regexp"
+import "strings"
+
+//==============================
+// runtime filters
+//==============================
+
+// display only messages matching this (nil to display all)
+var g_Filter *regexp.Regexp
+
+// changes of [notification] by commands, applied again after reloading config
+var g_NotificationChanges []func(notification *ConfigNotification)
+
+// patterns of [notification.channel."#name"] keyed by name without "#"
+var g_ChannelNotificationPatterns = map[string][]*regexp.Regexp{}
+
+func init() {
+	g_Commands["mute"] = Command{"#channel|@user", "hide messages of the channel or user", runMute}
+	g_Commands["unmute"] = Command{"#channel|@user", "show messages of the channel or user again", runUnmute}
+	g_Commands["follow"] = Command{"#channel", "show only followed channels", runFollow}
+	g_Commands["unfollow"] = Command{"#channel", "stop following the channel", runUnfollow}
+	g_Commands["filter"] = Command{"[regexp]", "show only messages matching regexp (without argument, show all)", runFilter}
+}
+
+// whether the message is hidden by channel or user (before unescape)
+func isMuted(message Message) bool {
+	if equalsAnyKeywords(message.Channel, g_Config.Notification.MuteChannels) {
+		return true
+	}
+	if equalsAnyKeywords(message.User, g_Config.Notification.MuteUsers) {
+		return true
+	}
+	if len(message.Subtype) > 0 && equalsAnyKeywords(message.Subtype, g_Config.Notification.MuteSubtypes) {
+		return true
+	}
+	if rule, exist := channelNotification(message.Channel); exist {
+		if rule.Mute || equalsAnyKeywords(message.User, rule.MuteUsers) {
+			return true
+		}
+	}
+	follows := g_Config.Notification.FollowChannels
+	if len(follows) > 0 && !equalsAnyKeywords(message.Channel, follows) {
+		return true
+	}
+	return false
+}
+
+// rule of [notification.channel."#name"] or [notification.channel.name]
+func channelNotification(channel string) (ChannelNotification, bool) {
+	if rule, exist := g_Config.Notification.Channel["#"+channel]; exist {
+		return rule, true
+	}
+	rule, exist := g_Config.Notification.Channel[channel]
+	return rule, exist
+}
+
+func compileChannelNotificationPatterns(channels map[string]ChannelNotification) map[string][]*regexp.Regexp {
+	compiled := map[string][]*regexp.Regexp{}
+	for name, rule := range channels {
+		if rule.Patterns != nil {
+			compiled[strings.TrimPrefix(name, "#")] = compileNotificationPatterns(rule.Patterns)
+		}
+	}
+	return compiled
+}
+
+// patterns to highlight messages of the channel
+func notificationPatterns(channel string) []*regexp.Regexp {
+	if patterns, exist := g_ChannelNotificationPatterns[channel]; exist {
+		return patterns
+	}
+	return g_NotificationPatterns
+}
+
+// whether the message is hidden by text (after unescape)
+func isFilteredOut(message Message) bool {
+	return g_Filter != nil && !g_Filter.MatchString(message.Text)
+}
+
+// change [notification], and remember it for reload
+func changeNotification(change func(notification *ConfigNotification)) {
+	change(&g_Config.Notification)
+	g_NotificationChanges = append(g_NotificationChanges, change)
+}
+
+func appendKeyword(keywords []string, keyword string) []string {
+	if equalsAnyKeywords(keyword, keywords) {
+		return keywords
+	}
+	return append(keywords, keyword)
+}
+
+func removeKeyword(keywords []string, keyword string) []string {
+	result := []string{}
+	for _, k := range keywords {
+		if k != keyword {
+			result = append(result, k)
+		}
+	}
+	return result
+}
+
+//==============================
+// /mute, /unmute
+//==============================
+
+func runMute(args []string) error {
+	target := firstArg(args)
+	switch {
+	case strings.HasPrefix(target, "#"):
+		changeNotification(func(notification *ConfigNotification) {
+			notification.MuteChannels = appendKeyword(notification.MuteChannels, target[1:])
+		})
+	case strings.HasPrefix(target, "@"):
+		changeNotification(func(notification *ConfigNotification) {
+			notification.MuteUsers = appendKeyword(notification.MuteUsers, target[1:])
+		})
+	default:
+		return fmt.Errorf("usage: /mute #channel|@user")
+	}
+	printStatus("muted " + target)
+	return nil
+}
+
+func runUnmute(args []string) error {
+	target := firstArg(args)
+	switch {
+	case strings.HasPrefix(target, "#"):
+		unmuteChannel(target[1:])
+	case strings.HasPrefix(target, "@"):
+		changeNotification(func(notification *ConfigNotification) {
+			notification.MuteUsers = removeKeyword(notification.MuteUsers, target[1:])
+		})
+	default:
+		return fmt.Errorf("usage: /unmute #channel|@user")
+	}
+	printStatus("unmuted " + target)
+	return nil
+}
+
+//==============================
+// /follow, /unfollow
+//==============================
+
+func runFollow(args []string) error {
+	target := firstArg(args)
+	if !strings.HasPrefix(target, "#") {
+		return fmt.Errorf("usage: /follow #channel")
+	}
+	followChannel(target[1:])
+	printStatus("following " + strings.Join(g_Config.Notification.FollowChannels, ", "))
+	return nil
+}
+
+func runUnfollow(args []string) error {
+	target := firstArg(args)
+	if !strings.HasPrefix(target, "#") {
+		return fmt.Errorf("usage: /unfollow #channel")
+	}
+	changeNotification(func(notification *ConfigNotification) {
+		notification.FollowChannels = removeKeyword(notification.FollowChannels, target[1:])
+	})
+	follows := g_Config.Notification.FollowChannels
+	if len(follows) == 0 {
+		printStatus("following all channels")
+	} else {
+		printStatus("following " + strings.Join(follows, ", "))
+	}
+	return nil
+}
+
+func followChannel(name string) {
+	changeNotification(func(notification *ConfigNotification) {
+		notification.FollowChannels = appendKeyword(notification.FollowChannels, name)
+	})
+}
+
+//==============================
+// /filter
+//==============================
+
+func runFilter(args []string) error {
+	if len(args) == 0 {
+		g_Filter = nil
+		printStatus("filter cleared")
+		return nil
+	}
+
+	filter, err := regexp.Compile(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	g_Filter = filter
+	printStatus("filter: " + filter.String())
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import "io/ioutil"
+import "path/filepath"
+import "regexp"
+import "testing"
+
+func TestIsMuted(t *testing.T) {
+	g_Config = defaultConfig()
+	g_Config.Notification.MuteUsers = []string{"slackbot"}
+	g_Config.Notification.FollowChannels = []string{"dev"}
+	g_Config.Notification.MuteSubtypes = []string{"channel_join", "bot_message"}
+
+	cases := []struct {
+		message  Message
+		expected bool
+	}{
+		{Message{Channel: "dev", User: "alice"}, false},
+		{Message{Channel: "dev", User: "slackbot"}, true},
+		{Message{Channel: "random", User: "alice"}, true},
+		{Message{Channel: "dev", User: "alice", Subtype: "bot_message"}, true},
+		{Message{Channel: "dev", User: "alice", Subtype: "me_message"}, false},
+	}
+	for _, c := range cases {
+		if result := isMuted(c.message); result != c.expected {
+			t.Errorf("%+v: expected %v, but %v\n", c.message, c.expected, result)
+		}
+	}
+}
+
+func TestChannelNotification(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	ioutil.WriteFile(path, []byte(`[notification]
+patterns = ["deploy"]
+[notification.channel."#incidents"]
+patterns = ["."]
+mute-users = ["pagerbot"]
+[notification.channel.random]
+patterns = []
+[notification.channel."#noise"]
+mute = true
+`), 0644)
+	g_Config = defaultConfig()
+	if err := decodeConfigFile(path, &g_Config); err != nil {
+		t.Fatal(err)
+	}
+	g_NotificationPatterns = compileNotificationPatterns(g_Config.Notification.Patterns)
+	g_ChannelNotificationPatterns = compileChannelNotificationPatterns(g_Config.Notification.Channel)
+	defer func() {
+		g_NotificationPatterns = nil
+		g_ChannelNotificationPatterns = map[string][]*regexp.Regexp{}
+	}()
+
+	highlights := []struct {
+		message  Message
+		expected bool
+	}{
+		{Message{Channel: "dev", Text: "deploy done"}, true},
+		{Message{Channel: "incidents", Text: "disk full"}, true},
+		{Message{Channel: "random", Text: "deploy done"}, false},
+		{Message{Channel: "noise", Text: "deploy done"}, true},
+	}
+	for _, c := range highlights {
+		if result := matchAnyPatterns(c.message.Text, notificationPatterns(c.message.Channel)); result != c.expected {
+			t.Errorf("%+v: expected %v, but %v\n", c.message, c.expected, result)
+		}
+	}
+
+	mutes := []struct {
+		message  Message
+		expected bool
+	}{
+		{Message{Channel: "incidents", User: "pagerbot"}, true},
+		{Message{Channel: "dev", User: "pagerbot"}, false},
+		{Message{Channel: "noise", User: "alice"}, true},
+	}
+	for _, c := range mutes {
+		if result := isMuted(c.message); result != c.expected {
+			t.Errorf("%+v: expected %v, but %v\n", c.message, c.expected, result)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import "flag"
+import "os"
+import "strings"
+import "unicode"
+
+//==============================
+// command line overrides of config
+//==============================
+
+// flag.Value of repeatable string flag
+type StringListFlag []string
+
+func (f *StringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *StringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// values given by flags for this run
+var g_FlagOverrides struct {
+	MuteChannels StringListFlag
+	MuteUsers    StringListFlag
+	Patterns     StringListFlag
+	MentionsOnly bool
+	NoColor      bool
+}
+
+func defineOverrideFlags() {
+	flag.Var(&g_FlagOverrides.MuteChannels, "mute-channel", "hide messages of the channel (repeatable)")
+	flag.Var(&g_FlagOverrides.MuteUsers, "mute-user", "hide messages of the user (repeatable)")
+	flag.Var(&g_FlagOverrides.Patterns, "pattern", "highlight messages matching the regexp (repeatable)")
+	flag.BoolVar(&g_FlagOverrides.MentionsOnly, "mentions-only", false, "display only messages matching patterns, mentioning you or in DMs")
+	flag.BoolVar(&g_FlagOverrides.NoColor, "no-color", false, "display without colors and styles")
+}
+
+// apply flags (and NO_COLOR environment variable) over config
+func applyFlagOverrides(config *Config) {
+	notification := &config.Notification
+	for _, channel := range g_FlagOverrides.MuteChannels {
+		notification.MuteChannels = appendKeyword(notification.MuteChannels, strings.TrimPrefix(channel, "#"))
+	}
+	for _, user := range g_FlagOverrides.MuteUsers {
+		notification.MuteUsers = appendKeyword(notification.MuteUsers, strings.TrimPrefix(user, "@"))
+	}
+	for _, pattern := range g_FlagOverrides.Patterns {
+		notification.Patterns = appendKeyword(notification.Patterns, pattern)
+	}
+	if g_FlagOverrides.MentionsOnly {
+		notification.MentionsOnly = true
+	}
+
+	// @see https://no-color.org/
+	if g_FlagOverrides.NoColor || len(os.Getenv("NO_COLOR")) > 0 {
+		config.Display.NoColor = true
+	}
+}
+
+// whether message mentions you
+func isMention(message Message) bool {
+	if len(g_Self.Id) == 0 {
+		return false
+	}
+	mention := "@" + selfName()
+	for text := message.Text; ; {
+		index := strings.Index(text, mention)
+		if index < 0 {
+			return false
+		}
+		text = text[index+len(mention):]
+		if !startsWithNameChar(text) {
+			return true
+		}
+	}
+}
+
+// whether text continues a name (e.g. "bob" of "@alicebob", but not "." of "@alice.")
+func startsWithNameChar(text string) bool {
+	for i, r := range text {
+		if r == '.' && i == 0 {
+			continue
+		}
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+	}
+	return false
+}
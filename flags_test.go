@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestApplyFlagOverrides(t *testing.T) {
+	defer func() {
+		g_FlagOverrides.MuteChannels = nil
+		g_FlagOverrides.Patterns = nil
+		g_FlagOverrides.MentionsOnly = false
+	}()
+	g_FlagOverrides.MuteChannels = StringListFlag{"#random", "dev"}
+	g_FlagOverrides.Patterns = StringListFlag{"deploy"}
+	g_FlagOverrides.MentionsOnly = true
+
+	config := defaultConfig()
+	config.Notification.MuteChannels = []string{"random"}
+	applyFlagOverrides(&config)
+
+	if len(config.Notification.MuteChannels) != 2 || config.Notification.MuteChannels[1] != "dev" {
+		t.Errorf("unexpected mute-channels %v\n", config.Notification.MuteChannels)
+	}
+	if len(config.Notification.Patterns) != 1 || !config.Notification.MentionsOnly {
+		t.Errorf("unexpected notification %+v\n", config.Notification)
+	}
+}
+
+func TestIsMention(t *testing.T) {
+	g_Self = SlackUser{Id: "U01", Name: "alice.smith"}
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice"})
+
+	if !isMention(Message{Text: "hi @alice"}) {
+		t.Errorf("expected mention\n")
+	}
+	for _, text := range []string{"hi @bob", "hi @alicebob", "@alice.smith2 is not me", "hi @alice_b"} {
+		if isMention(Message{Text: text}) {
+			t.Errorf("expected not mention in \"%s\"\n", text)
+		}
+	}
+	for _, text := range []string{"@alice: hi", "thanks @alice.", "cc @alicebob @alice", "@alice"} {
+		if !isMention(Message{Text: text}) {
+			t.Errorf("expected mention in \"%s\"\n", text)
+		}
+	}
+}
+
+func TestMentionsOnlyFilter(t *testing.T) {
+	savedConfig := g_Config
+	defer func() { g_Config = savedConfig }()
+	g_Config = defaultConfig()
+	g_Config.Notification.MentionsOnly = true
+	g_Self = SlackUser{Id: "U01", Name: "alice"}
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice"})
+	defer func() { g_Self = SlackUser{} }()
+
+	filter := g_FilterStages["mentions-only"]
+	if _, keep := filter.Filter(Message{ChannelId: "C01", Channel: "general", Text: "hi all"}); keep {
+		t.Errorf("expected message dropped\n")
+	}
+	if _, keep := filter.Filter(Message{ChannelId: "D01", Channel: "DM: bob", Text: "hi"}); !keep {
+		t.Errorf("expected direct message kept\n")
+	}
+}
@@ -4,5 +4,16 @@ go 1.18
 
 require (
 	github.com/BurntSushi/toml v1.1.0
+	github.com/yuin/gopher-lua v1.1.1
 	golang.org/x/net v0.12.0
+	google.golang.org/grpc v1.56.3
+	google.golang.org/protobuf v1.30.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
 )
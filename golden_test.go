@@ -0,0 +1,81 @@
+package main
+
+import "bytes"
+import "encoding/json"
+import "errors"
+import "flag"
+import "io/ioutil"
+import "net/http"
+import "path/filepath"
+import "strings"
+import "testing"
+import "time"
+
+var g_UpdateGolden = flag.Bool("update", false, "update golden files of rendering tests")
+
+// fail API calls not to depend on network in rendering tests
+type offlineTransport struct{}
+
+func (t offlineTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return nil, errors.New("offline in golden tests: " + request.URL.String())
+}
+
+// feed events of testdata/golden/*.json to handlers, and compare output with *.golden
+func TestGoldenRendering(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "golden", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedConfig, savedCache, savedClient, savedLocal := g_Config, g_NameCache, g_HttpClient, time.Local
+	savedOutput, savedPatterns := g_Output, g_NotificationPatterns
+	defer func() {
+		g_Config, g_NameCache, g_HttpClient, time.Local = savedConfig, savedCache, savedClient, savedLocal
+		g_Output, g_NotificationPatterns = savedOutput, savedPatterns
+		g_Renderer = &ConsoleRenderer{}
+		g_MessageStore = NewMessageStore(MESSAGE_STORE_CAPACITY)
+	}()
+	time.Local = time.UTC
+	g_HttpClient = &http.Client{Transport: offlineTransport{}}
+
+	for _, fixture := range fixtures {
+		events := []map[string]interface{}{}
+		data, err := ioutil.ReadFile(fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &events); err != nil {
+			t.Fatalf("%s: %v", fixture, err)
+		}
+
+		g_Config = defaultConfig()
+		g_Config.Notification.Patterns = []string{"deploy"}
+		g_NotificationPatterns = compileNotificationPatterns(g_Config.Notification.Patterns)
+		g_NameCache = NewNameCache(map[string]string{"U01": "alice", "U02": "bob", "C01": "general", "C02": "dev", "B01": "ci", "USLACKBOT": "slackbot", "D01": "DM: slackbot"})
+		g_ChannelTopics = map[string]ChannelTopic{"C01": {Topic: "announcements"}, "C02": {}, "D01": {}}
+		g_IntroducedChannels = map[string]struct{}{}
+		g_MessageStore = NewMessageStore(MESSAGE_STORE_CAPACITY)
+		g_Renderer = &ConsoleRenderer{}
+		output := &bytes.Buffer{}
+		g_Output = output
+
+		for _, event := range events {
+			dispatchEvent(event)
+		}
+
+		golden := strings.TrimSuffix(fixture, ".json") + ".golden"
+		if *g_UpdateGolden {
+			if err := ioutil.WriteFile(golden, output.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%s: %v (run with -update to create)", golden, err)
+		}
+		if output.String() != string(expected) {
+			t.Errorf("%s: expected %q, but %q\n", golden, string(expected), output.String())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import "encoding/json"
+import "log"
+import "net/http"
+import "sync"
+import "time"
+
+//==============================
+// health check
+//==============================
+
+// state of connection shared with HTTP server of /healthz
+type HealthState struct {
+	mutex       sync.Mutex
+	Connected   bool
+	ConnectedAt time.Time
+	LastEvent   time.Time
+}
+
+type HealthReport struct {
+	Status           string  `json:"status"` // "ok" or "unhealthy"
+	Connected        bool    `json:"connected"`
+	ConnectedSeconds float64 `json:"connected_seconds,omitempty"`
+	LastEventSeconds float64 `json:"last_event_seconds,omitempty"`
+}
+
+var g_Health = &HealthState{}
+
+func (h *HealthState) SetConnected(connected bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if connected && !h.Connected {
+		h.ConnectedAt = time.Now()
+		h.LastEvent = h.ConnectedAt
+	}
+	h.Connected = connected
+}
+
+// called on every event from websocket
+func (h *HealthState) Touch() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.LastEvent = time.Now()
+}
+
+// report at now, which is unhealthy if disconnected or no event for maxEventAge
+func (h *HealthState) Report(now time.Time, maxEventAge time.Duration) HealthReport {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	report := HealthReport{Status: "ok", Connected: h.Connected}
+	if !h.Connected {
+		report.Status = "unhealthy"
+		return report
+	}
+
+	report.ConnectedSeconds = now.Sub(h.ConnectedAt).Seconds()
+	age := now.Sub(h.LastEvent)
+	report.LastEventSeconds = age.Seconds()
+	if maxEventAge > 0 && age > maxEventAge {
+		report.Status = "unhealthy"
+	}
+	return report
+}
+
+func makeHealthzHandler(maxEventAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := g_Health.Report(time.Now(), maxEventAge)
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// serve /healthz at address in background
+func startHealthServer(address string) {
+	mux := http.NewServeMux()
+	// handlers run in goroutines of net/http, so read config here
+	maxEventAge := time.Duration(g_Config.Health.MaxEventAge) * time.Second
+	mux.HandleFunc("/healthz", makeHealthzHandler(maxEventAge))
+
+	go func() {
+		if err := http.ListenAndServe(address, mux); err != nil {
+			log.Print(err)
+		}
+	}()
+}
@@ -0,0 +1,42 @@
+package main
+
+import "net/http"
+import "net/http/httptest"
+import "testing"
+import "time"
+
+func TestHealthReport(t *testing.T) {
+	health := &HealthState{}
+	now := time.Now()
+	if report := health.Report(now, time.Minute); report.Status != "unhealthy" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "unhealthy", report.Status)
+	}
+
+	health.SetConnected(true)
+	if report := health.Report(time.Now(), time.Minute); report.Status != "ok" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "ok", report.Status)
+	}
+	if report := health.Report(time.Now().Add(2*time.Minute), time.Minute); report.Status != "unhealthy" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "unhealthy", report.Status)
+	}
+	if report := health.Report(time.Now().Add(2*time.Minute), 0); report.Status != "ok" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "ok", report.Status)
+	}
+}
+
+func TestHandleHealthz(t *testing.T) {
+	g_Health = &HealthState{}
+	recorder := httptest.NewRecorder()
+	makeHealthzHandler(time.Minute)(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d, but %d\n", http.StatusServiceUnavailable, recorder.Code)
+	}
+
+	g_Health.SetConnected(true)
+	recorder = httptest.NewRecorder()
+	makeHealthzHandler(time.Minute)(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected %d, but %d\n", http.StatusOK, recorder.Code)
+	}
+	g_Health = &HealthState{}
+}
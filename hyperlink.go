@@ -0,0 +1,81 @@
+package main
+
+import "regexp"
+import "strings"
+
+//==============================
+// terminal hyperlinks (OSC 8)
+//==============================
+
+// without trailing punctuations
+var g_UrlPattern = regexp.MustCompile(`https?://[^\s<>|"]*[^\s<>|").,]`)
+
+// @#name after unescape (preceded by start or space)
+var g_NamePattern = regexp.MustCompile(`(^|\s)([@#])([-_.0-9A-Za-z]+)`)
+
+// make text clickable to open url
+func hyperlink(url string, text string) string {
+	if len(url) == 0 {
+		return text
+	}
+	return "\033]8;;" + url + "\033\\" + text + "\033]8;;\033\\"
+}
+
+// URL of the path in the workspace ("" if the domain is unknown)
+func slackUrl(path string) string {
+	if len(g_Team.Domain) == 0 {
+		return ""
+	}
+	return "https://" + g_Team.Domain + ".slack.com/" + path
+}
+
+func channelUrl(channelId string) string {
+	if len(channelId) == 0 {
+		return ""
+	}
+	return slackUrl("archives/" + channelId)
+}
+
+func userUrl(userId string) string {
+	if len(userId) == 0 {
+		return ""
+	}
+	return slackUrl("team/" + userId)
+}
+
+// link URLs, #channels and @users in the message text
+func addHyperlinks(text string) string {
+	text = g_UrlPattern.ReplaceAllStringFunc(text, func(url string) string {
+		return hyperlink(url, shortenUrl(url, g_Config.Display.MaxUrlLength))
+	})
+
+	return g_NamePattern.ReplaceAllStringFunc(text, func(match string) string {
+		submatch := g_NamePattern.FindStringSubmatch(match)
+		url := ""
+		if submatch[2] == "#" {
+			if id, exist := findCachedId(submatch[3], "CG"); exist {
+				url = channelUrl(id)
+			}
+		} else if id, exist := findCachedId(submatch[3], "UW"); exist {
+			url = userUrl(id)
+		}
+		return submatch[1] + hyperlink(url, submatch[2]+submatch[3])
+	})
+}
+
+// link the padded user name of the header
+func linkUserName(field string, user string) string {
+	id, _ := findCachedId(user, "UW")
+	return linkPadded(field, userUrl(id))
+}
+
+// link the padded channel name of the header
+func linkChannelName(field string, channelId string) string {
+	return linkPadded(field, channelUrl(channelId))
+}
+
+// link text of field without trailing padding
+func linkPadded(field string, url string) string {
+	text := strings.TrimRight(field, " ")
+	return hyperlink(url, text) + field[len(text):]
+}
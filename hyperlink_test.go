@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestAddHyperlinks(t *testing.T) {
+	g_Team = SlackTeam{Id: "T01", Name: "team", Domain: "example"}
+	g_NameCache = NewNameCache(map[string]string{"C01": "general", "U01": "alice"})
+
+	expected := "see \033]8;;https://example.com/a?b=1\033\\https://example.com/a?b=1\033]8;;\033\\ in " +
+		"\033]8;;https://example.slack.com/archives/C01\033\\#general\033]8;;\033\\ by " +
+		"\033]8;;https://example.slack.com/team/U01\033\\@alice\033]8;;\033\\ and @bob"
+	result := addHyperlinks("see https://example.com/a?b=1 in #general by @alice and @bob")
+	if result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+
+	expected = "\033]8;;https://example.slack.com/archives/C01\033\\#general\033]8;;\033\\  "
+	result = linkChannelName("#general  ", "C01")
+	if result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
+
+func TestUrlPattern(t *testing.T) {
+	expected := "https://example.com/a"
+	if result := g_UrlPattern.FindString("docs (https://example.com/a)."); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
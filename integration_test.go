@@ -0,0 +1,81 @@
+//go:build integration
+// +build integration
+
+package main
+
+// Integration tests against a sandbox workspace
+//
+//   $ SLACKV_TEST_TOKEN=xoxp-... SLACKV_TEST_CHANNEL=C01234 go test -tags=integration
+
+import "fmt"
+import "os"
+import "testing"
+import "time"
+
+func setupIntegration(t *testing.T) string {
+	token := os.Getenv("SLACKV_TEST_TOKEN")
+	channel := os.Getenv("SLACKV_TEST_CHANNEL")
+	if len(token) == 0 || len(channel) == 0 {
+		t.Skip("SLACKV_TEST_TOKEN and SLACKV_TEST_CHANNEL are required")
+	}
+	g_Config = defaultConfig()
+	g_Config.General.Token = token
+	g_NameCache = NewNameCache(map[string]string{})
+	return channel
+}
+
+func TestIntegrationLogin(t *testing.T) {
+	setupIntegration(t)
+
+	session, err := login(g_Config.General.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Url) == 0 {
+		t.Errorf("empty websocket URL\n")
+	}
+
+	ws, err := connect(g_Config.General.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.Close()
+}
+
+func TestIntegrationCache(t *testing.T) {
+	channel := setupIntegration(t)
+
+	if err := cacheUserGroups(); err != nil && !isApiError(err, "missing_scope") {
+		t.Error(err)
+	}
+	if err := cacheChannelInfo(channel); err != nil {
+		t.Fatal(err)
+	}
+	if len(g_NameCache.Name(channel)) == 0 {
+		t.Errorf("channel %s is not cached\n", channel)
+	}
+}
+
+func TestIntegrationPostAndHistory(t *testing.T) {
+	channel := setupIntegration(t)
+
+	text := fmt.Sprintf("slackv integration test %s", time.Now().Format(time.RFC3339Nano))
+	ts, err := postMessage(channel, "", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := fetchHistory(channel, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range messages {
+		if getTs(msg) == ts {
+			if getText(msg) != text {
+				t.Errorf("expected \"%s\", but \"%s\"\n", text, getText(msg))
+			}
+			return
+		}
+	}
+	t.Errorf("posted message %s is not found in history\n", ts)
+}
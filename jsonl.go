@@ -0,0 +1,57 @@
+package main
+
+import "encoding/json"
+import "fmt"
+import "log"
+import "time"
+
+//==============================
+// JSONL output
+//==============================
+
+const OUTPUT_MODE_TEXT = "text"
+const OUTPUT_MODE_JSONL = "jsonl"
+
+// a line of JSONL output
+type JsonlMessage struct {
+	Timestamp string `json:"timestamp"` // RFC 3339
+	Ts        string `json:"ts"`
+	ThreadTs  string `json:"thread_ts,omitempty"`
+	ChannelId string `json:"channel_id,omitempty"`
+	Channel   string `json:"channel"`
+	UserType  string `json:"user_type,omitempty"`
+	User      string `json:"user"`
+	Text      string `json:"text"`
+	Subtype   string `json:"subtype,omitempty"`
+	Highlight bool   `json:"highlight,omitempty"`
+}
+
+func newJsonlMessage(message Message) JsonlMessage {
+	return JsonlMessage{
+		Timestamp: parseTs(message.Ts).Format(time.RFC3339),
+		Ts:        message.Ts,
+		ThreadTs:  message.ThreadTs,
+		ChannelId: message.ChannelId,
+		Channel:   message.Channel,
+		UserType:  message.UserType,
+		User:      message.User,
+		Text:      stripAnsi(message.Text),
+		Subtype:   message.Subtype,
+		Highlight: message.Highlight,
+	}
+}
+
+// print message as a line of JSON
+func printJsonl(message Message) {
+	data, err := json.Marshal(newJsonlMessage(message))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	fmt.Fprintln(g_Output, string(data))
+}
+
+// print status like "Connecting..."
+func printStatus(text string) {
+	g_Renderer.Status(text)
+}
@@ -0,0 +1,69 @@
+package main
+
+import "fmt"
+import "io/ioutil"
+import "os"
+import "strconv"
+import "strings"
+
+//==============================
+// instance lock
+//==============================
+
+// path of the lock file held by this process
+var g_LockFile string
+
+// create lock file not to run multiple instances with the same config
+//
+// A stale lock file left by a dead process is taken over.
+func acquireLock(path string) error {
+	for retry := 0; retry < 2; retry++ {
+		err := createLockFile(path)
+		if err == nil {
+			g_LockFile = path
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err == nil && pid > 0 && pid != os.Getpid() && processExists(pid) {
+			return fmt.Errorf(
+				"slackv is already running (pid %d). Remove %s if it is not.",
+				pid,
+				path,
+			)
+		}
+
+		// stale lock
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to lock %s", path)
+}
+
+// create lock file having pid at once (fails by os.IsExist if locked)
+//
+// The pid is written to a temporary file and linked, so that others never
+// read the lock file empty and take it as stale.
+func createLockFile(path string) error {
+	temp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := ioutil.WriteFile(temp, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(temp)
+	return os.Link(temp, path)
+}
+
+func releaseLock() {
+	if len(g_LockFile) > 0 {
+		os.Remove(g_LockFile)
+		g_LockFile = ""
+	}
+}
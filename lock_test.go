@@ -0,0 +1,47 @@
+package main
+
+import "fmt"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+
+func TestAcquireLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slackv.lock")
+	defer releaseLock()
+
+	// held by running process
+	ioutil.WriteFile(path, []byte(fmt.Sprintf("%d\n", os.Getppid())), 0644)
+	if err := acquireLock(path); err == nil {
+		t.Errorf("expected error for running instance\n")
+	}
+
+	// stale
+	ioutil.WriteFile(path, []byte("999999999\n"), 0644)
+	if err := acquireLock(path); err != nil {
+		t.Error(err)
+	}
+	releaseLock()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected lock file to be removed\n")
+	}
+}
+
+func TestCreateLockFile(t *testing.T) {
+	directory := t.TempDir()
+	path := filepath.Join(directory, "slackv.lock")
+	if err := createLockFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != fmt.Sprintf("%d\n", os.Getpid()) {
+		t.Errorf("expected pid, but \"%s\"\n", data)
+	}
+	if err := createLockFile(path); !os.IsExist(err) {
+		t.Errorf("expected exist error, but %v\n", err)
+	}
+
+	// no temporary file left
+	if files, _ := ioutil.ReadDir(directory); len(files) != 1 {
+		t.Errorf("expected only the lock file, but %d files\n", len(files))
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import "fmt"
+import "io/ioutil"
+import "log"
+import "os"
+import "path/filepath"
+import "regexp"
+import "sort"
+import "strings"
+import "time"
+import "unicode/utf8"
+
+//==============================
+// message logging
+//==============================
+
+const LOG_ROTATE_DAILY = "daily"
+const LOG_ROTATE_SIZE = "size"
+
+// write to a file of the date like irssi's autolog (e.g. general-2006-01-02.log)
+const LOG_ROTATE_DATED = "dated"
+
+// max bytes of file names made from channel names (most file systems allow 255)
+const MAX_FILE_NAME_LENGTH = 200
+
+// log file which rotates by date or size
+type RotatingLog struct {
+	Path       string
+	Rotate     string // "", "daily", "size" or "dated"
+	MaxSize    int64
+	MaxBackups int
+
+	file *os.File
+	size int64
+	date string // date of the file content (for daily rotation)
+}
+
+// opened log files keyed by channel ("" if not per-channel)
+var g_MessageLogs = map[string]*RotatingLog{}
+
+// write a line, and rotate if needed
+func (l *RotatingLog) WriteLine(now time.Time, line string) error {
+	date := now.Format("2006-01-02")
+
+	if l.file != nil {
+		switch l.Rotate {
+		case LOG_ROTATE_DAILY:
+			if date != l.date {
+				if err := l.rotate(l.Path+"."+l.date, now); err != nil {
+					return err
+				}
+			}
+		case LOG_ROTATE_DATED:
+			if date != l.date {
+				if err := l.Close(); err != nil {
+					return err
+				}
+			}
+		case LOG_ROTATE_SIZE:
+			if l.MaxSize > 0 && l.size+int64(len(line))+1 > l.MaxSize {
+				if err := l.rotate("", now); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if l.file == nil {
+		if err := l.open(now); err != nil {
+			return err
+		}
+	}
+
+	n, err := fmt.Fprintln(l.file, line)
+	l.size += int64(n)
+	return err
+}
+
+func (l *RotatingLog) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// path of the file of the date if dated, otherwise Path
+func (l *RotatingLog) datedPath(date string) string {
+	if l.Rotate != LOG_ROTATE_DATED {
+		return l.Path
+	}
+	ext := filepath.Ext(l.Path)
+	return strings.TrimSuffix(l.Path, ext) + "-" + date + ext
+}
+
+func (l *RotatingLog) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.datedPath(now.Format("2006-01-02")), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = stat.Size()
+	if l.Rotate == LOG_ROTATE_DAILY && l.size > 0 {
+		// continue the existing file only if it was written today
+		if stat.ModTime().Format("2006-01-02") != now.Format("2006-01-02") {
+			l.date = stat.ModTime().Format("2006-01-02")
+			return l.rotate(l.Path+"."+l.date, now)
+		}
+	}
+	l.date = now.Format("2006-01-02")
+	if l.Rotate == LOG_ROTATE_DATED {
+		l.pruneDatedBackups(l.date)
+	}
+	return nil
+}
+
+// close current file and rename it
+//
+// If backupPath is empty, backups are numbered as path.1, path.2, ...
+func (l *RotatingLog) rotate(backupPath string, now time.Time) error {
+	if err := l.Close(); err != nil {
+		return err
+	}
+
+	if len(backupPath) == 0 {
+		maxBackups := l.maxBackups()
+		os.Remove(fmt.Sprintf("%s.%d", l.Path, maxBackups))
+		for i := maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", l.Path, i), fmt.Sprintf("%s.%d", l.Path, i+1))
+		}
+		backupPath = l.Path + ".1"
+	}
+
+	if err := os.Rename(l.Path, backupPath); err != nil {
+		return err
+	}
+	if l.Rotate == LOG_ROTATE_DAILY {
+		l.pruneDatedBackups("")
+	}
+
+	return l.open(now)
+}
+
+// at least 1
+func (l *RotatingLog) maxBackups() int {
+	if l.MaxBackups <= 0 {
+		return 1
+	}
+	return l.MaxBackups
+}
+
+// remove files of old dates over MaxBackups (path.2006-01-02 if daily, path-2006-01-02.ext if dated)
+//
+// current is the date of the file being written if dated.
+func (l *RotatingLog) pruneDatedBackups(current string) {
+	dir := filepath.Dir(l.Path)
+	prefix, suffix := filepath.Base(l.Path)+".", ""
+	if l.Rotate == LOG_ROTATE_DATED {
+		ext := filepath.Ext(l.Path)
+		prefix, suffix = strings.TrimSuffix(filepath.Base(l.Path), ext)+"-", ext
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	backups := []string{}
+	for _, file := range files {
+		name := file.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) || len(name) != len(prefix)+len("2006-01-02")+len(suffix) {
+			continue
+		}
+		date := name[len(prefix) : len(name)-len(suffix)]
+		if _, err := time.Parse("2006-01-02", date); err == nil && date != current {
+			backups = append(backups, name)
+		}
+	}
+
+	// oldest first
+	sort.Strings(backups)
+	for len(backups) > l.maxBackups() {
+		os.Remove(filepath.Join(dir, backups[0]))
+		backups = backups[1:]
+	}
+}
+
+// device names which cannot be file names on Windows (even with extensions)
+var g_ReservedFileNames = regexp.MustCompile(`(?i)^(CON|PRN|AUX|NUL|COM[0-9]|LPT[0-9])(\.|$)`)
+
+// make a file name from channel name, user name, etc
+func sanitizeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, ". ")
+	if len(name) > MAX_FILE_NAME_LENGTH {
+		// cut at a rune boundary
+		cut := MAX_FILE_NAME_LENGTH
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		name = name[:cut]
+	}
+	if len(name) == 0 || g_ReservedFileNames.MatchString(name) {
+		name = "_" + name
+	}
+	return name
+}
+
+// write message to log files
+func logMessage(message Message) {
+	if len(g_Config.Logging.Directory) == 0 {
+		return
+	}
+
+	key := ""
+	fileName := "slackv.log"
+	if g_Config.Logging.PerChannel {
+		key = message.Channel
+		fileName = sanitizeFileName(message.Channel) + ".log"
+	}
+
+	messageLog, exist := g_MessageLogs[key]
+	if !exist {
+		messageLog = &RotatingLog{
+			Path:       filepath.Join(g_Config.Logging.Directory, fileName),
+			Rotate:     g_Config.Logging.Rotate,
+			MaxSize:    g_Config.Logging.MaxSize,
+			MaxBackups: g_Config.Logging.MaxBackups,
+		}
+		g_MessageLogs[key] = messageLog
+	}
+
+	if err := messageLog.WriteLine(time.Now(), formatLogLine(message)); err != nil {
+		log.Print(err)
+	}
+}
+
+// format message as a line of plain text
+//
+// Following lines of multi-line text are indented.
+func formatLogLine(message Message) string {
+	text := stripAnsi(message.Text + message.Annotation)
+	text = strings.Replace(text, "\n", "\n\t", -1)
+	return fmt.Sprintf(
+		"%s #%s @%s%s: %s",
+		parseTs(message.Ts).Format("2006/01/02 15:04:05"),
+		message.Channel,
+		message.UserType,
+		message.User,
+		text,
+	)
+}
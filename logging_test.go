@@ -0,0 +1,144 @@
+package main
+
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+import "time"
+import "unicode/utf8"
+
+func TestRotatingLogSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	l := &RotatingLog{Path: path, Rotate: LOG_ROTATE_SIZE, MaxSize: 8, MaxBackups: 2}
+	defer l.Close()
+
+	for _, line := range []string{"aaa", "bbb", "ccc", "ddd", "eee"} {
+		if err := l.WriteLine(time.Now(), line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{path: "eee\n", path + ".1": "ccc\nddd\n", path + ".2": "aaa\nbbb\n"}
+	for p, content := range expected {
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected \"%s\", but \"%s\"\n", p, content, string(data))
+		}
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	expected := "a_b_c"
+	result := sanitizeFileName("a/b:c")
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestSanitizeFileNameReserved(t *testing.T) {
+	for name, expected := range map[string]string{"con": "_con", "nul.txt": "_nul.txt", "console": "console", "..": "_"} {
+		result := sanitizeFileName(name)
+		if result != expected {
+			t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+		}
+	}
+
+	result := sanitizeFileName(strings.Repeat("日", 100))
+	if len(result) > MAX_FILE_NAME_LENGTH || !utf8.ValidString(result) {
+		t.Errorf("unexpected long name \"%s\"\n", result)
+	}
+}
+
+func TestRotatingLogDated(t *testing.T) {
+	dir := t.TempDir()
+	l := &RotatingLog{Path: filepath.Join(dir, "general.log"), Rotate: LOG_ROTATE_DATED}
+	defer l.Close()
+
+	day := time.Date(2020, 9, 13, 23, 59, 0, 0, time.Local)
+	for i, line := range []string{"aaa", "bbb"} {
+		if err := l.WriteLine(day.Add(time.Duration(i)*time.Minute), line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{"general-2020-09-13.log": "aaa\n", "general-2020-09-14.log": "bbb\n"}
+	for name, content := range expected {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected \"%s\", but \"%s\"\n", name, content, string(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "general.log")); err == nil {
+		t.Errorf("expected no undated file\n")
+	}
+}
+
+func TestRotatingLogDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	l := &RotatingLog{Path: path, Rotate: LOG_ROTATE_DAILY, MaxBackups: 2}
+	defer l.Close()
+
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	l.WriteLine(day1, "day1")
+	l.WriteLine(day1.AddDate(0, 0, 1), "day2 a")
+	l.WriteLine(day1.AddDate(0, 0, 1).Add(time.Hour), "day2 b")
+	l.WriteLine(day1.AddDate(0, 0, 2), "day3")
+
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{path + ".2024-01-01", "day1\n"},
+		{path + ".2024-01-02", "day2 a\nday2 b\n"},
+		{path, "day3\n"},
+	}
+	for _, c := range cases {
+		data, err := ioutil.ReadFile(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.expected {
+			t.Errorf("%s: expected %q, but %q\n", c.path, c.expected, string(data))
+		}
+	}
+
+	// the oldest backup is removed
+	l.WriteLine(day1.AddDate(0, 0, 3), "day4")
+	if _, err := os.Stat(path + ".2024-01-01"); !os.IsNotExist(err) {
+		t.Errorf("expected %s removed\n", path+".2024-01-01")
+	}
+	if _, err := os.Stat(path + ".2024-01-03"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRotatingLogDatedPrune(t *testing.T) {
+	dir := t.TempDir()
+	l := &RotatingLog{Path: filepath.Join(dir, "general.log"), Rotate: LOG_ROTATE_DATED, MaxBackups: 1}
+	defer l.Close()
+	ioutil.WriteFile(filepath.Join(dir, "general-dev-2020-09-01.log"), []byte("other channel\n"), 0644)
+
+	day := time.Date(2020, 9, 13, 12, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		if err := l.WriteLine(day.AddDate(0, 0, i), "line"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	expected := "general-2020-09-14.log general-2020-09-15.log general-dev-2020-09-01.log"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, strings.Join(names, " "))
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import "crypto/rand"
+import "encoding/hex"
+import "fmt"
+import "net"
+import "net/http"
+import "net/url"
+import "os"
+import "strconv"
+import "strings"
+import "time"
+
+import "slackv/console"
+
+//==============================
+// OAuth login
+//==============================
+
+// @see https://api.slack.com/methods/oauth.v2.access
+type SlackOauthAccessResponse struct {
+	Ok         bool
+	AuthedUser struct {
+		Id          string `json:"id"`
+		Scope       string `json:"scope"`
+		AccessToken string `json:"access_token"`
+	} `json:"authed_user"`
+}
+
+const LOGIN_TIMEOUT = 5 * time.Minute
+
+func redirectUrl() string {
+	return "http://localhost:" + strconv.Itoa(g_Config.General.RedirectPort) + "/callback"
+}
+
+// subcommand "login"
+//
+// Authorizes the app in browser, and stores the user token in the credential store.
+func runLogin() error {
+	if len(g_Config.General.ClientId) == 0 || len(g_Config.General.ClientSecret) == 0 {
+		return fmt.Errorf("set client-id and client-secret of the Slack app in the config file")
+	}
+
+	state, err := randomState()
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(g_Config.General.RedirectPort))
+	if err != nil {
+		return err
+	}
+
+	codes := make(chan string, 1)
+	errors := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+		if code := query.Get("code"); len(code) > 0 {
+			fmt.Fprintln(w, "Authorized. Close this page and return to slackv.")
+			codes <- code
+		} else {
+			fmt.Fprintln(w, "Not authorized.")
+			errors <- fmt.Errorf("authorization failed: %s", query.Get("error"))
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authorizeUrl := makeLoginUrl(state)
+	fmt.Fprintf(os.Stderr, "Authorize the app in browser:\n%s\n", authorizeUrl)
+	if err := openBrowser(authorizeUrl); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	select {
+	case code := <-codes:
+		token, err := exchangeCode(code)
+		if err != nil {
+			return err
+		}
+		return saveToken(token)
+	case err := <-errors:
+		return err
+	case <-time.After(LOGIN_TIMEOUT):
+		return fmt.Errorf("timed out waiting for authorization")
+	}
+}
+
+func makeLoginUrl(state string) string {
+	query := url.Values{}
+	query.Set("client_id", g_Config.General.ClientId)
+	query.Set("user_scope", strings.Join(requiredUserScopes(), ","))
+	query.Set("redirect_uri", redirectUrl())
+	query.Set("state", state)
+	return "https://slack.com/oauth/v2/authorize?" + query.Encode()
+}
+
+// get user token by code of redirect
+func exchangeCode(code string) (string, error) {
+	query := url.Values{}
+	query.Set("client_id", g_Config.General.ClientId)
+	query.Set("client_secret", g_Config.General.ClientSecret)
+	query.Set("code", code)
+	query.Set("redirect_uri", redirectUrl())
+
+	// not by callApi, which adds the configured token
+	accessResponse := SlackOauthAccessResponse{}
+	if _, err := requestApi("oauth.v2.access", query, &accessResponse); err != nil {
+		return "", err
+	}
+	if len(accessResponse.AuthedUser.AccessToken) == 0 {
+		return "", fmt.Errorf("no user token is issued")
+	}
+	return accessResponse.AuthedUser.AccessToken, nil
+}
+
+// store token in the credential store, or guide to set it in the config file
+//
+// The token is not displayed not to leave it in the terminal.
+func saveToken(token string) error {
+	if err := console.StoreSecret(KEYCHAIN_SERVICE, keychainAccount(), token); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot store the token in the credential store (%v).\n", err)
+		fmt.Fprintf(os.Stderr, "Copy \"User OAuth Token\" on https://api.slack.com/apps to token in %s or %s.\n", g_ConfigPath, TOKEN_ENV)
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "Logged in. The token is stored in the credential store.")
+	return nil
+}
+
+func randomState() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
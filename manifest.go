@@ -0,0 +1,120 @@
+package main
+
+import "encoding/json"
+import "fmt"
+
+//==============================
+// Slack app manifest
+//==============================
+
+// @see https://api.slack.com/reference/manifests
+type SlackManifest struct {
+	DisplayInformation SlackManifestDisplayInformation `json:"display_information"`
+	OauthConfig        SlackManifestOauthConfig        `json:"oauth_config"`
+	Settings           SlackManifestSettings           `json:"settings"`
+}
+
+type SlackManifestDisplayInformation struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SlackManifestOauthConfig struct {
+	RedirectUrls []string            `json:"redirect_urls,omitempty"`
+	Scopes       SlackManifestScopes `json:"scopes"`
+}
+
+type SlackManifestScopes struct {
+	User []string `json:"user"`
+}
+
+type SlackManifestSettings struct {
+	EventSubscriptions   SlackManifestEventSubscriptions `json:"event_subscriptions"`
+	SocketModeEnabled    bool                            `json:"socket_mode_enabled"`
+	OrgDeployEnabled     bool                            `json:"org_deploy_enabled"`
+	TokenRotationEnabled bool                            `json:"token_rotation_enabled"`
+}
+
+type SlackManifestEventSubscriptions struct {
+	UserEvents []string `json:"user_events"`
+}
+
+// user scopes to view messages
+var g_BaseUserScopes = []string{
+	"channels:history",
+	"channels:read",
+	"groups:history",
+	"groups:read",
+	"im:history",
+	"im:read",
+	"mpim:history",
+	"mpim:read",
+	"team:read",
+	"usergroups:read",
+	"users:read",
+}
+
+// user scopes for runtime commands
+var g_CommandUserScopes = []string{
+	"channels:write",
+	"chat:write",
+	"emoji:read",
+	"files:read",
+	"groups:write",
+	"pins:read",
+	"pins:write",
+	"reactions:write",
+	"search:read",
+	"stars:read",
+}
+
+// events to view messages
+var g_BaseUserEvents = []string{
+	"channel_created",
+	"im_created",
+	"message.channels",
+	"message.groups",
+	"message.im",
+	"message.mpim",
+	"user_change",
+}
+
+// user scopes needed by features enabled in config
+func requiredUserScopes() []string {
+	scopes := append([]string{}, g_BaseUserScopes...)
+	scopes = append(scopes, g_CommandUserScopes...)
+	if g_Config.General.MarkAsRead {
+		scopes = append(scopes, "im:write", "mpim:write")
+	}
+	return scopes
+}
+
+// user events needed by features enabled in config
+func requiredUserEvents() []string {
+	events := append([]string{}, g_BaseUserEvents...)
+	if g_Config.Display.Membership {
+		events = append(events, "member_joined_channel", "member_left_channel")
+	}
+	return events
+}
+
+func makeManifest(name string) SlackManifest {
+	manifest := SlackManifest{}
+	manifest.DisplayInformation.Name = name
+	manifest.DisplayInformation.Description = "Slack viewer"
+	manifest.OauthConfig.RedirectUrls = []string{redirectUrl()}
+	manifest.OauthConfig.Scopes.User = requiredUserScopes()
+	manifest.Settings.EventSubscriptions.UserEvents = requiredUserEvents()
+	manifest.Settings.SocketModeEnabled = true
+	return manifest
+}
+
+// subcommand "generate-manifest"
+func generateManifest(name string) error {
+	data, err := json.MarshalIndent(makeManifest(name), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import "fmt"
+import "log"
+import "net/url"
+
+//==============================
+// mark as read (conversations.mark)
+//==============================
+
+// ts to mark as read keyed by channel id
+//
+// Marks are sent periodically not to exceed rate limits.
+var g_PendingReadMarks = map[string]string{}
+
+func init() {
+	g_Commands["mark-read"] = Command{"[on|off]", "mark displayed messages as read (without argument, mark now)", runMarkRead}
+}
+
+// mark the message as read later
+func markAsRead(message Message) {
+	if !g_Config.General.MarkAsRead {
+		return
+	}
+	if len(message.ChannelId) == 0 || len(message.Ts) == 0 {
+		return
+	}
+	if isTsAfter(message.Ts, g_PendingReadMarks[message.ChannelId]) {
+		g_PendingReadMarks[message.ChannelId] = message.Ts
+	}
+}
+
+// send pending marks
+func flushReadMarks() {
+	for channel, ts := range g_PendingReadMarks {
+		query := url.Values{}
+		query.Set("channel", channel)
+		query.Set("ts", ts)
+		if err := callApi("conversations.mark", query, nil); err != nil {
+			log.Printf("%s: %s", channel, err)
+		}
+		delete(g_PendingReadMarks, channel)
+	}
+}
+
+func runMarkRead(args []string) error {
+	switch firstArg(args) {
+	case "":
+		flushReadMarks()
+	case "on":
+		g_Config.General.MarkAsRead = true
+		printStatus("mark-read: on")
+	case "off":
+		flushReadMarks()
+		g_Config.General.MarkAsRead = false
+		printStatus("mark-read: off")
+	default:
+		return fmt.Errorf("usage: /mark-read [on|off]")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import "time"
+
+//==============================
+// members joining and leaving channels
+//==============================
+
+// RTM sends both member_joined_channel and a channel_join message for a join
+const MEMBERSHIP_DUPLICATE_WINDOW = time.Minute
+
+// displayed joins and leaves keyed by channel, user and action
+var g_RecentMemberships = map[string]time.Time{}
+
+func init() {
+	g_EventHandlers.RegisterFunc("member_joined_channel", "", onMemberJoined)
+	g_EventHandlers.RegisterFunc("member_left_channel", "", onMemberLeft)
+	for _, subtype := range []string{"channel_join", "group_join"} {
+		g_EventHandlers.RegisterFunc("message", subtype, withLastTs(onMemberJoined))
+	}
+	for _, subtype := range []string{"channel_leave", "group_leave"} {
+		g_EventHandlers.RegisterFunc("message", subtype, withLastTs(onMemberLeft))
+	}
+}
+
+func onMemberJoined(msg map[string]interface{}) {
+	printMembership(msg, "+", "joined", "channel_join", time.Now())
+}
+
+func onMemberLeft(msg map[string]interface{}) {
+	printMembership(msg, "-", "left", "channel_leave", time.Now())
+}
+
+// "(+@alice joined #dev)" by [display] membership, otherwise messages as they are
+func printMembership(msg map[string]interface{}, sign string, action string, subtype string, now time.Time) {
+	if !g_Config.Display.Membership {
+		if _, isMessage := msg["subtype"]; isMessage {
+			onPureMessage(msg)
+		}
+		return
+	}
+	if message, exist := membershipMessage(msg, sign, action, subtype, now); exist {
+		printMessage(message)
+	}
+}
+
+// compact message of a join or leave (false if displayed already by another event)
+//
+// subtype is for mute-subtypes if msg is an event, not a message.
+func membershipMessage(msg map[string]interface{}, sign string, action string, subtype string, now time.Time) (Message, bool) {
+	channel, _ := msg["channel"].(string)
+	user, _ := msg["user"].(string)
+	if len(channel) == 0 || len(user) == 0 || isMembershipDisplayed(channel+" "+user+" "+action, now) {
+		return Message{}, false
+	}
+
+	message := newMessage(msg)
+	if len(message.Ts) == 0 {
+		// member_joined_channel and member_left_channel
+		message.Ts, _ = msg["event_ts"].(string)
+	}
+	if len(message.Subtype) == 0 {
+		message.Subtype = subtype
+	}
+	message.User = getUser(user)
+
+	text := "(" + sign + "@" + message.User + " " + action + " #" + message.Channel
+	if inviter, exist := msg["inviter"].(string); exist && len(inviter) > 0 {
+		text = text + ", invited by @" + getUser(inviter)
+	}
+	message.Text = "\033[90m" + text + ")\033[0m"
+	return message, true
+}
+
+// whether displayed recently, and remember it if not
+func isMembershipDisplayed(key string, now time.Time) bool {
+	for recentKey, displayed := range g_RecentMemberships {
+		if now.Sub(displayed) > MEMBERSHIP_DUPLICATE_WINDOW {
+			delete(g_RecentMemberships, recentKey)
+		}
+	}
+	if _, exist := g_RecentMemberships[key]; exist {
+		return true
+	}
+	g_RecentMemberships[key] = now
+	return false
+}
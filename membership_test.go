@@ -0,0 +1,50 @@
+package main
+
+import "bytes"
+import "strings"
+import "testing"
+import "time"
+
+func TestMembershipMessage(t *testing.T) {
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "U02": "bob", "C01": "dev"})
+	g_RecentMemberships = map[string]time.Time{}
+	now := time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC)
+
+	// the join message following the event is a duplicate
+	event := map[string]interface{}{"type": "member_joined_channel", "channel": "C01", "user": "U01", "inviter": "U02", "event_ts": "1600000000.000100"}
+	message, exist := membershipMessage(event, "+", "joined", "channel_join", now)
+	expected := "\033[90m(+@alice joined #dev, invited by @bob)\033[0m"
+	if !exist || message.Text != expected || message.Subtype != "channel_join" || message.Ts != "1600000000.000100" {
+		t.Errorf("expected %q of channel_join, but %+v\n", expected, message)
+	}
+	joined := map[string]interface{}{"subtype": "channel_join", "channel": "C01", "user": "U01", "ts": "1600000000.000200"}
+	if _, exist := membershipMessage(joined, "+", "joined", "channel_join", now.Add(time.Second)); exist {
+		t.Errorf("expected duplicate join to be skipped\n")
+	}
+
+	left := map[string]interface{}{"subtype": "group_leave", "channel": "C01", "user": "U01", "ts": "1600000000.000300"}
+	message, _ = membershipMessage(left, "-", "left", "channel_leave", now.Add(time.Hour))
+	expected = "\033[90m(-@alice left #dev)\033[0m"
+	if message.Text != expected || message.Subtype != "group_leave" {
+		t.Errorf("expected %q of group_leave, but %+v\n", expected, message)
+	}
+}
+
+func TestMembershipDisabled(t *testing.T) {
+	savedOutput := g_Output
+	defer func() { g_Output = savedOutput }()
+	output := &bytes.Buffer{}
+	g_Output = output
+	g_Renderer = &ConsoleRenderer{}
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "C01": "dev"})
+	g_ChannelTopics = map[string]ChannelTopic{"C01": {}}
+
+	// messages are displayed as they are, and events are not
+	dispatchEvent(map[string]interface{}{"type": "member_joined_channel", "channel": "C01", "user": "U01"})
+	dispatchEvent(map[string]interface{}{"type": "message", "subtype": "channel_join", "channel": "C01", "user": "U01", "ts": "1600000000.000100", "text": "<@U01> has joined the channel"})
+	if result := output.String(); !strings.Contains(result, "@alice has joined the channel") || strings.Contains(result, "(+") {
+		t.Errorf("expected the join message as it is, but %q\n", result)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import "bufio"
+import "crypto/subtle"
+import "crypto/tls"
+import "crypto/x509"
+import "fmt"
+import "io/ioutil"
+import "log"
+import "net"
+import "net/http"
+import "net/url"
+import "time"
+
+import "golang.org/x/net/proxy"
+import "golang.org/x/net/websocket"
+
+//==============================
+// network connections
+//==============================
+
+const DIAL_TIMEOUT = 10 * time.Second
+const RESPONSE_TIMEOUT = 30 * time.Second // until response header
+const API_TIMEOUT = 60 * time.Second      // whole Web API call including body
+
+var g_Dialer = &net.Dialer{Timeout: DIAL_TIMEOUT, KeepAlive: 30 * time.Second}
+
+// TLS settings of [network] for Web API and websocket
+var g_TlsConfig = &tls.Config{}
+
+func makeTlsConfig(network ConfigNetwork) (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if len(network.CaFile) > 0 {
+		pem, err := ioutil.ReadFile(network.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			// not available on some platforms
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", network.CaFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if network.InsecureSkipVerify {
+		log.Print("WARNING: insecure-skip-verify is set. Server certificates are NOT verified, so the token can be stolen.")
+		config.InsecureSkipVerify = true
+	}
+
+	return config, nil
+}
+
+// proxy for target URL by [network] proxy or HTTP_PROXY, HTTPS_PROXY and NO_PROXY (nil for direct)
+func proxyUrl(target *url.URL) (*url.URL, error) {
+	if len(g_Config.Network.Proxy) > 0 {
+		return url.Parse(g_Config.Network.Proxy)
+	}
+
+	// wss is proxied as https
+	request := &http.Request{URL: &url.URL{Scheme: target.Scheme, Host: target.Host}}
+	switch target.Scheme {
+	case "ws":
+		request.URL.Scheme = "http"
+	case "wss":
+		request.URL.Scheme = "https"
+	}
+	return http.ProxyFromEnvironment(request)
+}
+
+// client shared by Web API calls to reuse connections (renewed by loadConfig)
+var g_HttpClient = newHttpClient()
+
+// client with [network] settings and timeouts
+//
+// No timeout of the whole request, so that downloads of large files can
+// complete. Callers limit it by context if needed.
+func newHttpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = g_Dialer.DialContext
+	transport.TLSHandshakeTimeout = DIAL_TIMEOUT
+	transport.ResponseHeaderTimeout = RESPONSE_TIMEOUT
+	transport.TLSClientConfig = g_TlsConfig.Clone()
+	transport.Proxy = func(request *http.Request) (*url.URL, error) {
+		return proxyUrl(request.URL)
+	}
+	return &http.Client{Transport: transport}
+}
+
+// connect websocket of RTM through proxy if any
+func dialWebsocket(wsUrl string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(wsUrl, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+
+	host := config.Location.Host
+	if len(config.Location.Port()) == 0 {
+		if config.Location.Scheme == "wss" {
+			host = host + ":443"
+		} else {
+			host = host + ":80"
+		}
+	}
+
+	conn, err := dialThroughProxy(config.Location, host)
+	if err != nil {
+		return nil, err
+	}
+	if config.Location.Scheme == "wss" {
+		tlsConfig := g_TlsConfig.Clone()
+		tlsConfig.ServerName = config.Location.Hostname()
+		tlsConn := tls.Client(conn, tlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(DIAL_TIMEOUT))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// TCP connection to host (host:port) directly, via SOCKS5 or HTTP CONNECT
+func dialThroughProxy(target *url.URL, host string) (net.Conn, error) {
+	proxyLocation, err := proxyUrl(target)
+	if err != nil {
+		return nil, err
+	}
+	if proxyLocation == nil {
+		return g_Dialer.Dial("tcp", host)
+	}
+
+	switch proxyLocation.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyLocation.User != nil {
+			password, _ := proxyLocation.User.Password()
+			auth = &proxy.Auth{User: proxyLocation.User.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", proxyLocation.Host, auth, g_Dialer)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.Dial("tcp", host)
+	case "http", "":
+		return dialHttpConnect(proxyLocation, host)
+	}
+	return nil, fmt.Errorf("unsupported proxy: %s", proxyLocation)
+}
+
+// tunnel to host by HTTP CONNECT
+func dialHttpConnect(proxyLocation *url.URL, host string) (net.Conn, error) {
+	conn, err := g_Dialer.Dial("tcp", proxyLocation.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: host},
+		Host:   host,
+		Header: http.Header{},
+	}
+	if proxyLocation.User != nil {
+		password, _ := proxyLocation.User.Password()
+		request.SetBasicAuth(proxyLocation.User.Username(), password)
+		request.Header.Set("Proxy-Authorization", request.Header.Get("Authorization"))
+		request.Header.Del("Authorization")
+	}
+	if err := request.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// the server sends nothing until TLS handshake, so buffered bytes are only of the response
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %s", response.Status)
+	}
+	return conn, nil
+}
+
+// address for local servers to listen ("127.0.0.1" if host is omitted)
+//
+// Servers stream messages without encryption, so other than loopback needs token (set by tokenEnv).
+func localListenAddress(address string, token string, tokenEnv string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if len(host) == 0 {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && host != "localhost" && len(token) == 0 {
+		return "", fmt.Errorf("set %s to listen at %s other than loopback", tokenEnv, address)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// compare token in constant time
+func equalsToken(given string, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
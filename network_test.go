@@ -0,0 +1,110 @@
+package main
+
+import "bufio"
+import "crypto/tls"
+import "encoding/pem"
+import "io/ioutil"
+import "net"
+import "net/http"
+import "net/http/httptest"
+import "net/url"
+import "path/filepath"
+import "testing"
+
+func TestProxyUrl(t *testing.T) {
+	g_Config.Network.Proxy = "socks5://localhost:1080"
+	defer func() { g_Config.Network.Proxy = "" }()
+
+	target, _ := url.Parse("wss://wss-primary.slack.com/websocket")
+	proxyLocation, err := proxyUrl(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxyLocation.String() != "socks5://localhost:1080" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "socks5://localhost:1080", proxyLocation)
+	}
+}
+
+func TestDialHttpConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	requested := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			requested <- err.Error()
+			return
+		}
+		requested <- request.Method + " " + request.Host
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	}()
+
+	proxyLocation, _ := url.Parse("http://" + listener.Addr().String())
+	conn, err := dialHttpConnect(proxyLocation, "slack.com:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if request := <-requested; request != "CONNECT slack.com:443" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "CONNECT slack.com:443", request)
+	}
+}
+
+func TestMakeTlsConfigCaFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+
+	config, err := makeTlsConfig(ConfigNetwork{CaFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g_TlsConfig = config
+	defer func() { g_TlsConfig = &tls.Config{} }()
+
+	response, err := newHttpClient().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if _, err := makeTlsConfig(ConfigNetwork{CaFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Errorf("expected error for missing CA file\n")
+	}
+}
+
+func TestLocalListenAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		token    string
+		expected string
+	}{
+		{":50051", "", "127.0.0.1:50051"},
+		{"127.0.0.1:50051", "", "127.0.0.1:50051"},
+		{"localhost:50051", "", "localhost:50051"},
+		{"[::1]:50051", "", "[::1]:50051"},
+		{"0.0.0.0:50051", "", ""},
+		{"example.com:50051", "", ""},
+		{"0.0.0.0:50051", "secret", "0.0.0.0:50051"},
+	}
+	for _, test := range tests {
+		actual, err := localListenAddress(test.address, test.token, GRPC_TOKEN_ENV)
+		if len(test.expected) == 0 && err == nil {
+			t.Errorf("%s: expected error, but \"%s\"\n", test.address, actual)
+		} else if len(test.expected) > 0 && actual != test.expected {
+			t.Errorf("%s: expected \"%s\", but \"%s\" (%v)\n", test.address, test.expected, actual, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import "bytes"
+import "encoding/json"
+import "log"
+
+//==============================
+// message pipeline
+//==============================
+
+// stage which modifies message, or drops it by returning false
+type MessageFilter interface {
+	Filter(message Message) (Message, bool)
+}
+
+type MessageFilterFunc func(message Message) (Message, bool)
+
+func (f MessageFilterFunc) Filter(message Message) (Message, bool) {
+	return f(message)
+}
+
+// stage which outputs messages passed through filters
+type MessageSink interface {
+	Write(message Message)
+}
+
+type MessageSinkFunc func(message Message)
+
+func (f MessageSinkFunc) Write(message Message) {
+	f(message)
+}
+
+// filters and sinks in order
+type Pipeline struct {
+	Filters []MessageFilter
+	Sinks   []MessageSink
+}
+
+// stages by name for [pipeline] filters and sinks
+var g_FilterStages = map[string]MessageFilter{}
+var g_SinkStages = map[string]MessageSink{}
+
+// stages of [pipeline] by default
+var DEFAULT_FILTERS = []string{"mute", "unescape", "rewrite", "filter", "pattern", "script", "plugin", "mentions-only", "dedupe"}
+var DEFAULT_SINKS = []string{"log", "archive", "digest", "speech", "stream", "webhook", "display"}
+
+var g_Pipeline = &Pipeline{}
+
+func init() {
+	g_FilterStages["mute"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		return message, !isMuted(message)
+	})
+	g_FilterStages["unescape"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		message.Text = unescape(message.Text)
+		return message, true
+	})
+	g_FilterStages["rewrite"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		message.Text = rewriteText(message.Text, g_RewriteRules)
+		return message, true
+	})
+	g_FilterStages["filter"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		return message, !isFilteredOut(message)
+	})
+	g_FilterStages["pattern"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		// kept if highlighted already by the handler (reminders)
+		message.Highlight = message.Highlight || matchAnyPatterns(message.Text, notificationPatterns(message.Channel))
+		return message, true
+	})
+	g_FilterStages["script"] = MessageFilterFunc(scriptMessage)
+	g_FilterStages["plugin"] = MessageFilterFunc(pluginMessage)
+	g_FilterStages["mentions-only"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		return message, !g_Config.Notification.MentionsOnly || message.Highlight || isMention(message) || isDirectMessage(message)
+	})
+	g_FilterStages["dedupe"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		return message, !isRepeated(message)
+	})
+
+	g_SinkStages["log"] = MessageSinkFunc(logMessage)
+	g_SinkStages["archive"] = MessageSinkFunc(archiveMessage)
+	g_SinkStages["digest"] = MessageSinkFunc(collectDigest)
+	g_SinkStages["speech"] = MessageSinkFunc(speakMessage)
+	g_SinkStages["stream"] = MessageSinkFunc(streamMessage)
+	g_SinkStages["webhook"] = MessageSinkFunc(postWebhook)
+	g_SinkStages["display"] = MessageSinkFunc(renderMessage)
+
+	g_Pipeline = NewPipeline(DEFAULT_FILTERS, DEFAULT_SINKS)
+}
+
+// pipeline of stages by name (unknown ones are skipped)
+func NewPipeline(filters []string, sinks []string) *Pipeline {
+	pipeline := &Pipeline{}
+	for _, name := range filters {
+		if filter, exist := g_FilterStages[name]; exist {
+			pipeline.Filters = append(pipeline.Filters, filter)
+		} else {
+			log.Printf("unknown filter in [pipeline]: %s", name)
+		}
+	}
+	for _, name := range sinks {
+		if sink, exist := g_SinkStages[name]; exist {
+			pipeline.Sinks = append(pipeline.Sinks, sink)
+		} else {
+			log.Printf("unknown sink in [pipeline]: %s", name)
+		}
+	}
+	return pipeline
+}
+
+// pipeline of [pipeline] or the default stages
+func configuredPipeline(config ConfigPipeline) *Pipeline {
+	filters := DEFAULT_FILTERS
+	if len(config.Filters) > 0 {
+		filters = config.Filters
+	}
+	sinks := DEFAULT_SINKS
+	if len(config.Sinks) > 0 {
+		sinks = config.Sinks
+	}
+	return NewPipeline(filters, sinks)
+}
+
+// pass message through filters, keep it for commands, and write it to sinks
+func (p *Pipeline) Process(message Message) {
+	for _, filter := range p.Filters {
+		var keep bool
+		if message, keep = filter.Filter(message); !keep {
+			return
+		}
+	}
+
+	rememberMessage(message)
+	markAsRead(message)
+	if message.Subtype != "message_changed" {
+		g_MessageStore.Add(message)
+	}
+
+	for _, sink := range p.Sinks {
+		sink.Write(message)
+	}
+}
+
+// POST message as JSON to [pipeline] webhook-url in background
+func postWebhook(message Message) {
+	url := g_Config.Pipeline.WebhookUrl
+	if len(url) == 0 {
+		return
+	}
+	data, err := json.Marshal(newJsonlMessage(message))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+
+	go func() {
+		response, err := g_HttpClient.Post(url, "application/json", bytes.NewReader(data))
+		if err != nil {
+			log.Printf("webhook: %v", err)
+			return
+		}
+		response.Body.Close()
+		if response.StatusCode/100 != 2 {
+			log.Printf("webhook: %s", response.Status)
+		}
+	}()
+}
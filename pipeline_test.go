@@ -0,0 +1,60 @@
+package main
+
+import "encoding/json"
+import "net/http"
+import "net/http/httptest"
+import "testing"
+import "time"
+
+func TestPipeline(t *testing.T) {
+	written := []string{}
+	g_FilterStages["test-drop"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		return message, message.User != "spammer"
+	})
+	g_FilterStages["test-upper"] = MessageFilterFunc(func(message Message) (Message, bool) {
+		message.Text = message.Text + "!"
+		return message, true
+	})
+	g_SinkStages["test-sink"] = MessageSinkFunc(func(message Message) {
+		written = append(written, message.Text)
+	})
+	defer func() {
+		delete(g_FilterStages, "test-drop")
+		delete(g_FilterStages, "test-upper")
+		delete(g_SinkStages, "test-sink")
+	}()
+
+	pipeline := NewPipeline([]string{"test-drop", "unknown", "test-upper"}, []string{"test-sink"})
+	if len(pipeline.Filters) != 2 {
+		t.Errorf("expected unknown stage skipped, but %d filters\n", len(pipeline.Filters))
+	}
+	pipeline.Process(Message{ChannelId: "C01", Ts: "1600000000.000100", User: "spammer", Text: "buy"})
+	pipeline.Process(Message{ChannelId: "C01", Ts: "1600000000.000200", User: "alice", Text: "hi"})
+
+	if len(written) != 1 || written[0] != "hi!" {
+		t.Errorf("unexpected written messages %v\n", written)
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	received := make(chan JsonlMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		message := JsonlMessage{}
+		json.NewDecoder(r.Body).Decode(&message)
+		received <- message
+	}))
+	defer server.Close()
+
+	g_Config.Pipeline.WebhookUrl = server.URL
+	defer func() { g_Config.Pipeline.WebhookUrl = "" }()
+	postWebhook(Message{Ts: "1600000000.000100", Channel: "general", User: "alice", Text: "hello"})
+
+	select {
+	case message := <-received:
+		if message.Text != "hello" {
+			t.Errorf("expected \"%s\", but \"%s\"\n", "hello", message.Text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("webhook is not called\n")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import "bufio"
+import "encoding/json"
+import "fmt"
+import "io"
+import "log"
+import "os"
+import "os/exec"
+import "time"
+
+//==============================
+// external plugins over stdin/stdout
+//==============================
+
+// time to wait for a reply not to freeze display by a stuck plugin
+const PLUGIN_TIMEOUT = 2 * time.Second
+
+// an event written to plugins as a line of JSON
+type PluginEvent struct {
+	Id      int           `json:"id"`
+	Type    string        `json:"type"`
+	Message *JsonlMessage `json:"message,omitempty"`
+}
+
+// a reply line from plugins with the id of the event
+type PluginDirective struct {
+	Id       int    `json:"id"`
+	Suppress bool   `json:"suppress,omitempty"`
+	Annotate string `json:"annotate,omitempty"` // displayed after the text
+	Notify   string `json:"notify,omitempty"`   // displayed as a notice
+}
+
+// running plugin process
+type Plugin struct {
+	Name    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	replies chan PluginDirective
+	lastId  int
+}
+
+var g_Plugins []*Plugin
+
+func startPlugin(command []string) (*Plugin, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("empty plugin command")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	plugin := &Plugin{Name: command[0], cmd: cmd, stdin: stdin, replies: make(chan PluginDirective, 16)}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			directive := PluginDirective{}
+			if err := json.Unmarshal(scanner.Bytes(), &directive); err != nil {
+				log.Printf("plugin %s: %v", plugin.Name, err)
+				continue
+			}
+			plugin.replies <- directive
+		}
+		close(plugin.replies)
+	}()
+	return plugin, nil
+}
+
+// send message and wait for the directive (zero if timed out or exited)
+func (p *Plugin) Process(message Message) PluginDirective {
+	p.lastId++
+	jsonl := newJsonlMessage(message)
+	data, err := json.Marshal(PluginEvent{Id: p.lastId, Type: "message", Message: &jsonl})
+	if err != nil {
+		log.Print(err)
+		return PluginDirective{}
+	}
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		log.Printf("plugin %s: %v", p.Name, err)
+		return PluginDirective{}
+	}
+
+	timeout := time.NewTimer(PLUGIN_TIMEOUT)
+	defer timeout.Stop()
+	for {
+		select {
+		case directive, ok := <-p.replies:
+			if !ok {
+				return PluginDirective{}
+			}
+			// skip late replies to events timed out
+			if directive.Id == p.lastId {
+				return directive
+			}
+		case <-timeout.C:
+			log.Printf("plugin %s: no reply in %s", p.Name, PLUGIN_TIMEOUT)
+			return PluginDirective{}
+		}
+	}
+}
+
+// close stdin to tell plugin to exit
+func (p *Plugin) Stop() {
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() { done <- p.cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(PLUGIN_TIMEOUT):
+		p.cmd.Process.Kill()
+	}
+}
+
+// start plugins of [[plugin]]
+func startPlugins() {
+	for _, config := range g_Config.Plugin {
+		plugin, err := startPlugin(config.Command)
+		if err != nil {
+			log.Printf("failed to start plugin %v: %v", config.Command, err)
+			continue
+		}
+		g_Plugins = append(g_Plugins, plugin)
+	}
+}
+
+func stopPlugins() {
+	for _, plugin := range g_Plugins {
+		plugin.Stop()
+	}
+	g_Plugins = nil
+}
+
+// apply directives of plugins to message, and whether to keep it
+func pluginMessage(message Message) (Message, bool) {
+	for _, plugin := range g_Plugins {
+		directive := plugin.Process(message)
+		if len(directive.Notify) > 0 {
+			printStatus(plugin.Name + ": " + directive.Notify)
+		}
+		if directive.Suppress {
+			return message, false
+		}
+		if len(directive.Annotate) > 0 {
+			message.Annotation = message.Annotation + " \033[36m" + directive.Annotate + "\033[0m"
+		}
+	}
+	return message, true
+}
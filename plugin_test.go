@@ -0,0 +1,35 @@
+package main
+
+import "os/exec"
+import "testing"
+
+// plugin suppressing messages from spammer and annotating the others
+const TEST_PLUGIN_SCRIPT = `while read line; do
+  id=$(echo "$line" | sed 's/^{"id":\([0-9]*\).*/\1/')
+  case "$line" in
+    *'"user":"spammer"'*) echo "{\"id\":$id,\"suppress\":true}" ;;
+    *) echo "{\"id\":$id,\"annotate\":\"seen\"}" ;;
+  esac
+done`
+
+func TestPlugin(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip(err)
+	}
+	plugin, err := startPlugin([]string{"sh", "-c", TEST_PLUGIN_SCRIPT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g_Plugins = []*Plugin{plugin}
+	defer stopPlugins()
+
+	if _, keep := pluginMessage(Message{Ts: "1600000000.000100", Channel: "general", User: "spammer", Text: "buy"}); keep {
+		t.Errorf("expected message suppressed\n")
+	}
+
+	message, keep := pluginMessage(Message{Ts: "1600000000.000200", Channel: "general", User: "alice", Text: "hi"})
+	expected := " \033[36mseen\033[0m"
+	if !keep || message.Annotation != expected {
+		t.Errorf("expected %q, but %q\n", expected, message.Annotation)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import "bytes"
+import "log"
+import "strings"
+
+import "golang.org/x/net/html"
+
+//==============================
+// Slack posts
+//==============================
+
+// whether file is a post (document created in Slack)
+func isPost(file SlackFile) bool {
+	return file.Mode == "space" || file.Mode == "post" || file.Filetype == "space" || file.Filetype == "post"
+}
+
+// replace preview of post with the content converted to text (up to limit bytes)
+func expandPost(file SlackFile, limit int) SlackFile {
+	if !isPost(file) || len(file.UrlPrivate) == 0 {
+		return file
+	}
+
+	body, err := openPrivateUrl(file.UrlPrivate)
+	if err != nil {
+		log.Print(err)
+		return file
+	}
+	defer body.Close()
+
+	content, truncated, err := readLimited(body, limit)
+	if err != nil {
+		log.Print(err)
+		return file
+	}
+	text, err := htmlToText(content)
+	if err != nil {
+		log.Print(err)
+		return file
+	}
+	file.Preview = text
+	file.PreviewIsTruncated = truncated
+	return file
+}
+
+// convert HTML into text with basic formatting
+//
+// Headings and bold are displayed in bold, list items with bullets, and
+// links with their URLs.
+func htmlToText(source string) (string, error) {
+	root, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return "", err
+	}
+
+	buffer := bytes.Buffer{}
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			// collapse white spaces
+			words := strings.Fields(node.Data)
+			if len(words) == 0 {
+				return
+			}
+			text := strings.Join(words, " ")
+			atLineStart := buffer.Len() == 0 || bytes.HasSuffix(buffer.Bytes(), []byte("\n"))
+			if strings.TrimLeft(node.Data, " \t\n") != node.Data && !atLineStart {
+				text = " " + text
+			}
+			if strings.TrimRight(node.Data, " \t\n") != node.Data {
+				text = text + " "
+			}
+			buffer.WriteString(text)
+			return
+		}
+		if node.Type == html.ElementNode {
+			switch node.Data {
+			case "head", "script", "style":
+				return
+			case "pre":
+				buffer.WriteString("\n" + textContent(node) + "\n")
+				return
+			}
+		}
+
+		prefix, suffix := htmlDecoration(node)
+		buffer.WriteString(prefix)
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		buffer.WriteString(suffix)
+	}
+	walk(root)
+
+	// squash blank lines
+	lines := []string{}
+	for _, line := range strings.Split(buffer.String(), "\n") {
+		line = strings.TrimRight(line, " ")
+		if len(line) == 0 && (len(lines) == 0 || len(lines[len(lines)-1]) == 0) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// text to surround element node
+func htmlDecoration(node *html.Node) (string, string) {
+	if node.Type != html.ElementNode {
+		return "", ""
+	}
+
+	switch node.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return "\n\033[1m", "\033[0m\n"
+	case "b", "strong":
+		return "\033[1m", "\033[0m"
+	case "i", "em":
+		return "\033[3m", "\033[0m"
+	case "li":
+		return "\n• ", ""
+	case "p", "div", "ul", "ol", "blockquote", "table", "tr":
+		return "\n", "\n"
+	case "br":
+		return "\n", ""
+	case "td", "th":
+		return "", "\t"
+	case "a":
+		for _, attr := range node.Attr {
+			if attr.Key == "href" && strings.HasPrefix(attr.Val, "http") {
+				return "", " (" + attr.Val + ")"
+			}
+		}
+	}
+	return "", ""
+}
+
+// concatenated text of node and descendants as is
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	builder := strings.Builder{}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		builder.WriteString(textContent(child))
+	}
+	return builder.String()
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestHtmlToText(t *testing.T) {
+	source := `<html><head><title>x</title></head><body>
+<h1>Release notes</h1>
+<p>See <a href="https://example.com/">the site</a> and <b>check</b> it.</p>
+<ul><li>first</li><li>second</li></ul>
+<pre>line 1
+  line 2</pre>
+</body></html>`
+
+	expected := "\033[1mRelease notes\033[0m\n" +
+		"\n" +
+		"See the site (https://example.com/) and \033[1mcheck\033[0m it.\n" +
+		"\n" +
+		"• first\n" +
+		"• second\n" +
+		"\n" +
+		"line 1\n" +
+		"  line 2"
+	result, err := htmlToText(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
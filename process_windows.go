@@ -0,0 +1,20 @@
+package main
+
+import "syscall"
+
+const PROCESS_QUERY_LIMITED_INFORMATION = 0x1000
+const STILL_ACTIVE = 259
+
+func processExists(pid int) bool {
+	process, err := syscall.OpenProcess(PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(process)
+
+	var exitCode uint32
+	if err := syscall.GetExitCodeProcess(process, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == STILL_ACTIVE
+}
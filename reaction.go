@@ -0,0 +1,159 @@
+package main
+
+import "fmt"
+import "net/url"
+import "sort"
+import "strings"
+
+//==============================
+// reactions
+//==============================
+
+// @see https://api.slack.com/methods/emoji.list
+type SlackEmojiListResponse struct {
+	Ok    bool
+	Emoji map[string]string // name and URL or "alias:name"
+}
+
+// custom emoji names (nil until fetched)
+var g_EmojiNames map[string]struct{}
+
+// frequently used standard emoji (emoji.list has only custom ones)
+var g_StandardEmojiNames = []string{
+	"+1", "-1", "clap", "eyes", "heart", "joy", "ok_hand", "pray", "raised_hands",
+	"rocket", "smile", "sob", "tada", "thinking_face", "thumbsdown", "thumbsup",
+	"white_check_mark", "x", "wave", "100", "fire", "bow", "sweat_smile",
+}
+
+func init() {
+	g_Commands["react"] = Command{":emoji: [ref]", "add reaction to the message", runReact}
+	g_Commands["emoji"] = Command{"[prefix]", "list emoji names", runEmoji}
+	g_EventHandlers.RegisterFunc("emoji_changed", "add", onEmojiAdded)
+	g_EventHandlers.RegisterFunc("emoji_changed", "remove", onEmojiRemoved)
+}
+
+func cacheEmojiList() error {
+	emojiResponse := SlackEmojiListResponse{}
+	if err := callApi("emoji.list", url.Values{}, &emojiResponse); err != nil {
+		return err
+	}
+
+	g_EmojiNames = map[string]struct{}{}
+	for _, name := range g_StandardEmojiNames {
+		g_EmojiNames[name] = struct{}{}
+	}
+	for name := range emojiResponse.Emoji {
+		g_EmojiNames[name] = struct{}{}
+	}
+	return nil
+}
+
+//==============================
+// type: "emoji_changed"
+//==============================
+
+// keep names up to date in long sessions (fetched at first if not yet)
+func onEmojiAdded(msg map[string]interface{}) {
+	if name, exist := msg["name"].(string); exist && g_EmojiNames != nil {
+		g_EmojiNames[name] = struct{}{}
+	}
+}
+
+func onEmojiRemoved(msg map[string]interface{}) {
+	if g_EmojiNames == nil {
+		return
+	}
+	names, _ := msg["names"].([]interface{})
+	for _, name := range names {
+		if name, exist := name.(string); exist {
+			delete(g_EmojiNames, name)
+		}
+	}
+}
+
+// emoji names starting with prefix (sorted)
+func findEmoji(prefix string) []string {
+	names := []string{}
+	for name := range g_EmojiNames {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// complete emoji name
+//
+// Unknown names are returned as is, since standard emoji are not listed.
+func completeEmoji(name string) (string, error) {
+	if g_EmojiNames == nil {
+		if err := cacheEmojiList(); err != nil {
+			return "", err
+		}
+	}
+
+	if _, exist := g_EmojiNames[name]; exist {
+		return name, nil
+	}
+	candidates := findEmoji(name)
+	switch len(candidates) {
+	case 0:
+		return name, nil
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous :%s: (%s)", name, formatEmojiNames(candidates))
+	}
+}
+
+func formatEmojiNames(names []string) string {
+	const MAX_NAMES = 20
+	more := ""
+	if len(names) > MAX_NAMES {
+		more = fmt.Sprintf(" and %d more", len(names)-MAX_NAMES)
+		names = names[:MAX_NAMES]
+	}
+	return ":" + strings.Join(names, ": :") + ":" + more
+}
+
+//==============================
+// /react, /emoji
+//==============================
+
+func runReact(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /react :emoji: [ref]")
+	}
+	name, err := completeEmoji(strings.Trim(args[0], ":"))
+	if err != nil {
+		return err
+	}
+	message, err := resolveRef(firstArg(args[1:]))
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("channel", message.ChannelId)
+	query.Set("timestamp", message.Ts)
+	query.Set("name", name)
+	if err := callApi("reactions.add", query, nil); err != nil {
+		if isApiError(err, "invalid_name") {
+			return fmt.Errorf(":%s: is not found", name)
+		}
+		return err
+	}
+	printStatus("reacted :" + name + ":")
+	return nil
+}
+
+func runEmoji(args []string) error {
+	if g_EmojiNames == nil {
+		if err := cacheEmojiList(); err != nil {
+			return err
+		}
+	}
+	printStatus(formatEmojiNames(findEmoji(strings.Trim(firstArg(args), ":"))))
+	return nil
+}
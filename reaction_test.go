@@ -0,0 +1,34 @@
+package main
+
+import "fmt"
+import "testing"
+
+func TestCompleteEmoji(t *testing.T) {
+	g_EmojiNames = map[string]struct{}{"party_parrot": {}, "party_blob": {}, "shipit": {}}
+
+	if name, err := completeEmoji("ship"); err != nil || name != "shipit" {
+		t.Errorf("expected \"shipit\", but \"%s\" (%v)\n", name, err)
+	}
+	if name, err := completeEmoji("smile"); err != nil || name != "smile" {
+		t.Errorf("expected \"smile\", but \"%s\" (%v)\n", name, err)
+	}
+	if _, err := completeEmoji("party"); err == nil {
+		t.Errorf("expected error for ambiguous name\n")
+	}
+}
+
+func TestEmojiChanged(t *testing.T) {
+	g_EmojiNames = map[string]struct{}{"party_parrot": {}, "shipit": {}}
+
+	dispatchEvent(map[string]interface{}{"type": "emoji_changed", "subtype": "add", "name": "picard_facepalm", "value": "https://example.com/picard.png"})
+	dispatchEvent(map[string]interface{}{"type": "emoji_changed", "subtype": "remove", "names": []interface{}{"shipit"}})
+
+	expected := "[party_parrot picard_facepalm]"
+	if result := fmt.Sprint(findEmoji("p")); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+	if result := findEmoji("ship"); len(result) != 0 {
+		t.Errorf("expected no emoji, but %v\n", result)
+	}
+	g_EmojiNames = nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import "errors"
+import "fmt"
+import "log"
+import "math/rand"
+import "time"
+
+import "golang.org/x/net/websocket"
+
+//==============================
+// reconnection
+//==============================
+
+// returned by receiveRoutine when the server says "goodbye"
+var g_ErrGoodbye = errors.New("goodbye from server")
+
+// returned by receiveRoutine when the workspace starts migration
+var g_ErrTeamMigration = errors.New("team migration started")
+
+// whether waiting for the workspace migration to finish
+var g_Migrating bool
+
+// interval of reconnection during migration, which takes minutes
+const MIGRATION_RETRY_WAIT = 30 * time.Second
+
+// URL to reconnect given by "reconnect_url" event
+var g_ReconnectUrl string
+var g_ReconnectUrlTime time.Time
+
+// reconnect_url is valid only for a short time
+const RECONNECT_URL_LIFETIME = 1 * time.Minute
+
+func onReconnectUrl(msg map[string]interface{}) {
+	if url, ok := msg["url"].(string); ok {
+		g_ReconnectUrl = url
+		g_ReconnectUrlTime = time.Now()
+	}
+}
+
+// connect by recent reconnect_url if any, otherwise by rtm.connect
+func reconnect(token string) (*websocket.Conn, error) {
+	if len(g_ReconnectUrl) > 0 && time.Since(g_ReconnectUrlTime) < RECONNECT_URL_LIFETIME {
+		wsUrl := g_ReconnectUrl
+		g_ReconnectUrl = ""
+		ws, err := dialWebsocket(wsUrl)
+		if err == nil {
+			return ws, nil
+		}
+		log.Print(err)
+	}
+	g_ReconnectUrl = ""
+	return connect(token)
+}
+
+// whether err is by workspace migration, and wait before reconnection if so
+//
+// Any errors until reconnected are expected during migration, so they are not
+// displayed one by one.
+func waitForMigration(err error) bool {
+	migration := err == g_ErrTeamMigration || isApiError(err, "migration_in_progress")
+	if !migration && !g_Migrating {
+		return false
+	}
+
+	if !g_Migrating {
+		g_Migrating = true
+		printStatus(fmt.Sprintf("Workspace migration is in progress. Reconnecting every %s until it finishes...", MIGRATION_RETRY_WAIT))
+	} else {
+		log.Printf(".")
+	}
+	// reconnect_url is of the old server
+	g_ReconnectUrl = ""
+	time.Sleep(MIGRATION_RETRY_WAIT)
+	return true
+}
+
+// called when connected
+func finishMigration() {
+	if g_Migrating {
+		g_Migrating = false
+		printStatus("Workspace migration has finished.")
+	}
+}
+
+//==============================
+// backoff
+//==============================
+
+// report how long offline at this interval
+const OFFLINE_REPORT_INTERVAL = 1 * time.Minute
+
+// exponential backoff of reconnection with jitter
+//
+// Jitter avoids all clients reconnecting at once after an outage.
+type Backoff struct {
+	Min  time.Duration
+	Max  time.Duration
+	Wait time.Duration // before jitter
+
+	Attempts     int // failed attempts since offline
+	OfflineSince time.Time
+	LastReport   time.Time
+
+	random *rand.Rand
+}
+
+func NewBackoff(min time.Duration, max time.Duration) *Backoff {
+	return &Backoff{
+		Min:    min,
+		Max:    max,
+		Wait:   min,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// wait to retry after a failure: between half and all of the current wait
+func (b *Backoff) Next() time.Duration {
+	now := time.Now()
+	if b.Attempts == 0 {
+		b.OfflineSince = now
+		b.LastReport = now
+	}
+	b.Attempts++
+
+	wait := b.Wait/2 + time.Duration(b.random.Int63n(int64(b.Wait/2)+1))
+	b.Wait = b.Wait * 2
+	if b.Wait > b.Max {
+		b.Wait = b.Max
+	}
+	return wait
+}
+
+// duration offline to report periodically (0 if not yet)
+func (b *Backoff) OfflineReport() time.Duration {
+	now := time.Now()
+	if b.Attempts == 0 || now.Sub(b.LastReport) < OFFLINE_REPORT_INTERVAL {
+		return 0
+	}
+	b.LastReport = now
+	return now.Sub(b.OfflineSince)
+}
+
+// called when connected, and return the duration offline
+func (b *Backoff) Reset() time.Duration {
+	offline := time.Duration(0)
+	if b.Attempts > 0 {
+		offline = time.Since(b.OfflineSince)
+	}
+	b.Wait = b.Min
+	b.Attempts = 0
+	return offline
+}
+
+// "4m32s"
+func formatOffline(offline time.Duration) string {
+	return offline.Round(time.Second).String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import "net/http/httptest"
+import "runtime"
+import "strings"
+import "testing"
+import "time"
+
+import "golang.org/x/net/websocket"
+
+func TestOnReconnectUrl(t *testing.T) {
+	dispatchEvent(map[string]interface{}{"type": "reconnect_url", "url": "wss://example.com/websocket/abc"})
+	if g_ReconnectUrl != "wss://example.com/websocket/abc" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "wss://example.com/websocket/abc", g_ReconnectUrl)
+	}
+	g_ReconnectUrl = ""
+}
+
+func TestWaitForMigration(t *testing.T) {
+	if waitForMigration(&SlackApiError{Method: "rtm.connect", Code: "invalid_auth"}) {
+		t.Errorf("expected false for other errors\n")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	backoff := NewBackoff(1*time.Second, 4*time.Second)
+	expected := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, max := range expected {
+		wait := backoff.Next()
+		if wait < max/2 || wait > max {
+			t.Errorf("attempt %d: expected %s to %s, but %s\n", i+1, max/2, max, wait)
+		}
+	}
+	if backoff.Attempts != 4 {
+		t.Errorf("expected 4 attempts, but %d\n", backoff.Attempts)
+	}
+
+	backoff.LastReport = backoff.LastReport.Add(-OFFLINE_REPORT_INTERVAL)
+	if backoff.OfflineReport() <= 0 {
+		t.Errorf("expected report after the interval\n")
+	}
+	if backoff.OfflineReport() != 0 {
+		t.Errorf("expected no report just after the last\n")
+	}
+
+	backoff.Reset()
+	if wait := backoff.Next(); wait > 1*time.Second {
+		t.Errorf("expected wait reset, but %s\n", wait)
+	}
+}
+
+func TestFormatOffline(t *testing.T) {
+	if actual := formatOffline(4*time.Minute + 32*time.Second + 400*time.Millisecond); actual != "4m32s" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "4m32s", actual)
+	}
+}
+
+func TestReceiveRoutineGoodbye(t *testing.T) {
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		// events after goodbye must not block the reader
+		for _, frame := range []string{`{"type":"goodbye"}`, `{"type":"pong"}`, `{"type":"pong"}`} {
+			websocket.Message.Send(ws, frame)
+		}
+		var discarded string
+		websocket.Message.Receive(ws, &discarded)
+	}))
+	defer server.Close()
+
+	before := runtime.NumGoroutine()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", "http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := receiveRoutine(ws); err != g_ErrGoodbye {
+		t.Errorf("expected goodbye, but %v\n", err)
+	}
+	ws.Close()
+
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected %d goroutines, but %d\n", before, after)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import "log"
+import "os"
+import "os/signal"
+import "syscall"
+import "time"
+
+//==============================
+// config reload
+//==============================
+
+// SIGHUP to reload config (received in receiveRoutine)
+var g_ReloadSignals = make(chan os.Signal, 1)
+
+// modification time of the loaded config file
+var g_ConfigModTime time.Time
+
+func handleReloadSignal() {
+	signal.Notify(g_ReloadSignals, syscall.SIGHUP)
+}
+
+func configModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func isConfigModified() bool {
+	if g_ConfigModTime.IsZero() {
+		// not loaded from file
+		return false
+	}
+	return !configModTime(g_ConfigPath).Equal(g_ConfigModTime)
+}
+
+// reload notification, display and rewrite settings without reconnecting
+//
+// Changes by commands are kept (/filter is not in config). Others (token, logging, etc) need restart.
+func reloadConfig() {
+	g_ConfigModTime = configModTime(g_ConfigPath)
+
+	config := defaultConfig()
+	if err := decodeConfigFile(g_ConfigPath, &config); err != nil {
+		log.Print(err)
+		return
+	}
+	applyFlagOverrides(&config)
+	// keep /mute, /follow, etc
+	for _, change := range g_NotificationChanges {
+		change(&config.Notification)
+	}
+
+	g_Config.Notification = config.Notification
+	g_Config.Display = config.Display
+	g_NotificationPatterns = compileNotificationPatterns(config.Notification.Patterns)
+	g_ChannelNotificationPatterns = compileChannelNotificationPatterns(config.Notification.Channel)
+	g_Config.Rewrite = config.Rewrite
+	g_RewriteRules = compileRewriteRules(config.Rewrite)
+	g_MessageStore.Capacity = config.Display.Scrollback
+	g_Renderer = newRenderer()
+
+	printStatus("config reloaded: " + g_ConfigPath)
+	if g_Tui != nil {
+		g_Tui.Redraw()
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+func TestReloadConfig(t *testing.T) {
+	g_ConfigPath = filepath.Join(t.TempDir(), "config.toml")
+	ioutil.WriteFile(g_ConfigPath, []byte("[general]\ntoken = \"xoxp-1\"\n[notification]\npatterns = [\"@alice\"]\n"), 0644)
+	if err := loadConfig(g_ConfigPath); err != nil {
+		t.Fatal(err)
+	}
+	if isConfigModified() {
+		t.Errorf("expected not modified\n")
+	}
+
+	g_NotificationChanges = nil
+	runMute([]string{"#random"})
+	defer func() { g_NotificationChanges = nil }()
+
+	ioutil.WriteFile(g_ConfigPath, []byte("[general]\ntoken = \"xoxp-2\"\n[notification]\npatterns = [\"@bob\"]\nmute-users = [\"slackbot\"]\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(g_ConfigPath, later, later)
+	if !isConfigModified() {
+		t.Errorf("expected modified\n")
+	}
+
+	reloadConfig()
+	if len(g_NotificationPatterns) != 1 || g_NotificationPatterns[0].String() != "@bob" {
+		t.Errorf("unexpected patterns %v\n", g_NotificationPatterns)
+	}
+	if len(g_Config.Notification.MuteUsers) != 1 || g_Config.General.Token != "xoxp-1" {
+		t.Errorf("unexpected config %+v\n", g_Config)
+	}
+	if len(g_Config.Notification.MuteChannels) != 1 || g_Config.Notification.MuteChannels[0] != "random" {
+		t.Errorf("expected /mute kept, but %v\n", g_Config.Notification.MuteChannels)
+	}
+	if isConfigModified() {
+		t.Errorf("expected not modified after reload\n")
+	}
+}
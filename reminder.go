@@ -0,0 +1,44 @@
+package main
+
+import "strings"
+
+//==============================
+// reminders
+//==============================
+
+// user id of Slackbot, which posts reminders
+const SLACKBOT_USER = "USLACKBOT"
+
+const REMINDER_MARK = "⏰ "
+
+func init() {
+	g_EventHandlers.RegisterFunc("message", "reminder_add", withLastTs(onMessageReminderAdd))
+}
+
+// reminder set by someone ("set up a reminder ..."), or Slackbot reminding of it
+func isReminder(msg map[string]interface{}) bool {
+	if subtype, _ := msg["subtype"].(string); subtype == "reminder_add" {
+		return true
+	}
+	user, _ := msg["user"].(string)
+	return user == SLACKBOT_USER && strings.HasPrefix(getText(msg), "Reminder: ")
+}
+
+// mark and highlight not to miss reminders in the stream
+func asReminder(message Message) Message {
+	message.Text = REMINDER_MARK + message.Text
+	message.Highlight = true
+	return message
+}
+
+//==============================
+// subtype: "reminder_add"
+//==============================
+
+func onMessageReminderAdd(msg map[string]interface{}) {
+	message := newMessage(msg)
+	message.User = getUserByMessage(msg)
+	message.Text = getText(msg)
+
+	printMessage(asReminder(message))
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestIsReminder(t *testing.T) {
+	cases := []struct {
+		msg      map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"subtype": "reminder_add", "user": "U01", "text": "set up a reminder"}, true},
+		{map[string]interface{}{"user": SLACKBOT_USER, "text": "Reminder: stand-up."}, true},
+		{map[string]interface{}{"user": SLACKBOT_USER, "text": "You have been added to #dev"}, false},
+		{map[string]interface{}{"user": "U01", "text": "Reminder: not from Slackbot"}, false},
+	}
+	for _, c := range cases {
+		if result := isReminder(c.msg); result != c.expected {
+			t.Errorf("expected %v, but %v for %v\n", c.expected, result, c.msg)
+		}
+	}
+}
+
+func TestAsReminder(t *testing.T) {
+	message := asReminder(Message{Text: "Reminder: stand-up."})
+	expected := REMINDER_MARK + "Reminder: stand-up."
+	if message.Text != expected || !message.Highlight {
+		t.Errorf("expected highlighted \"%s\", but %+v\n", expected, message)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import "fmt"
+import "log"
+
+//==============================
+// renderers
+//==============================
+
+// backend to display messages and statuses
+type Renderer interface {
+	Render(message Message)
+	Status(text string)
+	// display the header again before the next message
+	ResetHeader()
+}
+
+// renderer chosen by output format, --tui and display profile
+var g_Renderer Renderer = &ConsoleRenderer{}
+
+func newRenderer() Renderer {
+	switch {
+	case g_Tui != nil:
+		return &TuiRenderer{g_Tui}
+	case g_OutputMode == OUTPUT_MODE_JSONL:
+		return &JsonlRenderer{}
+	case g_Config.Display.Profile == DISPLAY_PROFILE_SCREEN_READER:
+		return &PlainRenderer{}
+	}
+	return &ConsoleRenderer{}
+}
+
+//==============================
+// ANSI console
+//==============================
+
+// messages grouped under colored headers of user, channel and time
+type ConsoleRenderer struct {
+	header HeaderState
+}
+
+func (r *ConsoleRenderer) Render(message Message) {
+	strTimestamp := parseTs(message.Ts).Format("2006/01/02 15:04:05")
+	if len(message.ThreadTs) > 0 {
+		strTimestamp = strTimestamp + " [at " + parseTs(message.ThreadTs).Format("2006/01/02 15:04:05") + "]"
+	}
+
+	user := fmt.Sprintf("@%-18s", message.UserType+message.User)
+	channel := fmt.Sprintf("#%-20s", message.Channel+sharedMarker(message.ChannelId))
+	text := renderedText(message)
+	if g_Config.Display.Hyperlinks {
+		user = linkUserName(user, message.User)
+		channel = linkChannelName(channel, message.ChannelId)
+		text = addHyperlinks(text)
+	} else {
+		text = shortenUrls(text, g_Config.Display.MaxUrlLength)
+	}
+
+	newChannel, newHeader := r.header.Next(message)
+	if newChannel {
+		// insert a empty line and header
+		printConsole(fmt.Sprintf("\n\033[93m%s %s %s\033[0m\n", user, channel, strTimestamp))
+		printChannelIntro(message.ChannelId)
+	} else if newHeader {
+		// display header
+		printConsole(fmt.Sprintf("\033[93m%s %s %s\033[0m\n", user, channel, strTimestamp))
+	}
+
+	if message.Highlight {
+		text = "\033[5;95m" + text + "\033[0m"
+	}
+
+	// display body
+	printConsole(text + message.Annotation + "\n")
+}
+
+func (r *ConsoleRenderer) Status(text string) {
+	fmt.Fprintln(g_Output, text)
+}
+
+func (r *ConsoleRenderer) ResetHeader() {
+	r.header.Reset()
+}
+
+// text with the diff of edit, truncated
+func renderedText(message Message) string {
+	text := message.Text
+	if len(message.PrevText) > 0 {
+		text = wordDiff(unescape(message.PrevText), text, !isStyleStripped())
+	}
+	return truncateText(text, g_Config.Display.MaxMessageLength)
+}
+
+//==============================
+// plain text
+//==============================
+
+// a sentence per message for screen readers and braille displays
+type PlainRenderer struct{}
+
+func (r *PlainRenderer) Render(message Message) {
+	printConsole(formatForScreenReader(message, renderedText(message)))
+}
+
+func (r *PlainRenderer) Status(text string) {
+	fmt.Fprintln(g_Output, stripEscapes(text))
+}
+
+func (r *PlainRenderer) ResetHeader() {
+}
+
+//==============================
+// JSONL
+//==============================
+
+// a line of JSON per message to stdout
+//
+// Status goes to stderr to keep stdout parsable.
+type JsonlRenderer struct{}
+
+func (r *JsonlRenderer) Render(message Message) {
+	printJsonl(message)
+}
+
+func (r *JsonlRenderer) Status(text string) {
+	log.Print(text)
+}
+
+func (r *JsonlRenderer) ResetHeader() {
+}
+
+//==============================
+// TUI
+//==============================
+
+type TuiRenderer struct {
+	tui *Tui
+}
+
+func (r *TuiRenderer) Render(message Message) {
+	r.tui.Show(message)
+}
+
+func (r *TuiRenderer) Status(text string) {
+	r.tui.SetStatus(text)
+}
+
+func (r *TuiRenderer) ResetHeader() {
+}
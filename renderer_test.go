@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestNewRenderer(t *testing.T) {
+	defer func() {
+		g_OutputMode = OUTPUT_MODE_TEXT
+		g_Config.Display.Profile = ""
+	}()
+
+	if _, ok := newRenderer().(*ConsoleRenderer); !ok {
+		t.Errorf("expected ConsoleRenderer by default\n")
+	}
+	g_Config.Display.Profile = DISPLAY_PROFILE_SCREEN_READER
+	if _, ok := newRenderer().(*PlainRenderer); !ok {
+		t.Errorf("expected PlainRenderer for screen readers\n")
+	}
+	g_OutputMode = OUTPUT_MODE_JSONL
+	if _, ok := newRenderer().(*JsonlRenderer); !ok {
+		t.Errorf("expected JsonlRenderer for JSONL output\n")
+	}
+}
+
+func TestRenderedText(t *testing.T) {
+	expected := "hello"
+	result := renderedText(Message{Text: "hello"})
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestRenderedTextNoColor(t *testing.T) {
+	g_Config = defaultConfig()
+	g_Config.Display.NoColor = true
+	defer func() { g_Config = defaultConfig() }()
+
+	expected := "deploy [-started-]{+finished+} at 10:00"
+	if result := renderedText(Message{Text: "deploy finished at 10:00", PrevText: "deploy started at 10:00"}); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
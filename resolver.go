@@ -0,0 +1,108 @@
+package main
+
+import "log"
+
+//==============================
+// name resolution in background
+//==============================
+
+const RESOLVER_WORKERS = 4
+const RESOLVER_QUEUE_SIZE = 100
+
+// function calling Web API in a worker, and returning a function to apply the
+// result in the receiving loop
+type ResolveFunc func() (func(), error)
+
+type resolveJob struct {
+	id    string
+	fetch ResolveFunc
+}
+
+// pool of workers to call Web API without blocking the receiving loop
+//
+// Caches are updated only by the receiving loop through Resolved, so that
+// handlers don't need locks.
+type Resolver struct {
+	jobs     chan resolveJob
+	pending  map[string]struct{} // ids requested and not yet applied
+	running  bool
+	Resolved chan func()
+}
+
+var g_Resolver = NewResolver()
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		jobs:     make(chan resolveJob, RESOLVER_QUEUE_SIZE),
+		pending:  map[string]struct{}{},
+		Resolved: make(chan func(), RESOLVER_QUEUE_SIZE),
+	}
+}
+
+func (r *Resolver) Start(workers int) {
+	r.running = true
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range r.jobs {
+				id := job.id
+				apply, err := job.fetch()
+				r.Resolved <- func() {
+					delete(r.pending, id)
+					if err != nil {
+						log.Printf("%s: %s", id, err)
+						g_NameCache.SetFailed(id)
+						return
+					}
+					apply()
+				}
+			}
+		}()
+	}
+}
+
+// whether workers are started (not in subcommands)
+func (r *Resolver) Running() bool {
+	return r.running
+}
+
+// resolve id by fetch unless requested already (call in the receiving loop)
+func (r *Resolver) Request(id string, fetch ResolveFunc) {
+	if _, exist := r.pending[id]; exist {
+		return
+	}
+
+	select {
+	case r.jobs <- resolveJob{id, fetch}:
+		r.pending[id] = struct{}{}
+	default:
+		// requested again by later message
+	}
+}
+
+// name of id by cache, resolving it by fetch if not cached or stale
+//
+// Ids failed to resolve are not retried for a while, and the raw id (or the
+// stale name) is displayed meanwhile.
+func resolveName(id string, fetch ResolveFunc) string {
+	name, cached, stale := g_NameCache.Lookup(id)
+	if (!cached || stale) && !g_NameCache.IsFailing(id) {
+		if g_Config.General.AsyncNames {
+			g_Resolver.Request(id, fetch)
+		} else {
+			// never prompt for scopes here, which blocks the receiving loop
+			apply, err := fetch()
+			if err != nil {
+				log.Print(err)
+				g_NameCache.SetFailed(id)
+			} else {
+				apply()
+			}
+			name, cached, _ = g_NameCache.Lookup(id)
+		}
+	}
+
+	if !cached {
+		return id
+	}
+	return name
+}
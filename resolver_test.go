@@ -0,0 +1,70 @@
+package main
+
+import "errors"
+import "testing"
+
+func TestResolver(t *testing.T) {
+	g_NameCache = NewNameCache(nil)
+	resolver := NewResolver()
+	resolver.Start(2)
+
+	fetched := 0
+	fetch := func() (func(), error) {
+		fetched++
+		return func() { g_NameCache.Set("U01", "alice") }, nil
+	}
+	resolver.Request("U01", fetch)
+	// pending
+	resolver.Request("U01", fetch)
+	resolver.Request("U02", func() (func(), error) { return nil, errors.New("user_not_found") })
+
+	for i := 0; i < 2; i++ {
+		apply := <-resolver.Resolved
+		apply()
+	}
+	if name := g_NameCache.Name("U01"); name != "alice" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "alice", name)
+	}
+	if fetched != 1 || len(resolver.pending) != 0 {
+		t.Errorf("unexpected fetched %d, pending %v\n", fetched, resolver.pending)
+	}
+}
+
+func TestGetUserAsync(t *testing.T) {
+	g_NameCache = NewNameCache(nil)
+	g_Config.General.AsyncNames = true
+	g_Resolver = NewResolver()
+	defer func() { g_Config.General.AsyncNames = false }()
+
+	// ID until resolved
+	if name := getUser("U01"); name != "U01" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "U01", name)
+	}
+	if _, exist := g_Resolver.pending["U01"]; !exist {
+		t.Errorf("expected U01 requested\n")
+	}
+}
+
+func TestResolveNameFailure(t *testing.T) {
+	g_NameCache = NewNameCache(nil)
+	fetched := 0
+	fetch := func() (func(), error) {
+		fetched++
+		return nil, &SlackApiError{Method: "users.info", Code: "user_not_found"}
+	}
+
+	// raw ID, and not retried during the cooldown
+	for i := 0; i < 3; i++ {
+		if name := resolveName("U01", fetch); name != "U01" {
+			t.Errorf("expected \"%s\", but \"%s\"\n", "U01", name)
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("expected fetched once, but %d\n", fetched)
+	}
+
+	g_NameCache.Set("U01", "alice")
+	if g_NameCache.IsFailing("U01") {
+		t.Errorf("expected failure cleared by Set\n")
+	}
+}
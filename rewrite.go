@@ -0,0 +1,37 @@
+package main
+
+import "log"
+import "regexp"
+
+//==============================
+// rewrite rules
+//==============================
+
+type RewriteRule struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// rules of [[rewrite]] in order
+var g_RewriteRules []RewriteRule
+
+func compileRewriteRules(configs []ConfigRewrite) []RewriteRule {
+	rules := []RewriteRule{}
+	for _, config := range configs {
+		pattern, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			log.Print(err)
+			continue
+		}
+		rules = append(rules, RewriteRule{pattern, config.Replacement})
+	}
+	return rules
+}
+
+// apply rules to text in order ($1 in replacements refers to a submatch)
+func rewriteText(text string, rules []RewriteRule) string {
+	for _, rule := range rules {
+		text = rule.Pattern.ReplaceAllString(text, rule.Replacement)
+	}
+	return text
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestRewriteText(t *testing.T) {
+	rules := compileRewriteRules([]ConfigRewrite{
+		{Pattern: `\[ci-runner-[0-9]+\] `, Replacement: "[ci] "},
+		{Pattern: `([a-z0-9-]+)\.internal\.example\.com`, Replacement: "<$1>"},
+		{Pattern: `(invalid`, Replacement: ""},
+		{Pattern: `\n*-- $`, Replacement: ""},
+	})
+	if len(rules) != 3 {
+		t.Fatalf("expected invalid rule skipped, but %d rules\n", len(rules))
+	}
+
+	expected := "[ci] build failed on <db-1>"
+	result := rewriteText("[ci-runner-42] build failed on db-1.internal.example.com\n\n-- ", rules)
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
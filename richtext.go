@@ -0,0 +1,153 @@
+package main
+
+import "fmt"
+import "strconv"
+import "strings"
+
+//==============================
+// rich_text blocks
+//==============================
+
+// text of rich_text blocks in msg (in the escaped form as the text field), and whether found
+//
+// @see https://api.slack.com/reference/block-kit/blocks#rich_text
+func getRichText(msg map[string]interface{}) (string, bool) {
+	blocks, _ := msg["blocks"].([]interface{})
+	texts := []string{}
+	for _, block := range blocks {
+		block, _ := block.(map[string]interface{})
+		if block["type"] != "rich_text" {
+			continue
+		}
+		elements, _ := block["elements"].([]interface{})
+		for _, element := range elements {
+			element, _ := element.(map[string]interface{})
+			if text := formatRichTextBlock(element); len(text) > 0 {
+				texts = append(texts, text)
+			}
+		}
+	}
+	if len(texts) == 0 {
+		return "", false
+	}
+	return strings.Join(texts, "\n"), true
+}
+
+func formatRichTextBlock(element map[string]interface{}) string {
+	children, _ := element["elements"].([]interface{})
+
+	switch element["type"] {
+	case "rich_text_section":
+		return formatRichTextElements(children)
+	case "rich_text_preformatted":
+		return "\033[36m" + formatRichTextElements(children) + "\033[0m"
+	case "rich_text_quote":
+		lines := strings.Split(formatRichTextElements(children), "\n")
+		for i, line := range lines {
+			lines[i] = "\033[90m│\033[0m " + line
+		}
+		return strings.Join(lines, "\n")
+	case "rich_text_list":
+		style, _ := element["style"].(string)
+		indent, _ := element["indent"].(float64)
+		offset, _ := element["offset"].(float64)
+		lines := []string{}
+		for i, child := range children {
+			child, _ := child.(map[string]interface{})
+			marker := "•"
+			if style == "ordered" {
+				marker = strconv.Itoa(int(offset)+i+1) + "."
+			}
+			lines = append(lines, strings.Repeat("  ", int(indent))+marker+" "+formatRichTextBlock(child))
+		}
+		return strings.Join(lines, "\n")
+	}
+	return ""
+}
+
+func formatRichTextElements(elements []interface{}) string {
+	builder := strings.Builder{}
+	for _, element := range elements {
+		element, _ := element.(map[string]interface{})
+		builder.WriteString(formatRichTextElement(element))
+	}
+	return builder.String()
+}
+
+func formatRichTextElement(element map[string]interface{}) string {
+	text := ""
+	switch element["type"] {
+	case "text":
+		text, _ = element["text"].(string)
+		text = escapeText(text)
+	case "link":
+		url, _ := element["url"].(string)
+		label, _ := element["text"].(string)
+		if len(label) > 0 {
+			text = "<" + url + "|" + escapeText(label) + ">"
+		} else {
+			text = "<" + url + ">"
+		}
+	case "emoji":
+		text = formatEmojiElement(element)
+	case "user":
+		id, _ := element["user_id"].(string)
+		text = "<@" + id + ">"
+	case "channel":
+		id, _ := element["channel_id"].(string)
+		text = "<#" + id + ">"
+	case "usergroup":
+		id, _ := element["usergroup_id"].(string)
+		text = "<!subteam^" + id + ">"
+	case "broadcast":
+		kind, _ := element["range"].(string)
+		text = "<!" + kind + ">"
+	case "date":
+		timestamp, _ := element["timestamp"].(float64)
+		format, _ := element["format"].(string)
+		fallback, _ := element["fallback"].(string)
+		text = fmt.Sprintf("<!date^%d^%s|%s>", int64(timestamp), format, fallback)
+	}
+
+	style, _ := element["style"].(map[string]interface{})
+	for _, s := range []struct {
+		name     string
+		sequence string
+	}{
+		{"bold", "\033[1m"},
+		{"italic", "\033[3m"},
+		{"strike", "\033[9m"},
+		{"code", "\033[36m"},
+	} {
+		if enabled, _ := style[s.name].(bool); enabled {
+			text = s.sequence + text + "\033[0m"
+		}
+	}
+	return text
+}
+
+// unicode of emoji, or :name:
+func formatEmojiElement(element map[string]interface{}) string {
+	name, _ := element["name"].(string)
+	code, _ := element["unicode"].(string)
+	if len(code) == 0 {
+		return ":" + name + ":"
+	}
+
+	builder := strings.Builder{}
+	for _, hex := range strings.Split(code, "-") {
+		r, err := strconv.ParseInt(hex, 16, 32)
+		if err != nil {
+			return ":" + name + ":"
+		}
+		builder.WriteRune(rune(r))
+	}
+	return builder.String()
+}
+
+// escape &, < and > as the text field
+func escapeText(text string) string {
+	text = strings.Replace(text, "&", "&amp;", -1)
+	text = strings.Replace(text, "<", "&lt;", -1)
+	return strings.Replace(text, ">", "&gt;", -1)
+}
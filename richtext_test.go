@@ -0,0 +1,37 @@
+package main
+
+import "encoding/json"
+import "testing"
+
+func TestGetRichText(t *testing.T) {
+	source := `{"blocks": [{"type": "rich_text", "elements": [
+		{"type": "rich_text_section", "elements": [
+			{"type": "text", "text": "hi "},
+			{"type": "user", "user_id": "U01"},
+			{"type": "text", "text": " see", "style": {"bold": true}},
+			{"type": "text", "text": " "},
+			{"type": "link", "url": "https://example.com", "text": "docs"},
+			{"type": "emoji", "name": "smile", "unicode": "1f604"}
+		]},
+		{"type": "rich_text_list", "style": "ordered", "indent": 1, "elements": [
+			{"type": "rich_text_section", "elements": [{"type": "text", "text": "a < b"}]},
+			{"type": "rich_text_section", "elements": [{"type": "emoji", "name": "custom"}]}
+		]},
+		{"type": "rich_text_quote", "elements": [{"type": "text", "text": "quoted"}]},
+		{"type": "rich_text_preformatted", "elements": [{"type": "text", "text": "code"}]}
+	]}]}`
+	msg := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(source), &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "hi <@U01>\033[1m see\033[0m <https://example.com|docs>😄\n" +
+		"  1. a &lt; b\n" +
+		"  2. :custom:\n" +
+		"\033[90m│\033[0m quoted\n" +
+		"\033[36mcode\033[0m"
+	result, exist := getRichText(msg)
+	if !exist || result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import "context"
+import "io"
+import "log"
+import "net"
+import "sync"
+
+import "google.golang.org/grpc"
+import "google.golang.org/grpc/codes"
+import "google.golang.org/grpc/metadata"
+import "google.golang.org/grpc/status"
+import "google.golang.org/protobuf/proto"
+import "google.golang.org/protobuf/reflect/protodesc"
+import "google.golang.org/protobuf/reflect/protoreflect"
+import "google.golang.org/protobuf/types/descriptorpb"
+import "google.golang.org/protobuf/types/dynamicpb"
+
+//==============================
+// gRPC streaming API
+//==============================
+
+// messages buffered for each subscriber, which is disconnected if it falls behind
+const RPC_CLIENT_BUFFER = 256
+
+// address to serve gRPC by --grpc
+var g_GrpcAddress string
+
+// token which clients send as "authorization: Bearer <token>", required to listen other than loopback
+const GRPC_TOKEN_ENV = "SLACKV_GRPC_TOKEN"
+
+var g_RpcServer *RpcServer
+
+var g_SubscribeDescriptor protoreflect.MessageDescriptor
+var g_MessageEventDescriptor protoreflect.MessageDescriptor
+
+// descriptor of slackv.proto, built without protoc
+func init() {
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	boolean := descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	field := func(name string, number int32, label *descriptorpb.FieldDescriptorProto_Label, fieldType *descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Label: label, Type: fieldType}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("slackv.proto"),
+		Package: proto.String("slackv"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Subscribe"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("channels", 1, repeated, str),
+					field("users", 2, repeated, str),
+					field("highlight_only", 3, optional, boolean),
+				},
+			},
+			{
+				Name: proto.String("MessageEvent"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("ts", 1, optional, str),
+					field("thread_ts", 2, optional, str),
+					field("channel_id", 3, optional, str),
+					field("channel", 4, optional, str),
+					field("user_type", 5, optional, str),
+					field("user", 6, optional, str),
+					field("text", 7, optional, str),
+					field("subtype", 8, optional, str),
+					field("highlight", 9, optional, boolean),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("Slackv"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:            proto.String("Stream"),
+						InputType:       proto.String(".slackv.Subscribe"),
+						OutputType:      proto.String(".slackv.MessageEvent"),
+						ClientStreaming: proto.Bool(true),
+						ServerStreaming: proto.Bool(true),
+					},
+				},
+			},
+		},
+	}
+
+	descriptor, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		panic(err)
+	}
+	g_SubscribeDescriptor = descriptor.Messages().ByName("Subscribe")
+	g_MessageEventDescriptor = descriptor.Messages().ByName("MessageEvent")
+}
+
+// filters by Subscribe
+type RpcFilter struct {
+	Channels      []string
+	Users         []string
+	HighlightOnly bool
+}
+
+func (f RpcFilter) Match(message Message) bool {
+	if f.HighlightOnly && !message.Highlight {
+		return false
+	}
+	if len(f.Channels) > 0 && !equalsAnyKeywords(message.Channel, f.Channels) {
+		return false
+	}
+	if len(f.Users) > 0 && !equalsAnyKeywords(message.User, f.Users) {
+		return false
+	}
+	return true
+}
+
+type rpcSubscriber struct {
+	messages chan Message
+	mutex    sync.Mutex
+	filter   RpcFilter
+}
+
+// gRPC server to stream messages to subscribers
+type RpcServer struct {
+	server      *grpc.Server
+	listener    net.Listener
+	mutex       sync.Mutex
+	subscribers map[*rpcSubscriber]struct{}
+}
+
+// reject streams without "authorization: Bearer <token>" (no check if token is empty)
+func rpcAuthInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if len(token) > 0 && !hasRpcToken(stream.Context(), token) {
+			return status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(srv, stream)
+	}
+}
+
+func hasRpcToken(ctx context.Context, token string) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if equalsToken(value, "Bearer "+token) {
+			return true
+		}
+	}
+	return false
+}
+
+func startRpcServer(address string, token string) (*RpcServer, error) {
+	address, err := localListenAddress(address, token, GRPC_TOKEN_ENV)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &RpcServer{
+		server:      grpc.NewServer(grpc.StreamInterceptor(rpcAuthInterceptor(token))),
+		listener:    listener,
+		subscribers: map[*rpcSubscriber]struct{}{},
+	}
+	s.server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "slackv.Slackv",
+		HandlerType: (*interface{})(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Stream",
+				Handler:       func(srv interface{}, stream grpc.ServerStream) error { return s.stream(stream) },
+				ServerStreams: true,
+				ClientStreams: true,
+			},
+		},
+		Metadata: "slackv.proto",
+	}, s)
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			log.Print(err)
+		}
+	}()
+	return s, nil
+}
+
+func (s *RpcServer) Stop() {
+	s.server.Stop()
+}
+
+// handle a call of Stream until the client disconnects
+func (s *RpcServer) stream(stream grpc.ServerStream) error {
+	// wait for the first Subscribe not to send messages filtered out
+	filter, err := receiveFilter(stream)
+	if err != nil {
+		return err
+	}
+	subscriber := &rpcSubscriber{messages: make(chan Message, RPC_CLIENT_BUFFER), filter: filter}
+
+	s.mutex.Lock()
+	s.subscribers[subscriber] = struct{}{}
+	s.mutex.Unlock()
+	defer s.remove(subscriber)
+
+	// filters can be changed while streaming
+	received := make(chan error, 1)
+	go func() {
+		for {
+			filter, err := receiveFilter(stream)
+			if err == io.EOF {
+				// no more filter updates, but keep streaming until cancelled
+				return
+			} else if err != nil {
+				received <- err
+				return
+			}
+			subscriber.mutex.Lock()
+			subscriber.filter = filter
+			subscriber.mutex.Unlock()
+		}
+	}()
+
+	for {
+		select {
+		case message, ok := <-subscriber.messages:
+			if !ok {
+				// too slow
+				return nil
+			}
+			if err := stream.SendMsg(newMessageEvent(message)); err != nil {
+				return err
+			}
+		case err := <-received:
+			return err
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+func (s *RpcServer) remove(subscriber *rpcSubscriber) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exist := s.subscribers[subscriber]; exist {
+		delete(s.subscribers, subscriber)
+		close(subscriber.messages)
+	}
+}
+
+// send message to subscribers of matching filters
+func (s *RpcServer) Broadcast(message Message) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for subscriber := range s.subscribers {
+		subscriber.mutex.Lock()
+		match := subscriber.filter.Match(message)
+		subscriber.mutex.Unlock()
+		if !match {
+			continue
+		}
+
+		select {
+		case subscriber.messages <- message:
+		default:
+			// not to block the receiving loop by a slow subscriber
+			delete(s.subscribers, subscriber)
+			close(subscriber.messages)
+		}
+	}
+}
+
+func receiveFilter(stream grpc.ServerStream) (RpcFilter, error) {
+	subscribe := dynamicpb.NewMessage(g_SubscribeDescriptor)
+	if err := stream.RecvMsg(subscribe); err != nil {
+		return RpcFilter{}, err
+	}
+
+	fields := g_SubscribeDescriptor.Fields()
+	strings := func(name protoreflect.Name) []string {
+		list := subscribe.Get(fields.ByName(name)).List()
+		values := []string{}
+		for i := 0; i < list.Len(); i++ {
+			values = append(values, list.Get(i).String())
+		}
+		return values
+	}
+	return RpcFilter{
+		Channels:      strings("channels"),
+		Users:         strings("users"),
+		HighlightOnly: subscribe.Get(fields.ByName("highlight_only")).Bool(),
+	}, nil
+}
+
+func newMessageEvent(message Message) *dynamicpb.Message {
+	event := dynamicpb.NewMessage(g_MessageEventDescriptor)
+	fields := g_MessageEventDescriptor.Fields()
+	set := func(name protoreflect.Name, value string) {
+		if len(value) > 0 {
+			event.Set(fields.ByName(name), protoreflect.ValueOfString(value))
+		}
+	}
+	set("ts", message.Ts)
+	set("thread_ts", message.ThreadTs)
+	set("channel_id", message.ChannelId)
+	set("channel", message.Channel)
+	set("user_type", message.UserType)
+	set("user", message.User)
+	set("text", stripAnsi(message.Text))
+	set("subtype", message.Subtype)
+	if message.Highlight {
+		event.Set(fields.ByName("highlight"), protoreflect.ValueOfBool(true))
+	}
+	return event
+}
@@ -0,0 +1,217 @@
+package main
+
+import "context"
+import "fmt"
+import "io/ioutil"
+import "regexp"
+import "strings"
+import "testing"
+import "time"
+
+import "google.golang.org/grpc"
+import "google.golang.org/grpc/codes"
+import "google.golang.org/grpc/credentials/insecure"
+import "google.golang.org/grpc/metadata"
+import "google.golang.org/grpc/status"
+import "google.golang.org/protobuf/reflect/protoreflect"
+import "google.golang.org/protobuf/types/dynamicpb"
+
+func TestRpcFilter(t *testing.T) {
+	message := Message{Channel: "dev", User: "alice", Highlight: false}
+	tests := []struct {
+		filter   RpcFilter
+		expected bool
+	}{
+		{RpcFilter{}, true},
+		{RpcFilter{Channels: []string{"dev"}}, true},
+		{RpcFilter{Channels: []string{"general"}}, false},
+		{RpcFilter{Users: []string{"alice"}}, true},
+		{RpcFilter{Users: []string{"bob"}}, false},
+		{RpcFilter{HighlightOnly: true}, false},
+	}
+	for _, test := range tests {
+		if actual := test.filter.Match(message); actual != test.expected {
+			t.Errorf("expected %v, but %v for %v\n", test.expected, actual, test.filter)
+		}
+	}
+}
+
+func TestRpcServer(t *testing.T) {
+	server, err := startRpcServer("127.0.0.1:0", "")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Stop()
+
+	conn, err := grpc.Dial(server.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, "/slackv.Slackv/Stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscribe := dynamicpb.NewMessage(g_SubscribeDescriptor)
+	channels := subscribe.Mutable(g_SubscribeDescriptor.Fields().ByName("channels")).List()
+	channels.Append(protoreflect.ValueOfString("dev"))
+	if err := stream.SendMsg(subscribe); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the subscriber registered
+	for i := 0; i < 100; i++ {
+		server.mutex.Lock()
+		count := len(server.subscribers)
+		server.mutex.Unlock()
+		if count > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.Broadcast(Message{Channel: "general", User: "bob", Text: "filtered"})
+	server.Broadcast(Message{Channel: "dev", User: "alice", Ts: "1600000000.000100", Text: "hello"})
+
+	event := dynamicpb.NewMessage(g_MessageEventDescriptor)
+	if err := stream.RecvMsg(event); err != nil {
+		t.Fatal(err)
+	}
+	text := event.Get(g_MessageEventDescriptor.Fields().ByName("text")).String()
+	if text != "hello" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "hello", text)
+	}
+}
+
+func TestRpcServerToken(t *testing.T) {
+	server, err := startRpcServer("127.0.0.1:0", "secret")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Stop()
+
+	conn, err := grpc.Dial(server.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	for _, token := range []string{"", "wrong"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if len(token) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, "/slackv.Slackv/Stream")
+		if err == nil {
+			err = stream.RecvMsg(dynamicpb.NewMessage(g_MessageEventDescriptor))
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%q: expected Unauthenticated, but %v\n", token, err)
+		}
+		cancel()
+	}
+}
+
+// the descriptor built in rpc.go matches slackv.proto
+func TestRpcDescriptorMatchesProto(t *testing.T) {
+	data, err := ioutil.ReadFile("slackv.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := string(data)
+
+	rpc := regexp.MustCompile(`rpc (\w+)\((stream )?(\w+)\) returns \((stream )?(\w+)\)`).FindStringSubmatch(source)
+	if rpc == nil || rpc[1] != "Stream" || rpc[2] == "" || rpc[3] != "Subscribe" || rpc[4] == "" || rpc[5] != "MessageEvent" {
+		t.Errorf("unexpected rpc in slackv.proto: %v\n", rpc)
+	}
+
+	descriptors := map[string]protoreflect.MessageDescriptor{
+		"Subscribe":    g_SubscribeDescriptor,
+		"MessageEvent": g_MessageEventDescriptor,
+	}
+	messageRegexp := regexp.MustCompile(`(?s)message (\w+) \{(.*?)\n\}`)
+	fieldRegexp := regexp.MustCompile(`(repeated )?(\w+) (\w+) = (\d+);`)
+	messages := messageRegexp.FindAllStringSubmatch(source, -1)
+	if len(messages) != len(descriptors) {
+		t.Errorf("expected %d messages, but %d in slackv.proto\n", len(descriptors), len(messages))
+	}
+	for _, message := range messages {
+		descriptor, exist := descriptors[message[1]]
+		if !exist {
+			t.Errorf("unexpected message %s in slackv.proto\n", message[1])
+			continue
+		}
+
+		fields := []string{}
+		for _, field := range fieldRegexp.FindAllStringSubmatch(message[2], -1) {
+			fields = append(fields, field[0])
+		}
+		built := []string{}
+		for i := 0; i < descriptor.Fields().Len(); i++ {
+			field := descriptor.Fields().Get(i)
+			repeated := ""
+			if field.Cardinality() == protoreflect.Repeated {
+				repeated = "repeated "
+			}
+			built = append(built, fmt.Sprintf("%s%s %s = %d;", repeated, field.Kind(), field.Name(), field.Number()))
+		}
+		if strings.Join(fields, "\n") != strings.Join(built, "\n") {
+			t.Errorf("%s: expected\n%s\nbut\n%s\n", message[1], strings.Join(fields, "\n"), strings.Join(built, "\n"))
+		}
+	}
+}
+
+func TestRpcServerAfterCloseSend(t *testing.T) {
+	server, err := startRpcServer("127.0.0.1:0", "")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Stop()
+
+	conn, err := grpc.Dial(server.listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, "/slackv.Slackv/Stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// subscribe once without further filters
+	if err := stream.SendMsg(dynamicpb.NewMessage(g_SubscribeDescriptor)); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the server may receive EOF before or after the first broadcast
+	received := make(chan string, 1)
+	go func() {
+		event := dynamicpb.NewMessage(g_MessageEventDescriptor)
+		if err := stream.RecvMsg(event); err != nil {
+			received <- err.Error()
+			return
+		}
+		received <- event.Get(g_MessageEventDescriptor.Fields().ByName("text")).String()
+	}()
+	for {
+		server.Broadcast(Message{Channel: "dev", User: "alice", Text: "hello"})
+		select {
+		case text := <-received:
+			if text != "hello" {
+				t.Errorf("expected \"%s\", but \"%s\"\n", "hello", text)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import "context"
+import "fmt"
+import "log"
+import "time"
+
+import lua "github.com/yuin/gopher-lua"
+
+//==============================
+// Lua scripting hook
+//==============================
+
+// state of [script] path (nil if not configured)
+var g_Script *lua.LState
+
+// name of the function called for each message
+const SCRIPT_MESSAGE_HOOK = "on_message"
+
+// on_message is aborted after this not to block the receiving loop
+const SCRIPT_TIMEOUT = 500 * time.Millisecond
+
+// load the script which defines on_message(msg)
+//
+// The script can call slackv.notify(text) and slackv.log(text).
+func loadScript(path string) error {
+	state := lua.NewState()
+	slackv := state.NewTable()
+	state.SetField(slackv, "notify", state.NewFunction(func(L *lua.LState) int {
+		printStatus(L.CheckString(1))
+		return 0
+	}))
+	state.SetField(slackv, "log", state.NewFunction(func(L *lua.LState) int {
+		log.Print(L.CheckString(1))
+		return 0
+	}))
+	state.SetGlobal("slackv", slackv)
+
+	if err := state.DoFile(path); err != nil {
+		state.Close()
+		return err
+	}
+	if state.GetGlobal(SCRIPT_MESSAGE_HOOK).Type() != lua.LTFunction {
+		state.Close()
+		return fmt.Errorf("%s: function %s(msg) is not defined", path, SCRIPT_MESSAGE_HOOK)
+	}
+
+	if g_Script != nil {
+		g_Script.Close()
+	}
+	g_Script = state
+	return nil
+}
+
+// message as a table for scripts
+func messageToTable(state *lua.LState, message Message) *lua.LTable {
+	table := state.NewTable()
+	fields := map[string]string{
+		"ts":         message.Ts,
+		"thread_ts":  message.ThreadTs,
+		"channel_id": message.ChannelId,
+		"channel":    message.Channel,
+		"user_type":  message.UserType,
+		"user":       message.User,
+		"text":       message.Text,
+		"subtype":    message.Subtype,
+		"annotation": message.Annotation,
+	}
+	for key, value := range fields {
+		state.SetField(table, key, lua.LString(value))
+	}
+	state.SetField(table, "highlight", lua.LBool(message.Highlight))
+	return table
+}
+
+// call on_message(msg) to modify message, and whether to keep it
+//
+// The script drops message by returning false, and modifies it by changing
+// text, highlight or annotation of msg. msg.tag is displayed after the text.
+func runScript(state *lua.LState, message Message) (Message, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), SCRIPT_TIMEOUT)
+	defer cancel()
+	state.SetContext(ctx)
+	defer state.RemoveContext()
+
+	table := messageToTable(state, message)
+	err := state.CallByParam(lua.P{Fn: state.GetGlobal(SCRIPT_MESSAGE_HOOK), NRet: 1, Protect: true}, table)
+	if err != nil {
+		log.Print(err)
+		return message, true
+	}
+	result := state.Get(-1)
+	state.Pop(1)
+	if result == lua.LFalse {
+		return message, false
+	}
+
+	if text, ok := state.GetField(table, "text").(lua.LString); ok {
+		message.Text = string(text)
+	}
+	if annotation, ok := state.GetField(table, "annotation").(lua.LString); ok {
+		message.Annotation = string(annotation)
+	}
+	if tag, ok := state.GetField(table, "tag").(lua.LString); ok && len(tag) > 0 {
+		message.Annotation = message.Annotation + " \033[36m[" + string(tag) + "]\033[0m"
+	}
+	message.Highlight = lua.LVAsBool(state.GetField(table, "highlight"))
+	return message, true
+}
+
+// apply [script] to message if configured
+func scriptMessage(message Message) (Message, bool) {
+	if g_Script == nil {
+		return message, true
+	}
+	return runScript(g_Script, message)
+}
@@ -0,0 +1,85 @@
+package main
+
+import "io/ioutil"
+import "path/filepath"
+import "testing"
+import "time"
+
+func TestRunScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hook.lua")
+	script := `
+function on_message(msg)
+  if msg.user == "spammer" then
+    return false
+  end
+  if msg.channel == "alerts" then
+    msg.text = string.gsub(msg.text, "^%[FIRING%] ", "")
+    msg.highlight = true
+    msg.tag = "alert"
+  end
+end
+`
+	if err := ioutil.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadScript(path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		g_Script.Close()
+		g_Script = nil
+	}()
+
+	if _, keep := scriptMessage(Message{Channel: "general", User: "spammer", Text: "buy"}); keep {
+		t.Errorf("expected message dropped\n")
+	}
+
+	message, keep := scriptMessage(Message{Channel: "alerts", User: "monitor", Text: "[FIRING] disk full"})
+	if !keep || message.Text != "disk full" || !message.Highlight {
+		t.Errorf("unexpected message %+v\n", message)
+	}
+	expected := " \033[36m[alert]\033[0m"
+	if message.Annotation != expected {
+		t.Errorf("expected %q, but %q\n", expected, message.Annotation)
+	}
+}
+
+func TestLoadScriptWithoutHook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.lua")
+	ioutil.WriteFile(path, []byte("x = 1\n"), 0644)
+	if err := loadScript(path); err == nil {
+		t.Errorf("expected error without on_message\n")
+	}
+}
+
+func TestRunScriptTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "loop.lua")
+	script := `
+function on_message(msg)
+  msg.text = "modified"
+  while true do end
+end
+`
+	if err := ioutil.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadScript(path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		g_Script.Close()
+		g_Script = nil
+	}()
+
+	// kept as it is, and the state is usable again
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		message, keep := scriptMessage(Message{Channel: "general", User: "alice", Text: "hi"})
+		if !keep || message.Text != "hi" {
+			t.Errorf("unexpected message %+v\n", message)
+		}
+		if elapsed := time.Since(start); elapsed > 2*SCRIPT_TIMEOUT {
+			t.Errorf("expected aborted in %s, but %s\n", SCRIPT_TIMEOUT, elapsed)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import "fmt"
+import "net/url"
+import "strconv"
+
+//==============================
+// /search (search.messages)
+//==============================
+
+// @see https://api.slack.com/methods/search.messages
+type SlackSearchMessagesResponse struct {
+	Ok       bool
+	Messages SlackSearchMessages
+}
+
+type SlackSearchMessages struct {
+	Total   int
+	Matches []SlackSearchMatch
+}
+
+type SlackSearchMatch struct {
+	Channel   SlackChannel
+	User      string
+	Username  string
+	Ts        string
+	Text      string
+	Permalink string
+}
+
+const SEARCH_COUNT = 20
+
+func init() {
+	g_Commands["search"] = Command{"<query>", "search messages in the workspace", runSearch}
+}
+
+func searchMessages(query string, count int) (SlackSearchMessages, error) {
+	values := url.Values{}
+	values.Set("query", query)
+	values.Set("count", strconv.Itoa(count))
+	values.Set("sort", "timestamp")
+
+	searchResponse := SlackSearchMessagesResponse{}
+	if err := callApi("search.messages", values, &searchResponse); err != nil {
+		return SlackSearchMessages{}, err
+	}
+
+	return searchResponse.Messages, nil
+}
+
+func runSearch(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: /search <query>")
+	}
+
+	// keep quotes of phrases
+	result, err := searchMessages(g_CommandText, SEARCH_COUNT)
+	if err != nil {
+		return err
+	}
+
+	// display oldest first like live messages
+	for i := len(result.Matches) - 1; i >= 0; i-- {
+		match := result.Matches[i]
+		message := Message{
+			Ts:        match.Ts,
+			ChannelId: match.Channel.Id,
+			Channel:   match.Channel.Name,
+			User:      match.Username,
+			Text:      unescape(match.Text),
+		}
+		if len(match.User) > 0 {
+			message.User = getUser(match.User)
+		}
+		message.Highlight = matchAnyPatterns(message.Text, notificationPatterns(message.Channel))
+
+		renderMessage(message)
+		rememberMessage(message)
+	}
+
+	printStatus(fmt.Sprintf("%d of %d messages", len(result.Matches), result.Total))
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import "bufio"
+import "encoding/json"
+import "fmt"
+import "log"
+import "net"
+import "os"
+import "strings"
+import "sync"
+import "time"
+
+//==============================
+// streaming server of JSONL
+//==============================
+
+// lines buffered for each client, which is disconnected if it falls behind
+const STREAM_CLIENT_BUFFER = 256
+
+// token which clients send as the first line, required to listen other than loopback
+const SERVE_TOKEN_ENV = "SLACKV_SERVE_TOKEN"
+
+// wait for the token line from a client
+const STREAM_AUTH_TIMEOUT = 10 * time.Second
+
+// server streaming messages as JSONL to clients of unix socket or TCP (--serve)
+type StreamServer struct {
+	listener net.Listener
+	token    string // no check if empty
+	mutex    sync.Mutex
+	clients  map[chan []byte]struct{}
+}
+
+var g_StreamServer *StreamServer
+var g_ServeAddress string
+
+// listen "unix:/path" or "tcp:host:port"
+func listenStream(address string, token string) (net.Listener, error) {
+	parts := strings.SplitN(address, ":", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("invalid address: %s (unix:/path or tcp:host:port)", address)
+	}
+
+	switch parts[0] {
+	case "unix":
+		// remove the socket left by the last run
+		if info, err := os.Lstat(parts[1]); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(parts[1])
+		}
+		return net.Listen("unix", parts[1])
+	case "tcp":
+		tcpAddress, err := localListenAddress(parts[1], token, SERVE_TOKEN_ENV)
+		if err != nil {
+			return nil, err
+		}
+		return net.Listen("tcp", tcpAddress)
+	}
+	return nil, fmt.Errorf("invalid address: %s (unix:/path or tcp:host:port)", address)
+}
+
+func startStreamServer(address string, token string) (*StreamServer, error) {
+	listener, err := listenStream(address, token)
+	if err != nil {
+		return nil, err
+	}
+	server := &StreamServer{listener: listener, token: token, clients: map[chan []byte]struct{}{}}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server, nil
+}
+
+func (s *StreamServer) Close() {
+	s.listener.Close()
+}
+
+// write lines to conn until disconnected
+func (s *StreamServer) serve(conn net.Conn) {
+	defer conn.Close()
+	if !s.authenticate(conn) {
+		return
+	}
+
+	lines := make(chan []byte, STREAM_CLIENT_BUFFER)
+	s.mutex.Lock()
+	s.clients[lines] = struct{}{}
+	s.mutex.Unlock()
+
+	// detect disconnection by read
+	closed := make(chan struct{})
+	go func() {
+		buffer := make([]byte, 256)
+		for {
+			if _, err := conn.Read(buffer); err != nil {
+				close(closed)
+				return
+			}
+		}
+	}()
+
+	defer s.remove(lines)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				// too slow
+				return
+			}
+			if _, err := conn.Write(line); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// whether the first line of conn is the token
+func (s *StreamServer) authenticate(conn net.Conn) bool {
+	if len(s.token) == 0 {
+		return true
+	}
+
+	conn.SetReadDeadline(time.Now().Add(STREAM_AUTH_TIMEOUT))
+	defer conn.SetReadDeadline(time.Time{})
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return equalsToken(strings.TrimRight(line, "\r\n"), s.token)
+}
+
+func (s *StreamServer) remove(lines chan []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exist := s.clients[lines]; exist {
+		delete(s.clients, lines)
+		close(lines)
+	}
+}
+
+// send message to all clients as a line of JSON
+func (s *StreamServer) Broadcast(message Message) {
+	data, err := json.Marshal(newJsonlMessage(message))
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	line := append(data, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for lines := range s.clients {
+		select {
+		case lines <- line:
+		default:
+			// not to block the receiving loop by a slow client
+			delete(s.clients, lines)
+			close(lines)
+		}
+	}
+}
+
+// stream message if --serve, --grpc or [web]
+func streamMessage(message Message) {
+	if g_StreamServer != nil {
+		g_StreamServer.Broadcast(message)
+	}
+	if g_RpcServer != nil {
+		g_RpcServer.Broadcast(message)
+	}
+	if g_WebServer != nil {
+		g_WebServer.Broadcast(message)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import "bufio"
+import "net"
+import "path/filepath"
+import "strings"
+import "testing"
+import "time"
+
+func TestStreamServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slackv.sock")
+	server, err := startStreamServer("unix:"+path, "")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Close()
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// wait for the client registered
+	for i := 0; i < 100; i++ {
+		server.mutex.Lock()
+		count := len(server.clients)
+		server.mutex.Unlock()
+		if count > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.Broadcast(Message{Channel: "dev", User: "alice", Ts: "1600000000.000100", Text: "hello"})
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, "\"text\":\"hello\"") {
+		t.Errorf("unexpected line \"%s\"\n", line)
+	}
+}
+
+func TestListenStreamInvalid(t *testing.T) {
+	for _, address := range []string{"", "unix:", "udp:127.0.0.1:0", "/tmp/slackv.sock", "tcp:0.0.0.0:0"} {
+		if _, err := listenStream(address, ""); err == nil {
+			t.Errorf("expected error for \"%s\"\n", address)
+		}
+	}
+}
+
+func TestStreamServerToken(t *testing.T) {
+	server, err := startStreamServer("tcp:127.0.0.1:0", "secret")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer server.Close()
+
+	for _, token := range []string{"wrong", "secret"} {
+		conn, err := net.Dial("tcp", server.listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte(token + "\n"))
+
+		// only the client of the token is registered
+		for i := 0; i < 100; i++ {
+			server.mutex.Lock()
+			count := len(server.clients)
+			server.mutex.Unlock()
+			if count > 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		server.Broadcast(Message{Channel: "dev", User: "alice", Ts: "1600000000.000100", Text: "hello"})
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		if expected := token == "secret"; strings.Contains(line, "\"text\":\"hello\"") != expected {
+			t.Errorf("%s: unexpected line \"%s\"\n", token, line)
+		}
+	}
+}
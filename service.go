@@ -0,0 +1,114 @@
+package main
+
+import "fmt"
+import "log"
+import "os"
+import "path/filepath"
+
+import "slackv/console"
+
+//==============================
+// Windows service
+//==============================
+
+// log file of service in the directory of config file
+const SERVICE_LOG_FILE = "slackv-service.log"
+
+// run by the service control manager (--service)
+var g_RunAsService bool
+
+// service name for the profile
+func serviceName() string {
+	if len(g_ProfileName) > 0 {
+		return "slackv-" + g_ProfileName
+	}
+	return "slackv"
+}
+
+// slackv service install|uninstall
+func runServiceCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: slackv service install|uninstall")
+	}
+
+	switch args[0] {
+	case "install":
+		command, err := serviceCommandLine()
+		if err != nil {
+			return err
+		}
+		if err := console.InstallService(serviceName(), "slackv ("+serviceName()+")", command); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Installed service %s: %s\n", serviceName(), command)
+		return nil
+	case "uninstall":
+		if err := console.UninstallService(serviceName()); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Uninstalled service %s\n", serviceName())
+		return nil
+	}
+	return fmt.Errorf("usage: slackv service install|uninstall")
+}
+
+// command line for the service with absolute paths
+func serviceCommandLine() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if len(g_ConfigPath) == 0 {
+		return "", fmt.Errorf("config file is not found (the service needs the token in it)")
+	}
+	configPath, err := filepath.Abs(g_ConfigPath)
+	if err != nil {
+		return "", err
+	}
+
+	command := fmt.Sprintf("\"%s\" --service --config \"%s\"", executable, configPath)
+	if len(g_ProfileName) > 0 {
+		command = command + " --profile " + g_ProfileName
+	}
+	return command, nil
+}
+
+// run headless as service, logging to file
+//
+// The service starts in the system directory, so relative paths in the config
+// are of the directory of the config file.
+func startService() error {
+	if g_UseTui {
+		return fmt.Errorf("--tui is not available for service")
+	}
+
+	configPath, err := filepath.Abs(g_ConfigPath)
+	if err != nil {
+		return err
+	}
+	g_ConfigPath = configPath
+	if err := os.Chdir(filepath.Dir(configPath)); err != nil {
+		return err
+	}
+
+	logFile, err := os.OpenFile(SERVICE_LOG_FILE, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	log.SetOutput(logFile)
+
+	go func() {
+		err := console.RunService(serviceName(), func() {
+			log.Print("Stopping service")
+			flushState()
+			releaseLock()
+			closeDumpFile()
+			console.StopService()
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	}()
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import "strings"
+import "testing"
+
+func TestServiceCommandLine(t *testing.T) {
+	g_ConfigPath = "config.toml"
+	g_ProfileName = "work"
+	defer func() { g_ConfigPath = ""; g_ProfileName = "" }()
+
+	command, err := serviceCommandLine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(command, " --service --config \"") || !strings.HasSuffix(command, "config.toml\" --profile work") {
+		t.Errorf("unexpected command line \"%s\"\n", command)
+	}
+	if serviceName() != "slackv-work" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "slackv-work", serviceName())
+	}
+}
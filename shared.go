@@ -0,0 +1,63 @@
+package main
+
+import "net/url"
+import "strings"
+
+//==============================
+// Slack Connect (shared channels)
+//==============================
+
+// @see https://api.slack.com/methods/team.info
+type SlackTeamInfoResponse struct {
+	Ok   bool
+	Team SlackTeam
+}
+
+// external team ids keyed by id of externally shared channel
+var g_SharedChannels = map[string][]string{}
+
+func rememberSharedChannel(channel SlackChannel) {
+	if !channel.IsExtShared {
+		return
+	}
+	teamIds := []string{}
+	for _, teamId := range channel.SharedTeamIds {
+		if teamId != g_Team.Id {
+			teamIds = append(teamIds, teamId)
+		}
+	}
+	g_SharedChannels[channel.Id] = teamIds
+}
+
+func fetchTeamName(teamId string) (string, error) {
+	query := url.Values{}
+	query.Set("team", teamId)
+
+	teamResponse := SlackTeamInfoResponse{}
+	if err := callApi("team.info", query, &teamResponse); err != nil {
+		return "", err
+	}
+	return teamResponse.Team.Name, nil
+}
+
+// name of team (the id if failed, which is retried later)
+func getTeam(teamId string) string {
+	return resolveName(teamId, func() (func(), error) {
+		name, err := fetchTeamName(teamId)
+		return func() { g_NameCache.Set(teamId, name) }, err
+	})
+}
+
+// " ⇄ external team names" for externally shared channel, or ""
+func sharedMarker(channelId string) string {
+	teamIds, exist := g_SharedChannels[channelId]
+	if !exist {
+		return ""
+	}
+
+	names := []string{}
+	for _, teamId := range teamIds {
+		names = append(names, getTeam(teamId))
+	}
+	return strings.TrimRight(" ⇄ "+strings.Join(names, ", "), " ")
+}
@@ -0,0 +1,36 @@
+package main
+
+import "net/http"
+import "testing"
+
+func TestSharedMarker(t *testing.T) {
+	g_Team = SlackTeam{Id: "T01", Name: "team"}
+	g_NameCache = NewNameCache(map[string]string{"T02": "Acme"})
+	g_SharedChannels = map[string][]string{}
+
+	rememberSharedChannel(SlackChannel{Id: "C01", IsShared: true, IsExtShared: true, SharedTeamIds: []string{"T01", "T02"}})
+	rememberSharedChannel(SlackChannel{Id: "C02", IsShared: true, SharedTeamIds: []string{"T01"}})
+
+	if result := sharedMarker("C01"); result != " ⇄ Acme" {
+		t.Errorf("expected \" ⇄ Acme\", but \"%s\"\n", result)
+	}
+	if result := sharedMarker("C02"); result != "" {
+		t.Errorf("expected \"\", but \"%s\"\n", result)
+	}
+}
+
+func TestGetTeamFailed(t *testing.T) {
+	savedClient := g_HttpClient
+	defer func() { g_HttpClient = savedClient }()
+	g_HttpClient = &http.Client{Transport: offlineTransport{}}
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{})
+
+	// the id is displayed, but not cached as the name
+	if result := getTeam("T03"); result != "T03" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "T03", result)
+	}
+	if _, cached := g_NameCache.Get("T03"); cached || !g_NameCache.IsFailing("T03") {
+		t.Errorf("expected T03 failing and not cached\n")
+	}
+}
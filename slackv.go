@@ -1,18 +1,19 @@
 package main
 
 import "encoding/json"
+import "flag"
 import "fmt"
 import "html"
-import "io/ioutil"
 import "log"
-import "net/http"
 import "net/url"
+import "os"
+import "os/signal"
 import "regexp"
 import "strconv"
 import "strings"
+import "syscall"
 import "time"
 
-import "github.com/BurntSushi/toml"
 import "golang.org/x/net/websocket"
 
 import "slackv/console"
@@ -24,16 +25,191 @@ import "slackv/console"
 type Config struct {
 	General      ConfigGeneral
 	Notification ConfigNotification
+	Display      ConfigDisplay
+	Logging      ConfigLogging
+	Archive      ConfigArchive
+	Files        ConfigFiles
+	Health       ConfigHealth
+	Web          ConfigWeb
+	Digest       ConfigDigest
+	Dedupe       ConfigDedupe
+	Rewrite      []ConfigRewrite
+	Script       ConfigScript
+	Plugin       []ConfigPlugin
+	Pipeline     ConfigPipeline
+	Network      ConfigNetwork
 }
 
 type ConfigGeneral struct {
-	Token string
+	Token      string
+	BotToken   string `toml:"bot-token"` // for API calls which only it has the scope of
+	ClientId   string `toml:"client-id"` // to guide re-authorization
+	CatchUp    bool   `toml:"catch-up"`  // display messages missed since last exit
+	StateFile  string `toml:"state-file"`
+	MarkAsRead bool   `toml:"mark-as-read"` // mark displayed messages as read
+	LockFile   string `toml:"lock-file"`    // not to run multiple instances
+
+	UnreadSummary bool `toml:"unread-summary"` // display unread counts on connect
+	FetchUnread   bool `toml:"fetch-unread"`   // display unread messages on connect
+
+	ClientSecret string `toml:"client-secret"` // for "slackv login"
+	RedirectPort int    `toml:"redirect-port"` // of local server to receive OAuth redirect
+
+	// resolve names in background, and display IDs until resolved
+	AsyncNames bool `toml:"async-names"`
+	// seconds to refresh cached names of users, channels and bots (0 for never)
+	NameTtl int `toml:"name-ttl"`
 }
 
 type ConfigNotification struct {
 	Patterns     []string
 	MuteChannels []string `toml:"mute-channels"`
 	MuteUsers    []string `toml:"mute-users"`
+	// hide messages of these subtypes ("bot_message", "channel_join", etc)
+	MuteSubtypes []string `toml:"mute-subtypes"`
+	// display only these channels if not empty
+	FollowChannels []string `toml:"follow-channels"`
+	// display only messages matching patterns or mentioning you
+	MentionsOnly bool `toml:"mentions-only"`
+	// speak highlighted messages and DMs aloud (SAPI, say or espeak)
+	Speak bool
+	// print summary of the day at "15:04" ("" to disable)
+	DailySummary string `toml:"daily-summary"`
+	// rules for channels by name ("#name" or "name")
+	Channel map[string]ChannelNotification
+}
+
+type ChannelNotification struct {
+	// highlight by these instead of the above if set ([] for no highlights)
+	Patterns []string
+	// hide messages of the channel
+	Mute bool
+	// hide messages of these users in the channel
+	MuteUsers []string `toml:"mute-users"`
+}
+
+type ConfigLogging struct {
+	Directory  string
+	PerChannel bool   `toml:"per-channel"`
+	Rotate     string // "", "daily", "size" or "dated"
+	MaxSize    int64  `toml:"max-size"` // bytes
+	MaxBackups int    `toml:"max-backups"`
+}
+
+type ConfigArchive struct {
+	// JSONL file to store displayed messages for searching
+	Path string
+}
+
+type ConfigNetwork struct {
+	// "http://host:port" or "socks5://host:port" (default: HTTP_PROXY, HTTPS_PROXY and NO_PROXY)
+	Proxy string
+	// PEM file of CA certificates trusted in addition to the system ones
+	CaFile string `toml:"ca-file"`
+	// don't verify server certificates (insecure, only for debugging)
+	InsecureSkipVerify bool `toml:"insecure-skip-verify"`
+	// max seconds to wait between reconnection attempts
+	MaxReconnectWait int `toml:"max-reconnect-wait"`
+}
+
+type ConfigHealth struct {
+	// address of HTTP server for /healthz (e.g. "127.0.0.1:8977", "" to disable)
+	Listen string
+	// unhealthy if no event for this seconds while connected (0 for unlimited)
+	MaxEventAge int `toml:"max-event-age"`
+}
+
+type ConfigWeb struct {
+	// address of HTTP server for the web UI (e.g. "127.0.0.1:8978", "" to disable)
+	Listen string
+	// channels to serve Atom feed at /feed/<channel>.atom from [archive]
+	FeedChannels []string `toml:"feed-channels"`
+}
+
+type ConfigDigest struct {
+	// send highlights and DMs by email every this hours (0 to disable)
+	Hours    int
+	SmtpHost string `toml:"smtp-host"`
+	SmtpPort int    `toml:"smtp-port"`
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+type ConfigDedupe struct {
+	// display this many identical bot messages in a row, and collapse the rest (0 to disable)
+	Threshold int
+	// seconds between identical messages to be regarded as repetition
+	Window int
+	// rules for channels by name (0 to follow the above)
+	Channels map[string]DedupeRule
+}
+
+type DedupeRule struct {
+	Threshold int
+	Window    int
+}
+
+type ConfigRewrite struct {
+	// regexp to replace in message text
+	Pattern string
+	// "$1" refers to a submatch ("" to remove)
+	Replacement string
+}
+
+type ConfigScript struct {
+	// Lua script defining on_message(msg) to drop or modify messages
+	Path string
+}
+
+type ConfigPlugin struct {
+	// executable and arguments, which reads events and writes directives as JSON lines
+	Command []string
+}
+
+type ConfigPipeline struct {
+	// names of stages in order (default: all)
+	Filters []string
+	Sinks   []string
+	// URL to POST each message as JSON by "webhook" sink
+	WebhookUrl string `toml:"webhook-url"`
+}
+
+type ConfigFiles struct {
+	// directory to save files by /download
+	Directory string
+	// display whole content of snippets instead of truncated preview
+	FetchSnippets bool `toml:"fetch-snippets"`
+	// display content of posts instead of only the title
+	FetchPosts bool `toml:"fetch-posts"`
+	// max bytes of fetched snippet or post
+	SnippetLimit int `toml:"snippet-limit"`
+}
+
+type ConfigDisplay struct {
+	// "default", "ascii-only" or "screen-reader"
+	Profile string
+	// number of messages kept in memory for TUI
+	Scrollback int
+	// max characters of attachments (0 for unlimited)
+	MaxAttachmentLength int `toml:"max-attachment-length"`
+	// max characters of displayed messages (0 for unlimited)
+	MaxMessageLength int `toml:"max-message-length"`
+	// "label-url" or "label" for <https://example.com|label>
+	LinkFormat string `toml:"link-format"`
+	// shorten URLs longer than this to "host/…/tail" (0 for unlimited)
+	MaxUrlLength int `toml:"max-url-length"`
+	// emit clickable links (OSC 8) if the terminal supports them
+	Hyperlinks bool
+	// show counts of unseen messages in the terminal title
+	Title bool
+	// also set the tmux window name
+	TmuxTitle bool `toml:"tmux-title"`
+	// display without colors and styles (also by NO_COLOR environment variable)
+	NoColor bool `toml:"no-color"`
+	// display members joining and leaving channels
+	Membership bool
 }
 
 //==============================
@@ -62,8 +238,13 @@ type SlackUsersInfoResponse struct {
 }
 
 type SlackTeam struct {
-	Id   string
-	Name string
+	Id     string
+	Name   string
+	Domain string
+}
+
+type SlackTopic struct {
+	Value string `json:"value"`
 }
 
 // @see https://api.slack.com/types/channel
@@ -73,6 +254,19 @@ type SlackChannel struct {
 	User      string `json:"user"` // for Direct Message
 	IsMember  bool   `json:"is_member"`
 	IsPrivate bool   `json:"is_private"`
+	IsMpim    bool   `json:"is_mpim"`
+
+	Topic   SlackTopic `json:"topic"`
+	Purpose SlackTopic `json:"purpose"`
+
+	// for Slack Connect
+	IsShared      bool     `json:"is_shared"`
+	IsExtShared   bool     `json:"is_ext_shared"`
+	SharedTeamIds []string `json:"shared_team_ids"`
+
+	// only for members
+	LastRead           string `json:"last_read"`
+	UnreadCountDisplay int    `json:"unread_count_display"`
 }
 
 type SlackConversationsInfoResponse struct {
@@ -120,9 +314,28 @@ type SlackIm struct {
 	UserId string `json:"user"`
 }
 
+// @see https://api.slack.com/types/file
+type SlackFile struct {
+	Id                 string `json:"id"`
+	Name               string `json:"name"`
+	Title              string `json:"title"`
+	Filetype           string `json:"filetype"`
+	Mode               string `json:"mode"`
+	UrlPrivate         string `json:"url_private"`
+	Preview            string `json:"preview"`
+	PreviewIsTruncated bool   `json:"preview_is_truncated"`
+}
+
 type SlackBot struct {
-	Id   string
-	Name string
+	Id    string
+	Name  string
+	AppId string `json:"app_id"`
+}
+
+// @see https://api.slack.com/methods/bots.info
+type SlackBotsInfoResponse struct {
+	Ok  bool
+	Bot SlackBot
 }
 
 type SlackSession struct {
@@ -133,10 +346,38 @@ type SlackSession struct {
 	Team  SlackTeam
 }
 
+//==============================
+// internal structures
+//==============================
+
+// a message to display
+type Message struct {
+	Ts         string // Slack timestamp, which is also the message id
+	ThreadTs   string // Slack timestamp of the parent message
+	ChannelId  string
+	Channel    string
+	UserType   string
+	User       string
+	Text       string
+	Annotation string
+	Subtype    string
+	Highlight  bool        // matches any notification patterns
+	Files      []SlackFile // shared files
+	PrevText   string      // text before edited
+}
+
 //==============================
 // internal settings
 //==============================
 
+// environment variable of token
+const TOKEN_ENV = "SLACKV_TOKEN"
+
+const (
+	LINK_FORMAT_LABEL_URL = "label-url"
+	LINK_FORMAT_LABEL     = "label"
+)
+
 var g_IgnoreMessageTypes = map[string]struct{}{
 	"bot_added":           struct{}{},
 	"channel_joined":      struct{}{},
@@ -149,8 +390,7 @@ var g_IgnoreMessageTypes = map[string]struct{}{
 	"group_marked":        struct{}{},
 	"im_marked":           struct{}{},
 	"perf_change":         struct{}{},
-	"reaction_added":      struct{}{},
-	"reaction_removed":    struct{}{},
+	"pong":                struct{}{},
 	"thread_marked":       struct{}{},
 	"user_change":         struct{}{},
 	"user_huddle_changed": struct{}{},
@@ -159,6 +399,7 @@ var g_IgnoreMessageTypes = map[string]struct{}{
 }
 var g_InfoMessageTypes = map[string]struct{}{
 	"channel_created":      struct{}{},
+	"im_created":           struct{}{},
 	"message":              struct{}{},
 	"user_profile_changed": struct{}{},
 }
@@ -168,53 +409,210 @@ var g_InfoMessageTypes = map[string]struct{}{
 //==============================
 
 // maps user-id, channel-id, etc and name
-var g_IdNameMap map[string]string
-
-var g_LastUser = ""
-var g_LastChannel = ""
-var g_LastThreadTs = time.Unix(0, 0)
+var g_NameCache = NewNameCache(nil)
 
 var g_MentionPattern = regexp.MustCompile(`<@([^>|]+)(\|([^>]*))?>`)
 var g_ChannelPattern = regexp.MustCompile(`<#([^>|]+)(\|([^>]*))?>`)
 var g_UserGroupPattern = regexp.MustCompile(`<!subteam\^([^>|]+)(\|([^>]*))?>`)
 var g_KeywordPattern = regexp.MustCompile(`<!([^>|]+)(\|([^>]*))?>`)
+var g_ContactPattern = regexp.MustCompile(`<(mailto|tel):([^>|]*)(\|([^>]*))?>`)
+var g_LinkPattern = regexp.MustCompile(`<([a-zA-Z][-+.a-zA-Z0-9]*:[^>|]*)(\|([^>]*))?>`)
 var g_NotificationPatterns []*regexp.Regexp
 
 var g_Config Config
+var g_Team SlackTeam
+var g_Self SlackUser
+
+var g_OutputMode string
+var g_UseTui bool
+var g_ConfigPath string
 
 //==============================
 // entry point
 //==============================
 
 func main() {
-	console.Initialize()
+	flag.StringVar(&g_OutputMode, "output", OUTPUT_MODE_TEXT, "output format (text, jsonl)")
+	flag.BoolVar(&g_UseTui, "tui", false, "full screen interface with channel list")
+	defineOverrideFlags()
+	flag.StringVar(&g_ProfileName, "profile", "", "use [profile.<name>] in the config file")
+	flag.StringVar(&g_ServeAddress, "serve", "", "stream messages as JSONL to clients of unix:/path or tcp:[host]:port (default host: 127.0.0.1)")
+	flag.StringVar(&g_GrpcAddress, "grpc", "", "serve gRPC streaming API (slackv.proto) at [host]:port (default host: 127.0.0.1)")
+	flag.StringVar(&g_DumpEventsPath, "dump-events", "", "append raw events from websocket to the file (for bug reports)")
+	flag.BoolVar(&g_RunAsService, "service", false, "run as Windows service (registered by \"slackv service install\")")
+	flag.StringVar(&g_ConfigPath, "config", "", "config file (default: config.toml in the working directory, $XDG_CONFIG_HOME/slackv or ~/.config/slackv)")
+	flag.Parse()
+
+	if len(g_ConfigPath) == 0 {
+		g_ConfigPath = findConfigFile(configDirectories())
+	}
+
+	if g_OutputMode != OUTPUT_MODE_TEXT && g_OutputMode != OUTPUT_MODE_JSONL {
+		log.Fatalf("unknown output format: %s", g_OutputMode)
+	}
+	if g_UseTui && g_OutputMode != OUTPUT_MODE_TEXT {
+		log.Fatal("--tui is available only for text output")
+	}
+
+	// subcommands
+	switch flag.Arg(0) {
+	case "generate-manifest":
+		// config is optional to generate manifest
+		if err := loadConfig(g_ConfigPath); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		name := "slackv"
+		if flag.NArg() > 1 {
+			name = flag.Arg(1)
+		}
+		if err := generateManifest(name); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "login":
+		if err := loadConfig(g_ConfigPath); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		if err := runLogin(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "auth":
+		if err := runAuth(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "search":
+		if err := loadConfig(g_ConfigPath); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		if err := searchArchive(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "stats":
+		if err := loadConfig(g_ConfigPath); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		if err := runStats(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "service":
+		if err := runServiceCommand(flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if g_RunAsService {
+		if err := startService(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := console.Initialize(); err != nil && !console.SupportsAnsi() {
+		log.Printf("ANSI escape sequences are not supported, so colors are disabled: %s", err)
+	}
 	defer console.Finalize()
+	if g_UseTui && !console.SupportsAnsi() {
+		log.Fatal("--tui needs a terminal supporting ANSI escape sequences")
+	}
 
-	g_IdNameMap = map[string]string{}
+	err := loadConfig(g_ConfigPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	if err := requireToken(err); err != nil {
+		log.Fatal(err)
+	}
 
-	err := loadConfig("config.toml")
-	if err != nil {
+	if err := acquireLock(g_Config.General.LockFile); err != nil {
 		log.Fatal(err)
-		return
 	}
+	defer releaseLock()
+	handleExitSignals()
+	handleReloadSignal()
 
-	fmt.Println("Connecting...")
-	waitNS := 1 * time.Second
+	if len(g_DumpEventsPath) > 0 {
+		if err := openDumpFile(g_DumpEventsPath); err != nil {
+			log.Fatal(err)
+		}
+		defer closeDumpFile()
+	}
+	if len(g_Config.Health.Listen) > 0 {
+		startHealthServer(g_Config.Health.Listen)
+	}
+	startWatchdog()
+	if len(g_ServeAddress) > 0 {
+		g_StreamServer, err = startStreamServer(g_ServeAddress, os.Getenv(SERVE_TOKEN_ENV))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer g_StreamServer.Close()
+	}
+	if len(g_Config.Web.Listen) > 0 {
+		g_WebServer, err = startWebServer(g_Config.Web.Listen, os.Getenv(WEB_TOKEN_ENV))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer g_WebServer.Close()
+	}
+	if len(g_GrpcAddress) > 0 {
+		g_RpcServer, err = startRpcServer(g_GrpcAddress, os.Getenv(GRPC_TOKEN_ENV))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer g_RpcServer.Stop()
+	}
+	// also for topics even if names are resolved synchronously
+	g_Resolver.Start(RESOLVER_WORKERS)
+	startPlugins()
+	defer stopPlugins()
+	detectTokenScopes()
+
+	if g_Config.General.CatchUp {
+		if err := loadState(g_Config.General.StateFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	g_MessageStore.Capacity = g_Config.Display.Scrollback
+
+	startInputReader()
+	if g_UseTui {
+		startTui()
+		defer stopTui()
+	}
+
+	printStatus("Connecting...")
+	maxWait := time.Duration(g_Config.Network.MaxReconnectWait) * time.Second
+	if maxWait < time.Second {
+		maxWait = time.Second
+	}
+	backoff := NewBackoff(1*time.Second, maxWait)
 
 	var lastError error
 
 	for {
-		ws, err := connect(g_Config.General.Token)
+		ws, err := reconnect(g_Config.General.Token)
 		if err != nil {
 			goto L_Error
 		}
 		defer ws.Close()
 
-		waitNS = 1 * time.Second
+		g_Health.SetConnected(true)
+		finishMigration()
+		if offline := backoff.Reset(); offline > 0 {
+			log.Printf("Reconnected after offline for %s", formatOffline(offline))
+		}
 		lastError = nil
 
 		err = cacheUserGroups()
-		if err != nil {
+		if _, isApiError := err.(*SlackApiError); isApiError {
+			// user groups are optional
+			log.Print(err)
+		} else if err != nil {
 			ws.Close()
 			goto L_Error
 		}
@@ -227,6 +625,18 @@ func main() {
 
 	L_Error:
 
+		g_Health.SetConnected(false)
+		if err == g_ErrGoodbye {
+			// the server will close the connection soon, so reconnect now
+			log.Printf("Reconnecting...")
+			lastError = nil
+			continue
+		}
+		if waitForMigration(err) {
+			lastError = nil
+			continue
+		}
+
 		if !errorEquals(err, lastError) {
 			log.Print(err)
 			log.Printf("Connecting...")
@@ -235,14 +645,29 @@ func main() {
 			log.Printf(".")
 		}
 
-		time.Sleep(waitNS)
-		waitNS = waitNS * 2
-		if waitNS > 15*time.Second {
-			waitNS = 15 * time.Second
+		wait := backoff.Next()
+		if offline := backoff.OfflineReport(); offline > 0 {
+			printStatus(fmt.Sprintf("offline for %s (%d attempts)", formatOffline(offline), backoff.Attempts))
 		}
+		time.Sleep(wait)
 	}
 }
 
+// clean up on Ctrl-C, etc
+func handleExitSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		stopTui()
+		flushState()
+		releaseLock()
+		console.Finalize()
+		os.Exit(1)
+	}()
+}
+
 func errorEquals(a error, b error) bool {
 	if a != nil && b != nil {
 		return a.Error() == b.Error()
@@ -251,22 +676,86 @@ func errorEquals(a error, b error) bool {
 }
 
 func loadConfig(path string) error {
-	_, err := toml.DecodeFile(path, &g_Config)
+	g_Config = defaultConfig()
+
+	// missing file is returned at last if no token is given otherwise
+	fileErr := decodeConfigFile(path, &g_Config)
+	if fileErr == nil {
+		log.Printf("config: %s", path)
+		g_ConfigModTime = configModTime(path)
+	} else if !os.IsNotExist(fileErr) {
+		return fileErr
+	}
+
+	// environment variable takes precedence over config file (credential store follows by requireToken)
+	if token := os.Getenv(TOKEN_ENV); len(token) > 0 {
+		g_Config.General.Token = token
+	}
+
+	applyFlagOverrides(&g_Config)
+	g_NotificationPatterns = compileNotificationPatterns(g_Config.Notification.Patterns)
+	g_ChannelNotificationPatterns = compileChannelNotificationPatterns(g_Config.Notification.Channel)
+	checkDailySummaryTime(g_Config.Notification.DailySummary)
+	g_RewriteRules = compileRewriteRules(g_Config.Rewrite)
+	g_Pipeline = configuredPipeline(g_Config.Pipeline)
+	// once config and flags are final (and again by startTui)
+	g_Renderer = newRenderer()
+	if len(g_Config.Script.Path) > 0 {
+		if err := loadScript(g_Config.Script.Path); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	g_TlsConfig, err = makeTlsConfig(g_Config.Network)
 	if err != nil {
 		return err
 	}
+	g_HttpClient = newHttpClient()
+	g_NameCache.Ttl = time.Duration(g_Config.General.NameTtl) * time.Second
 
-	if g_Config.Notification.Patterns != nil {
-		for _, pattern := range g_Config.Notification.Patterns {
-			if regex, err := regexp.Compile(pattern); err != nil {
-				log.Print(err)
-			} else {
-				g_NotificationPatterns = append(g_NotificationPatterns, regex)
-			}
+	if len(g_Config.General.Token) == 0 {
+		return fileErr
+	}
+	return nil
+}
+
+func compileNotificationPatterns(patterns []string) []*regexp.Regexp {
+	regexes := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		if regex, err := regexp.Compile(pattern); err != nil {
+			log.Print(err)
+		} else {
+			regexes = append(regexes, regex)
 		}
 	}
+	return regexes
+}
 
-	return nil
+// values of keys missing in config file
+func defaultConfig() Config {
+	config := Config{}
+	config.General.StateFile = "state.json"
+	config.General.LockFile = "slackv.lock"
+	if len(g_ProfileName) > 0 {
+		// profiles can run at the same time
+		config.General.StateFile = "state-" + g_ProfileName + ".json"
+		config.General.LockFile = "slackv-" + g_ProfileName + ".lock"
+	}
+	config.General.RedirectPort = 8976
+	config.General.NameTtl = 24 * 60 * 60
+	config.Logging.MaxSize = 10 * 1024 * 1024
+	config.Logging.MaxBackups = 5
+	config.Display.Scrollback = MESSAGE_STORE_CAPACITY
+	config.Display.LinkFormat = LINK_FORMAT_LABEL_URL
+	config.Display.MaxAttachmentLength = 1000
+	config.Files.Directory = "downloads"
+	config.Files.SnippetLimit = 64 * 1024
+	config.Network.MaxReconnectWait = 15
+	config.Health.MaxEventAge = 3 * PING_INTERVAL_SECONDS
+	config.Digest.SmtpPort = 587
+	config.Dedupe.Window = 600
+	return config
 }
 
 // login to Slack and connect websocket
@@ -275,8 +764,11 @@ func connect(token string) (*websocket.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
+	g_Team = session.Team
+	g_Self = session.Self
+	updateTitle()
 
-	ws, err := websocket.Dial(session.Url, "", "http://localhost/")
+	ws, err := dialWebsocket(session.Url)
 	if err != nil {
 		return nil, err
 	}
@@ -289,124 +781,220 @@ func login(token string) (SlackSession, error) {
 	query := url.Values{}
 	query.Set("token", token)
 
-	request, err := http.NewRequest(
-		"POST",
-		"https://slack.com/api/rtm.connect",
-		strings.NewReader(query.Encode()),
-	)
-	if err != nil {
-		return SlackSession{}, err
-	}
-
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return SlackSession{}, err
-	}
-	defer response.Body.Close()
-
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return SlackSession{}, err
-	}
-
 	session := SlackSession{}
-	if err := json.Unmarshal(data, &session); err != nil {
+	if err := callApi("rtm.connect", query, &session); err != nil {
 		return SlackSession{}, err
 	}
-	if !session.Ok {
-		return session, fmt.Errorf("Error: %s", session.Error)
-	}
 
 	return session, nil
 }
 
 func cacheUserGroups() error {
-	query := url.Values{}
-	query.Set("token", g_Config.General.Token)
-
-	request, err := http.NewRequest(
-		"POST",
-		"https://slack.com/api/usergroups.list",
-		strings.NewReader(query.Encode()),
-	)
-	if err != nil {
-		return err
-	}
-
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return err
-	}
-	defer response.Body.Close()
-
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return err
-	}
-
 	groupsResponse := SlackUserGroupsListResponse{}
-	if err := json.Unmarshal(data, &groupsResponse); err != nil {
+	if err := callApi("usergroups.list", url.Values{}, &groupsResponse); err != nil {
 		return err
 	}
 
 	for _, group := range groupsResponse.UserGroups {
-		g_IdNameMap[group.Id] = group.Name
+		g_NameCache.Set(group.Id, group.Name)
 	}
 
 	return nil
 }
 
+// interval to send "ping" to websocket
+const PING_INTERVAL_SECONDS = 30
+
 // receiving loop
+//
+// Events from websocket and command lines from stdin are handled one by one.
 func receiveRoutine(ws *websocket.Conn) error {
-	for {
-		// receive from ws, and map to string and interface{} from JSON
-		var unmappedMsg interface{}
+	events := make(chan map[string]interface{})
+	errors := make(chan error, 1)
+	// closed on return not to leave the reader blocked on sending an event (ws is closed by the caller)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			// receive from ws, and map to string and interface{} from JSON
+			var frame []byte
+			var unmappedMsg interface{}
+
+			if err := websocket.Message.Receive(ws, &frame); err != nil {
+				errors <- err
+				return
+			}
+			dumpEvent(frame)
+			g_Health.Touch()
+			if err := json.Unmarshal(frame, &unmappedMsg); err != nil {
+				errors <- err
+				return
+			}
 
-		if err := websocket.JSON.Receive(ws, &unmappedMsg); err != nil {
-			return err
+			if msg, ok := unmappedMsg.(map[string]interface{}); ok {
+				select {
+				case events <- msg:
+				case <-done:
+					return
+				}
+			} else {
+				log.Printf("unexpected event: %v", unmappedMsg)
+			}
 		}
+	}()
 
-		msg := unmappedMsg.(map[string]interface{})
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
 
-		// debug log
-		if _, exist := g_IgnoreMessageTypes[msg["type"].(string)]; !exist {
-			if _, exist := g_InfoMessageTypes[msg["type"].(string)]; !exist {
-				// full dump
-				//log.Printf("msg: %+v\n", msg)
-			} else {
-				// info
-				//log.Printf("type: %s, subtype: %s\n", msg["type"], msg["subtype"])
+	// Slack replies "pong", which tells the connection is alive
+	pingTicker := time.NewTicker(PING_INTERVAL_SECONDS * time.Second)
+	defer pingTicker.Stop()
+	pingId := 0
+
+	inputLines := g_InputLines
+	inputKeys := g_InputKeys
+	for {
+		select {
+		case err := <-errors:
+			return err
+		case msg := <-events:
+			switch msg["type"] {
+			case "goodbye":
+				return g_ErrGoodbye
+			case "team_migration_started":
+				return g_ErrTeamMigration
+			}
+			dispatchEventSafely(msg)
+		case line, ok := <-inputLines:
+			if !ok {
+				// stdin is closed
+				inputLines = nil
+				continue
+			}
+			clearUnseen()
+			runCommandLine(line)
+			if g_Tui != nil {
+				g_Tui.DrawPrompt()
+			}
+		case key, ok := <-inputKeys:
+			if !ok {
+				inputKeys = nil
+				continue
+			}
+			if g_Tui == nil {
+				continue
+			}
+			if line, entered := g_Tui.HandleKey(key); entered {
+				clearUnseen()
+				runCommandLine(line)
+				g_Tui.DrawPrompt()
+			}
+		case apply := <-g_Resolver.Resolved:
+			apply()
+		case <-g_ReloadSignals:
+			reloadConfig()
+		case <-g_ResizeSignals:
+			if g_Tui != nil {
+				g_Tui.Resize(terminalSize())
+			}
+		case <-pingTicker.C:
+			pingId++
+			if err := websocket.JSON.Send(ws, map[string]interface{}{"id": pingId, "type": "ping"}); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			flushReadMarks()
+			flushState()
+			sendDigestIfDue(time.Now())
+			printDailySummaryIfDue(time.Now())
+			flushRepeats(time.Now())
+			if isConfigModified() {
+				reloadConfig()
 			}
 		}
+	}
+}
 
-		// dispatch from type
-		switch msg["type"] {
-		case "hello":
-			fmt.Println("Connected!")
-		case "bot_added":
-			onBotAdded(msg)
-		case "channel_created":
-			onChannelCreated(msg)
-		case "channel_joined":
-			onChannelJoined(msg)
-		case "group_joined":
-			onGroupJoined(msg)
-		case "message":
-			onMessage(msg)
-		case "team_join":
-			onTeamJoin(msg)
-		case "user_profile_changed":
-			onUserProfileChanged(msg)
+// dispatch event, and keep running even if handler panics by unexpected payload
+func dispatchEventSafely(msg map[string]interface{}) {
+	defer func() {
+		if err := recover(); err != nil {
+			raw, _ := json.Marshal(msg)
+			log.Printf("failed to handle event: %v: %s", err, raw)
+		}
+	}()
+	dispatchEvent(msg)
+}
+
+func dispatchEvent(msg map[string]interface{}) {
+	// debug log
+	msgType, _ := msg["type"].(string)
+	if _, exist := g_IgnoreMessageTypes[msgType]; !exist {
+		if _, exist := g_InfoMessageTypes[msgType]; !exist {
+			// full dump
+			//log.Printf("msg: %+v\n", msg)
+		} else {
+			// info
+			//log.Printf("type: %s, subtype: %s\n", msg["type"], msg["subtype"])
 		}
 	}
 
-	return nil
+	g_EventHandlers.Dispatch(msg)
+}
+
+func init() {
+	g_EventHandlers.RegisterFunc("hello", "", onHello)
+	g_EventHandlers.RegisterFunc("bot_added", "", onBotAdded)
+	g_EventHandlers.RegisterFunc("channel_created", "", onChannelCreated)
+	g_EventHandlers.RegisterFunc("channel_joined", "", onChannelJoined)
+	g_EventHandlers.RegisterFunc("group_joined", "", onGroupJoined)
+	g_EventHandlers.RegisterFunc("im_created", "", onImCreated)
+	g_EventHandlers.RegisterFunc("im_open", "", onImOpen)
+	g_EventHandlers.RegisterFunc("reaction_added", "", onReactionAdded)
+	g_EventHandlers.RegisterFunc("reconnect_url", "", onReconnectUrl)
+	g_EventHandlers.RegisterFunc("reaction_removed", "", onReactionRemoved)
+	g_EventHandlers.RegisterFunc("team_join", "", onTeamJoin)
+	g_EventHandlers.RegisterFunc("team_rename", "", onTeamRename)
+	g_EventHandlers.RegisterFunc("team_domain_change", "", onTeamDomainChange)
+	g_EventHandlers.RegisterFunc("user_profile_changed", "", onUserProfileChanged)
+
+	messageHandlers := map[string]func(msg map[string]interface{}){
+		"":                 onMessageDefault,
+		"bot_message":      onMessageBot,
+		"channel_topic":    onMessageTopic,
+		"group_topic":      onMessageTopic,
+		"channel_purpose":  onMessageTopic,
+		"group_purpose":    onMessageTopic,
+		"file_comment":     onMessageFileComment,
+		"file_mention":     func(msg map[string]interface{}) {},
+		"file_share":       onMessageFileShare,
+		"huddle_thread":    onMessageCall,
+		"me_message":       onMessageMe,
+		"message_changed":  onMessageChanged,
+		"message_deleted":  onMessageDeleted,
+		"message_replied":  func(msg map[string]interface{}) {},
+		"pinned_item":      onMessagePinned,
+		"unpinned_item":    onMessagePinned,
+		"thread_broadcast": onMessageThreadBroadcast,
+	}
+	for subtype, handler := range messageHandlers {
+		g_EventHandlers.RegisterFunc("message", subtype, withLastTs(handler))
+	}
+}
+
+//==============================
+// type: "hello"
+//==============================
+
+func onHello(msg map[string]interface{}) {
+	printStatus("Connected!")
+	notifySystemdReady()
+	catchUp()
+	if !g_UnreadSummaryDone {
+		g_UnreadSummaryDone = true
+		showUnreadSummary()
+	}
 }
 
 //==============================
@@ -416,7 +1004,7 @@ func receiveRoutine(ws *websocket.Conn) error {
 func onBotAdded(msg map[string]interface{}) {
 	id := msg["bot"].(map[string]interface{})["id"].(string)
 	name := msg["bot"].(map[string]interface{})["name"].(string)
-	g_IdNameMap[id] = name
+	g_NameCache.Set(id, name)
 }
 
 // ==============================
@@ -425,7 +1013,7 @@ func onBotAdded(msg map[string]interface{}) {
 func onChannelCreated(msg map[string]interface{}) {
 	id := msg["channel"].(map[string]interface{})["id"].(string)
 	name := msg["channel"].(map[string]interface{})["name"].(string)
-	g_IdNameMap[id] = name
+	g_NameCache.Set(id, name)
 }
 
 // ==============================
@@ -434,6 +1022,39 @@ func onChannelCreated(msg map[string]interface{}) {
 func onChannelJoined(msg map[string]interface{}) {
 }
 
+//==============================
+// type: "im_created", "im_open"
+//==============================
+
+// name the DM by the user at once, not by conversations.info on the first message
+func onImCreated(msg map[string]interface{}) {
+	channel, _ := msg["channel"].(map[string]interface{})
+	id, _ := channel["id"].(string)
+	user, _ := channel["user"].(string)
+	if len(user) == 0 {
+		user, _ = msg["user"].(string)
+	}
+	rememberIm(id, user)
+}
+
+// user of im_open is yourself who opened it, so the other is looked up by conversations.info
+func onImOpen(msg map[string]interface{}) {
+	if id, _ := msg["channel"].(string); len(id) > 0 {
+		// in background if async-names
+		getChannel(id)
+	}
+}
+
+func rememberIm(id string, user string) {
+	if len(id) == 0 || len(user) == 0 || user == g_Self.Id {
+		return
+	}
+	if _, cached := g_NameCache.Get(id); cached {
+		return
+	}
+	applyChannelInfo(id, SlackChannel{Id: id, User: user})
+}
+
 //==============================
 // type: "group_joined"
 //==============================
@@ -445,63 +1066,68 @@ func onGroupJoined(msg map[string]interface{}) {
 // type: "message"
 //==============================
 
+// handle msg as a message event by subtype (also for history from Web API without type)
 func onMessage(msg map[string]interface{}) {
-	switch msg["subtype"] {
-	case "bot_message":
-		onMessageBot(msg)
-	case "file_comment":
-		onMessageFileComment(msg)
-	case "file_mention":
-		return
-	case "file_share":
-		onMessageFileShare(msg)
-	case "me_message":
-		onMessageMe(msg)
-	case "message_changed":
-		onMessageChanged(msg)
-	case "message_replied":
-		return
-	default:
-		if _, exist := msg["text"]; exist {
-			onPureMessage(msg)
-		}
+	subtype, _ := msg["subtype"].(string)
+	if handler, exist := g_EventHandlers.Lookup("message", subtype); exist {
+		handler.Handle(msg)
+	}
+}
+
+// remember ts of every message before handling it by subtype
+func withLastTs(handler func(msg map[string]interface{})) func(msg map[string]interface{}) {
+	return func(msg map[string]interface{}) {
+		updateLastTs(msg)
+		handler(msg)
+	}
+}
+
+// message without subtype or with unknown one
+func onMessageDefault(msg map[string]interface{}) {
+	if _, isCall := getCallText(msg); isCall {
+		onMessageCall(msg)
+	} else if _, exist := msg["text"]; exist {
+		onPureMessage(msg)
 	}
 }
 
 func onPureMessage(msg map[string]interface{}) {
-	timestamp := getTimestamp(msg)
-	threadTs := getThreadTs(msg)
-	channel := getChannelByMessage(msg)
-	userType := getUserType(msg)
-	user := getUserByMessage(msg)
-	text := msg["text"].(string)
+	message := newMessage(msg)
+	message.User = getUserByMessage(msg)
+	message.Text = msg["text"].(string)
+	if text, exist := getRichText(msg); exist {
+		message.Text = text
+	}
+	if isReminder(msg) {
+		message = asReminder(message)
+	}
 
-	printMessage(timestamp, threadTs, channel, userType, user, text, "")
+	printMessage(message)
 }
 
 func onMessageBot(msg map[string]interface{}) {
-	timestamp := getTimestamp(msg)
-	threadTs := getThreadTs(msg)
-	channel := getChannelByMessage(msg)
-	userType := getUserType(msg)
-	user := getBot(msg)
-	text := getText(msg)
+	message := newMessage(msg)
+	message.User = getBot(msg)
+	message.Text = getText(msg)
 	toRemoveLastUser := false
 
 	if attachments, exist := msg["attachments"].([]interface{}); exist {
 		if attachment, exist := attachments[0].(map[string]interface{}); exist {
-			title := ""
-			text, title = getAttachmentText(attachment)
-			text = title + text
+			text, title := getAttachmentText(attachment)
+			message.Text = title + text
 			toRemoveLastUser = true
 		}
 	}
+	if text, isCall := getCallText(msg); isCall {
+		// call from apps (Zoom, etc)
+		message.Text = text
+	}
 
-	printMessage(timestamp, threadTs, channel, userType, user, text, "")
+	printMessage(message)
 
 	if toRemoveLastUser {
 		// display header on next message
-		g_LastUser = ""
+		g_Renderer.ResetHeader()
 	}
 }
 
@@ -514,65 +1140,125 @@ func onMessageFileComment(msg map[string]interface{}) {
 	if !exist {
 		return
 	}
-	timestamp := getTimestamp(msg)
-	threadTs := getThreadTs(msg)
-	channel := getChannelByMessage(msg)
-	userType := getUserType(msg)
-	user := getUserByMessage(comment)
+	message := newMessage(msg)
+	message.User = getUserByMessage(comment)
 	title := "comment to: " + getTitle(file)
 	text := comment["comment"].(string)
 
 	title = "\033[44m" + strings.TrimSpace(title) + "\033[0m\n"
-	text = title + text
+	message.Text = title + text
 
-	printMessage(timestamp, threadTs, channel, userType, user, text, "")
+	printMessage(message)
 
 	// display header on next message
-	g_LastUser = ""
+	g_Renderer.ResetHeader()
 }
 
 func onMessageFileShare(msg map[string]interface{}) {
-	var text = ""
-
-	file, exist := msg["file"].(map[string]interface{})
-	if !exist {
+	message := newMessage(msg)
+	if len(message.Files) == 0 {
 		return
 	}
-	timestamp := getTimestamp(msg)
-	threadTs := getThreadTs(msg)
-	channel := getChannelByMessage(msg)
-	userType := getUserType(msg)
-	user := getUserByMessage(msg)
-	title := "file: " + getTitle(file)
-	if preview, exist := file["preview"].(string); exist {
-		if isPreviewTruncated(file) {
-			preview = preview + "..."
+	message.User = getUserByMessage(msg)
+
+	texts := []string{}
+	if _, isLegacy := msg["file"]; !isLegacy {
+		// text is a comment to the files (legacy "uploaded a file" otherwise)
+		if text := getText(msg); len(text) > 0 {
+			texts = append(texts, text)
 		}
-		title = "\033[44m" + strings.TrimSpace(title) + "\033[0m\n"
-		text = title + preview
-	} else {
-		text = msg["text"].(string)
 	}
+	for _, file := range message.Files {
+		if g_Config.Files.FetchSnippets {
+			file = expandSnippet(file, g_Config.Files.SnippetLimit)
+		}
+		if g_Config.Files.FetchPosts {
+			file = expandPost(file, g_Config.Files.SnippetLimit)
+		}
+		texts = append(texts, formatFile(file))
+	}
+	message.Text = strings.Join(texts, "\n")
 
-	printMessage(timestamp, threadTs, channel, userType, user, text, "")
+	printMessage(message)
 
 	// display header on next message
-	g_LastUser = ""
+	g_Renderer.ResetHeader()
+}
+
+func onMessageCall(msg map[string]interface{}) {
+	message := newMessage(msg)
+	message.User = getUserByMessage(msg)
+	message.Text, _ = getCallText(msg)
+
+	printMessage(message)
+}
+
+// reply also sent to channel
+func onMessageThreadBroadcast(msg map[string]interface{}) {
+	message := newMessage(msg)
+	message.User = getUserByMessage(msg)
+	message.Text = getText(msg)
+	if text, exist := getRichText(msg); exist {
+		message.Text = text
+	}
+
+	marker := "broadcast from thread"
+	if root, exist := msg["root"].(map[string]interface{}); exist {
+		if rootText := firstLine(unescape(getText(root))); len(rootText) > 0 {
+			marker = marker + ": " + truncateText(rootText, 40)
+		}
+	}
+	message.Annotation = " \033[94m[" + marker + "]\033[0m"
+
+	printMessage(message)
+}
+
+func onMessagePinned(msg map[string]interface{}) {
+	message := newMessage(msg)
+	message.User = getUserByMessage(msg)
+
+	action := "pinned"
+	if message.Subtype == "unpinned_item" {
+		action = "unpinned"
+	}
+	text := firstLine(unescape(getPinnedText(msg)))
+	if len(text) == 0 {
+		message.Text = "\033[90m" + action + " an item\033[0m"
+	} else {
+		message.Text = "\033[90m" + action + " a message:\033[0m " + text
+	}
+
+	printMessage(message)
+}
+
+// text of pinned message in "item" or attachments
+func getPinnedText(msg map[string]interface{}) string {
+	if item, exist := msg["item"].(map[string]interface{}); exist {
+		if text := getText(item); len(text) > 0 {
+			return text
+		}
+		if itemMessage, exist := item["message"].(map[string]interface{}); exist {
+			return getText(itemMessage)
+		}
+	}
+	text, _ := getAttachmentsText(msg)
+	return text
+}
+
+func firstLine(text string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
 }
 
 func onMessageMe(msg map[string]interface{}) {
-	timestamp := getTimestamp(msg)
-	threadTs := getThreadTs(msg)
-	channel := getChannelByMessage(msg)
-	userType := getUserType(msg)
-	user := getUserByMessage(msg)
-	text := "\033[3m\033[90m" + msg["text"].(string) + "\033[0m"
+	message := newMessage(msg)
+	message.User = getUserByMessage(msg)
+	message.Text = "\033[3m\033[90m" + msg["text"].(string) + "\033[0m"
 
-	printMessage(timestamp, threadTs, channel, userType, user, text, "")
+	printMessage(message)
 }
 
 func onMessageChanged(msg map[string]interface{}) {
-	message, exist := msg["message"].(map[string]interface{})
+	changedMessage, exist := msg["message"].(map[string]interface{})
 	if !exist {
 		return
 	}
@@ -580,79 +1266,92 @@ func onMessageChanged(msg map[string]interface{}) {
 	if !exist {
 		return
 	}
-	timestamp := getTimestamp(message)
-	threadTs := getThreadTs(msg)
-	channel := getChannelByMessage(msg)
-	userType := getUserType(msg)
-	user := getUserByMessage(message)
-	text := getText(message)
+	message := newMessage(msg)
+	message.Ts = getTs(changedMessage)
+	message.User = getUserByMessage(changedMessage)
+	text := getText(changedMessage)
 	prevText := getText(prevMessage)
 	if text != prevText {
-		annotation := " \033[93m(edited)\033[0m"
-		printMessage(timestamp, threadTs, channel, userType, user, text, annotation)
+		g_MessageStore.Edit(message.Key(), unescape(text))
+
+		message.Text = text
+		message.PrevText = prevText
+		message.Annotation = " \033[93m(edited)\033[0m"
+		printMessage(message)
 	}
 
-	attText, attTitle := getAttachmentsText(message)
+	attText, attTitle := getAttachmentsText(changedMessage)
 	attText = attTitle + attText
 	prevAttText, prevAttTitle := getAttachmentsText(prevMessage)
 	prevAttText = prevAttTitle + prevAttText
 	if attText != prevAttText {
-		printMessage(timestamp, threadTs, channel, userType, user, attText, "")
+		message.Text = attText
+		message.PrevText = prevAttText
+		message.Annotation = ""
+		printMessage(message)
 
 		// display header on next message
-		g_LastUser = ""
+		g_Renderer.ResetHeader()
+	}
+
+	callText, _ := getCallText(changedMessage)
+	prevCallText, _ := getCallText(prevMessage)
+	if callText != prevCallText {
+		g_MessageStore.Edit(message.Key(), callText)
+
+		message.Text = callText
+		message.PrevText = ""
+		message.Annotation = ""
+		printMessage(message)
 	}
 }
 
-func cacheChannelInfo(name string) error {
+func onMessageDeleted(msg map[string]interface{}) {
+	channel, _ := msg["channel"].(string)
+	deletedTs, _ := msg["deleted_ts"].(string)
+	g_MessageStore.Delete(MessageKey{channel, deletedTs})
+}
+
+func fetchChannelInfo(name string) (SlackChannel, error) {
 	query := url.Values{}
-	query.Set("token", g_Config.General.Token)
 	query.Set("channel", name)
 
-	request, err := http.NewRequest(
-		"POST",
-		"https://slack.com/api/conversations.info",
-		strings.NewReader(query.Encode()),
-	)
-	if err != nil {
-		return err
+	conversationResponse := SlackConversationsInfoResponse{}
+	if err := callApi("conversations.info", query, &conversationResponse); err != nil {
+		return SlackChannel{}, err
 	}
+	return conversationResponse.Channel, nil
+}
 
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return err
+func applyChannelInfo(name string, channel SlackChannel) {
+	rememberSharedChannel(channel)
+	rememberChannelTopic(channel)
+	if channel.IsMpim {
+		g_NameCache.Set(name, mpimName(channel))
+	} else if len(channel.Name) > 0 {
+		g_NameCache.Set(name, channel.Name)
+	} else if len(channel.User) > 0 {
+		if user := getUser(channel.User); user != channel.User {
+			// not yet if resolving in background
+			g_NameCache.Set(name, user)
+		}
 	}
-	defer response.Body.Close()
+}
 
-	data, err := ioutil.ReadAll(response.Body)
+func cacheChannelInfo(name string) error {
+	channel, err := fetchChannelInfo(name)
 	if err != nil {
 		return err
 	}
-
-	conversationResponse := SlackConversationsInfoResponse{}
-	if err := json.Unmarshal(data, &conversationResponse); err != nil {
-		return err
-	}
-
-	if len(conversationResponse.Channel.Name) > 0 {
-		g_IdNameMap[name] = conversationResponse.Channel.Name
-	} else if len(conversationResponse.Channel.User) > 0 {
-		g_IdNameMap[name] = getUser(conversationResponse.Channel.User)
-	}
-
+	applyChannelInfo(name, channel)
 	return nil
 }
 
 func getChannel(channel string) string {
-	if _, cached := g_IdNameMap[channel]; !cached {
-		if err := cacheChannelInfo(channel); err != nil {
-			log.Print(err)
-		}
-	}
-	return g_IdNameMap[channel]
+	return resolveName(channel, func() (func(), error) {
+		info, err := fetchChannelInfo(channel)
+		return func() { applyChannelInfo(channel, info) }, err
+	})
 }
 
 func getChannelByMessage(msg map[string]interface{}) string {
@@ -667,61 +1366,56 @@ func getUserType(msg map[string]interface{}) string {
 	if _, exist := msg["bot_id"]; exist {
 		userType = userType + "[bot]"
 	}
-	if _, exist := msg["app_id"]; exist {
-		userType = userType + "[app]"
+	if appId, exist := msg["app_id"].(string); exist {
+		if name := getApp(appId, msg); len(name) > 0 {
+			userType = userType + "[app:" + name + "]"
+		} else {
+			userType = userType + "[app]"
+		}
 	}
 	return userType
 }
 
-func cacheUserInfo(name string) error {
-	query := url.Values{}
-	query.Set("token", g_Config.General.Token)
-	query.Set("user", name)
-
-	request, err := http.NewRequest(
-		"POST",
-		"https://slack.com/api/users.info",
-		strings.NewReader(query.Encode()),
-	)
-	if err != nil {
-		return err
+// name of app by bot_profile of msg or bot info
+func getApp(appId string, msg map[string]interface{}) string {
+	if profile, exist := msg["bot_profile"].(map[string]interface{}); exist {
+		if name, exist := profile["name"].(string); exist && len(name) > 0 {
+			g_NameCache.Set(appId, name)
+		}
 	}
-
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return err
+	if _, cachedApp := g_NameCache.Get(appId); !cachedApp {
+		if bot, exist := msg["bot_id"].(string); exist {
+			// cached by bots.info
+			getBot(map[string]interface{}{"bot_id": bot})
+		}
 	}
-	defer response.Body.Close()
+	return g_NameCache.Name(appId)
+}
 
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return err
-	}
+func fetchUserInfo(name string) (SlackUser, error) {
+	query := url.Values{}
+	query.Set("user", name)
 
 	userResponse := SlackUsersInfoResponse{}
-	if err := json.Unmarshal(data, &userResponse); err != nil {
-		return err
+	if err := callApi("users.info", query, &userResponse); err != nil {
+		return SlackUser{}, err
 	}
+	return userResponse.User, nil
+}
 
-	if len(userResponse.User.Profile.DisplayName) > 0 {
-		g_IdNameMap[name] = userResponse.User.Profile.DisplayName
+func applyUserInfo(name string, user SlackUser) {
+	if len(user.Profile.DisplayName) > 0 {
+		g_NameCache.Set(name, user.Profile.DisplayName)
 	} else {
-		g_IdNameMap[name] = userResponse.User.Name
+		g_NameCache.Set(name, user.Name)
 	}
-
-	return nil
 }
 
 func getUser(user string) string {
-	if _, cachedUser := g_IdNameMap[user]; !cachedUser {
-		if err := cacheUserInfo(user); err != nil {
-			log.Print(err)
-		}
-	}
-	return g_IdNameMap[user]
+	return resolveName(user, func() (func(), error) {
+		info, err := fetchUserInfo(user)
+		return func() { applyUserInfo(user, info) }, err
+	})
 }
 
 func getUserByMessage(msg map[string]interface{}) string {
@@ -731,9 +1425,31 @@ func getUserByMessage(msg map[string]interface{}) string {
 	return ""
 }
 
+func fetchBotInfo(bot string) (SlackBot, error) {
+	query := url.Values{}
+	query.Set("bot", bot)
+
+	botResponse := SlackBotsInfoResponse{}
+	if err := callApi("bots.info", query, &botResponse); err != nil {
+		return SlackBot{}, err
+	}
+	return botResponse.Bot, nil
+}
+
+func applyBotInfo(id string, bot SlackBot) {
+	g_NameCache.Set(id, bot.Name)
+	if len(bot.AppId) > 0 {
+		g_NameCache.Set(bot.AppId, bot.Name)
+	}
+}
+
 func getBot(msg map[string]interface{}) string {
 	if mayBot, exist := msg["bot_id"]; exist {
-		return g_IdNameMap[mayBot.(string)]
+		bot := mayBot.(string)
+		return resolveName(bot, func() (func(), error) {
+			info, err := fetchBotInfo(bot)
+			return func() { applyBotInfo(bot, info) }, err
+		})
 	}
 	return ""
 }
@@ -745,22 +1461,49 @@ func getText(msg map[string]interface{}) string {
 	return ""
 }
 
-func getTimestamp(msg map[string]interface{}) time.Time {
-	fTs := 0.0
-	if strTs, exist := msg["ts"]; exist {
-		fTs, _ = strconv.ParseFloat(strTs.(string), 64)
+func getTs(msg map[string]interface{}) string {
+	if ts, exist := msg["ts"].(string); exist {
+		return ts
 	}
-	return time.Unix(int64(fTs), 0)
+	return ""
 }
 
-func getThreadTs(msg map[string]interface{}) time.Time {
-	fTs := 0.0
-	if strTs, exist := msg["thread_ts"]; exist {
-		fTs, _ = strconv.ParseFloat(strTs.(string), 64)
+func getThreadTs(msg map[string]interface{}) string {
+	if threadTs, exist := msg["thread_ts"].(string); exist {
+		return threadTs
 	}
+	return ""
+}
+
+// convert Slack timestamp ("1234567890.123456") to time
+func parseTs(ts string) time.Time {
+	fTs, _ := strconv.ParseFloat(ts, 64)
 	return time.Unix(int64(fTs), 0)
 }
 
+// compare Slack timestamps without loss of precision
+func isTsAfter(ts string, than string) bool {
+	if len(ts) != len(than) {
+		return len(ts) > len(than)
+	}
+	return ts > than
+}
+
+// make a message with common fields of msg
+func newMessage(msg map[string]interface{}) Message {
+	channelId, _ := msg["channel"].(string)
+	subtype, _ := msg["subtype"].(string)
+	return Message{
+		Ts:        getTs(msg),
+		ThreadTs:  getThreadTs(msg),
+		ChannelId: channelId,
+		Channel:   getChannelByMessage(msg),
+		UserType:  getUserType(msg),
+		Subtype:   subtype,
+		Files:     getFiles(msg),
+	}
+}
+
 func getTitle(msg map[string]interface{}) string {
 	if title, exist := msg["title"]; exist {
 		return title.(string)
@@ -768,6 +1511,40 @@ func getTitle(msg map[string]interface{}) string {
 	return ""
 }
 
+// files of "file" (legacy) and "files" fields
+func getFiles(msg map[string]interface{}) []SlackFile {
+	raw := []interface{}{}
+	if file, exist := msg["file"]; exist {
+		raw = append(raw, file)
+	}
+	if files, exist := msg["files"].([]interface{}); exist {
+		raw = append(raw, files...)
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	decoded := []SlackFile{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		log.Print(err)
+		return nil
+	}
+
+	files := []SlackFile{}
+	ids := map[string]struct{}{}
+	for _, file := range decoded {
+		if _, exist := ids[file.Id]; !exist {
+			ids[file.Id] = struct{}{}
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
 func getPreview(msg map[string]interface{}) string {
 	if preview, exist := msg["preview"]; exist {
 		return preview.(string)
@@ -814,103 +1591,82 @@ func getAttachmentText(attachment map[string]interface{}) (string, string) {
 	if text, exist = attachment["text"].(string); !exist {
 		text, _ = attachment["fallback"].(string)
 	}
-	if textLen := len(text); textLen > 1000 {
-		text = text[:1000] + "..."
-	}
+	text = truncateText(text, g_Config.Display.MaxAttachmentLength)
 
 	return text, title
 }
 
-func printMessage(
-	timestamp time.Time,
-	threadTs time.Time,
-	channel string,
-	userType string,
-	user string,
-	text string,
-	annotation string,
-) {
-	if equalsAnyKeywords(channel, g_Config.Notification.MuteChannels) {
+func printMessage(message Message) {
+	if len(message.Text) == 0 {
 		return
 	}
-	if equalsAnyKeywords(user, g_Config.Notification.MuteUsers) {
-		return
-	}
-	if len(text) == 0 {
-		return
-	}
-
-	strTimestamp := timestamp.Format("2006/01/02 15:04:05")
-	if threadTs.Unix() != 0 {
-		strTimestamp = strTimestamp + " [at " + threadTs.Format("2006/01/02 15:04:05") + "]"
-	}
-
-	if channel != g_LastChannel {
-		// insert a empty line and header
-		fmt.Printf(
-			"\n\033[93m@%-18s #%-20s %s\033[0m\n",
-			userType+user,
-			channel,
-			strTimestamp,
-		)
-	} else if user != g_LastUser || !threadTs.Equal(g_LastThreadTs) {
-		// display header
-		fmt.Printf(
-			"\033[93m@%-18s #%-20s %s\033[0m\n",
-			userType+user,
-			channel,
-			strTimestamp,
-		)
-	}
-
-	text = unescape(text)
-	if matchAnyPatterns(text, g_NotificationPatterns) {
-		text = "\033[5;95m" + text + "\033[0m"
-	}
-
-	// display body
-	fmt.Printf("%s%s\n", text, annotation)
+	g_Pipeline.Process(message)
+}
 
-	g_LastChannel = channel
-	g_LastUser = user
-	g_LastThreadTs = threadTs
+// display message by the renderer
+func renderMessage(message Message) {
+	countUnseen(message)
+	g_Renderer.Render(message)
 }
 
 func unescape(text string) string {
+	// replaced in single pass not to rescan names, which may contain markup
 	// <#G01234|group> or <#G01234>
-	for isMatching := true; isMatching; {
-		isMatching = false
-		if index := g_ChannelPattern.FindStringSubmatchIndex(text); index != nil {
-			isMatching = true
-			text = text[:index[0]] + "#" + getChannel(text[index[2]:index[3]]) + text[index[1]:]
-		}
-	}
+	text = g_ChannelPattern.ReplaceAllStringFunc(text, func(token string) string {
+		return "#" + getChannel(g_ChannelPattern.FindStringSubmatch(token)[1])
+	})
 
 	// <@U01234|user> or <@U01234>
-	for isMatching := true; isMatching; {
-		isMatching = false
-		if index := g_MentionPattern.FindStringSubmatchIndex(text); index != nil {
-			isMatching = true
-			text = text[:index[0]] + "@" + getUser(text[index[2]:index[3]]) + text[index[1]:]
-		}
-	}
+	text = g_MentionPattern.ReplaceAllStringFunc(text, func(token string) string {
+		return "@" + getUser(g_MentionPattern.FindStringSubmatch(token)[1])
+	})
 
 	// <!subteam^S1A2B3C4D|@user-group> or <!subteam^S1A2B3C4D>
-	for isMatching := true; isMatching; {
-		isMatching = false
-		if index := g_UserGroupPattern.FindStringSubmatchIndex(text); index != nil {
-			if name, exist := g_IdNameMap[text[index[2]:index[3]]]; exist {
-				isMatching = true
-				text = text[:index[0]] + "@" + name + text[index[1]:]
-			}
+	text = g_UserGroupPattern.ReplaceAllStringFunc(text, func(token string) string {
+		if name, exist := g_NameCache.Get(g_UserGroupPattern.FindStringSubmatch(token)[1]); exist {
+			return "@" + name
 		}
-	}
+		return token
+	})
+
+	// <!date^1392734382^{date_num}|2014-02-18>
+	text = unescapeDates(text, time.Now())
 
 	// <!here|here> or <!here>
 	text = g_KeywordPattern.ReplaceAllString(text, "@$1")
+
+	// <mailto:a@example.com|a@example.com> or <tel:+1234|+1 234>
+	text = g_ContactPattern.ReplaceAllStringFunc(text, func(contact string) string {
+		match := g_ContactPattern.FindStringSubmatch(contact)
+		return formatContact(match[2], match[4])
+	})
+
+	// <https://example.com|label> or <https://example.com>
+	text = g_LinkPattern.ReplaceAllStringFunc(text, func(link string) string {
+		match := g_LinkPattern.FindStringSubmatch(link)
+		return formatLink(match[1], match[3])
+	})
 	return html.UnescapeString(text)
 }
 
+func formatLink(url string, label string) string {
+	if len(label) == 0 || label == url {
+		return url
+	}
+	if g_Config.Display.LinkFormat == LINK_FORMAT_LABEL {
+		return label
+	}
+	return label + " (" + url + ")"
+}
+
+// address of mailto or tel without scheme, and label only if it differs
+func formatContact(address string, label string) string {
+	if len(label) == 0 || label == address {
+		return address
+	}
+	return formatLink(address, label)
+}
+
 func matchAnyPatterns(text string, patterns []*regexp.Regexp) bool {
 	for _, pattern := range patterns {
 		if pattern.MatchString(text) {
@@ -936,7 +1692,34 @@ func equalsAnyKeywords(text string, keywords []string) bool {
 func onTeamJoin(msg map[string]interface{}) {
 	id := msg["user"].(map[string]interface{})["id"].(string)
 	name := msg["user"].(map[string]interface{})["name"].(string)
-	g_IdNameMap[id] = name
+	g_NameCache.Set(id, name)
+}
+
+//==============================
+// type: "team_rename", "team_domain_change"
+//==============================
+
+func onTeamRename(msg map[string]interface{}) {
+	name, _ := msg["name"].(string)
+	if len(name) == 0 || name == g_Team.Name {
+		return
+	}
+	printStatus("workspace renamed: " + g_Team.Name + " → " + name)
+	g_Team.Name = name
+	if len(g_Team.Id) > 0 {
+		g_NameCache.Set(g_Team.Id, name)
+	}
+	updateTitle()
+}
+
+// domain of links to messages, users, etc
+func onTeamDomainChange(msg map[string]interface{}) {
+	domain, _ := msg["domain"].(string)
+	if len(domain) == 0 || domain == g_Team.Domain {
+		return
+	}
+	printStatus("workspace domain changed: " + g_Team.Domain + " → " + domain)
+	g_Team.Domain = domain
 }
 
 //==============================
@@ -952,5 +1735,5 @@ func onUserProfileChanged(msg map[string]interface{}) {
 	} else {
 		name = user["name"].(string)
 	}
-	g_IdNameMap[id] = name
+	g_NameCache.Set(id, name)
 }
@@ -1,5 +1,6 @@
 package main
 
+import "io/ioutil"
 import "net/http"
 import "testing"
 
@@ -12,7 +13,7 @@ func TestErrorEquals(t *testing.T) {
 }
 
 func TestUnescape1(t *testing.T) {
-	g_IdNameMap = map[string]string{"G01234": "test_group"}
+	g_NameCache = NewNameCache(map[string]string{"G01234": "test_group"})
 	expected := "#test_group foo"
 	result := unescape("<#G01234|test_group> foo")
 	if result != expected {
@@ -25,7 +26,7 @@ func TestUnescape1(t *testing.T) {
 }
 
 func TestUnescape2(t *testing.T) {
-	g_IdNameMap = map[string]string{"U01234": "test_user"}
+	g_NameCache = NewNameCache(map[string]string{"U01234": "test_user"})
 	expected := "@test_user foo"
 	result := unescape("<@U01234|test_user> foo")
 	if result != expected {
@@ -38,7 +39,7 @@ func TestUnescape2(t *testing.T) {
 }
 
 func TestUnescape3(t *testing.T) {
-	g_IdNameMap = map[string]string{}
+	g_NameCache = NewNameCache(map[string]string{})
 	expected := "@here foo"
 	result := unescape("<!here|here> foo")
 	if result != expected {
@@ -51,7 +52,7 @@ func TestUnescape3(t *testing.T) {
 }
 
 func TestUnescape4(t *testing.T) {
-	g_IdNameMap = map[string]string{"S1A2B3C4D": "hoge-piyo"}
+	g_NameCache = NewNameCache(map[string]string{"S1A2B3C4D": "hoge-piyo"})
 	expected := "@hoge-piyo foo"
 	result := unescape("<!subteam^S1A2B3C4D|@hoge-piyo> foo")
 	if result != expected {
@@ -62,3 +63,193 @@ func TestUnescape4(t *testing.T) {
 		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
 	}
 }
+
+func TestIsTsAfter(t *testing.T) {
+	if !isTsAfter("1600000000.000200", "1600000000.000100") {
+		t.Errorf("expected to be after\n")
+	}
+	if isTsAfter("1600000000.000100", "1600000000.000100") {
+		t.Errorf("expected not to be after\n")
+	}
+	if !isTsAfter("1600000000.000100", "") {
+		t.Errorf("expected to be after empty\n")
+	}
+}
+
+func TestGetPinnedText(t *testing.T) {
+	msg := map[string]interface{}{
+		"subtype": "pinned_item",
+		"item":    map[string]interface{}{"message": map[string]interface{}{"text": "release notes\nline 2"}},
+	}
+	if result := firstLine(getPinnedText(msg)); result != "release notes" {
+		t.Errorf("expected \"release notes\", but \"%s\"\n", result)
+	}
+
+	msg = map[string]interface{}{
+		"subtype":     "pinned_item",
+		"attachments": []interface{}{map[string]interface{}{"fallback": "deploy done"}},
+	}
+	if result := firstLine(getPinnedText(msg)); result != "deploy done" {
+		t.Errorf("expected \"deploy done\", but \"%s\"\n", result)
+	}
+}
+
+func TestGetUserType(t *testing.T) {
+	g_NameCache = NewNameCache(map[string]string{"B01": "deploy"})
+
+	msg := map[string]interface{}{
+		"bot_id":      "B01",
+		"app_id":      "A01",
+		"bot_profile": map[string]interface{}{"name": "GitHub", "app_id": "A01"},
+	}
+	if result := getUserType(msg); result != "[bot][app:GitHub]" {
+		t.Errorf("expected \"[bot][app:GitHub]\", but \"%s\"\n", result)
+	}
+
+	// cached
+	msg = map[string]interface{}{"app_id": "A01"}
+	if result := getUserType(msg); result != "[app:GitHub]" {
+		t.Errorf("expected \"[app:GitHub]\", but \"%s\"\n", result)
+	}
+}
+
+func TestUnescapeLinks(t *testing.T) {
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{})
+
+	expected := "see docs (https://example.com/a?b=1&c=2) or https://example.com"
+	result := unescape("see <https://example.com/a?b=1&amp;c=2|docs> or <https://example.com>")
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	g_Config.Display.LinkFormat = LINK_FORMAT_LABEL
+	expected = "see docs"
+	if result := unescape("see <https://example.com|docs>"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestUnescapeContacts(t *testing.T) {
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{})
+
+	expected := "mail a@example.com or call +1 555 0100 (+15550100), a@example.com"
+	result := unescape("mail <mailto:a@example.com|a@example.com> or call <tel:+15550100|+1 555 0100>, <mailto:a@example.com>")
+	if result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	g_Config.Display.LinkFormat = LINK_FORMAT_LABEL
+	expected = "mail Alice"
+	if result := unescape("mail <mailto:a@example.com|Alice>"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestDispatchEventSafely(t *testing.T) {
+	// payloads not of expected shapes must not crash
+	dispatchEventSafely(map[string]interface{}{"type": "bot_added", "bot": "B01"})
+	dispatchEventSafely(map[string]interface{}{"type": "channel_created"})
+	dispatchEventSafely(map[string]interface{}{"type": 1})
+}
+
+func TestUnescapeNamesWithMarkup(t *testing.T) {
+	// names must not be unescaped again
+	g_NameCache = NewNameCache(map[string]string{"U01": "<@U01>", "C01": "<#C01>"})
+	expected := "@<@U01> #<#C01>"
+	if result := unescape("<@U01> <#C01>"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func FuzzUnescape(f *testing.F) {
+	for _, seed := range []string{
+		"<#G01234|test_group> foo",
+		"<@U01234|user> <@U05678>",
+		"<!subteam^S01|@group> <!subteam^S02>",
+		"<!date^1392734382^{date_num} {time}|2014-02-18>",
+		"<!date^1392734382^{date_pretty}^https://example.com|fallback>",
+		"<!here|here> <!channel>",
+		"see <https://example.com|docs> &lt;&amp;&gt;",
+		"<@<@U01>> <#<#C01>|x> <<!date^^|>>",
+	} {
+		f.Add(seed)
+	}
+
+	savedCache, savedClient := g_NameCache, g_HttpClient
+	defer func() { g_NameCache, g_HttpClient = savedCache, savedClient }()
+	g_HttpClient = &http.Client{Transport: offlineTransport{}}
+	g_NameCache = NewNameCache(map[string]string{"U01": "<@U01>", "C01": "<#C01|general>", "S01": "<!subteam^S01>"})
+
+	f.Fuzz(func(t *testing.T, text string) {
+		unescape(text)
+	})
+}
+
+func FuzzDispatchEvent(f *testing.F) {
+	f.Add("", "hello <@U01>", "")
+	f.Add("me_message", "<!date^0^{ago}|now>", "")
+	f.Add("bot_message", "<https://example.com|<@U01>>", "1600000000.000100")
+	f.Add("message_changed", "<#C01>", "x")
+
+	savedCache, savedClient, savedOutput := g_NameCache, g_HttpClient, g_Output
+	defer func() { g_NameCache, g_HttpClient, g_Output = savedCache, savedClient, savedOutput }()
+	g_HttpClient = &http.Client{Transport: offlineTransport{}}
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "C01": "general"})
+	g_ChannelTopics = map[string]ChannelTopic{"C01": {}}
+	g_Output = ioutil.Discard
+
+	f.Fuzz(func(t *testing.T, subtype string, text string, threadTs string) {
+		msg := map[string]interface{}{
+			"type":    "message",
+			"channel": "C01",
+			"user":    "U01",
+			"ts":      "1600000000.000100",
+			"text":    text,
+		}
+		if len(subtype) > 0 {
+			msg["subtype"] = subtype
+		}
+		if len(threadTs) > 0 {
+			msg["thread_ts"] = threadTs
+		}
+		dispatchEvent(msg)
+	})
+}
+
+func TestImCreated(t *testing.T) {
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "U02": "bob"})
+	g_Self = SlackUser{Id: "U02"}
+
+	dispatchEvent(map[string]interface{}{
+		"type":    "im_created",
+		"user":    "U02",
+		"channel": map[string]interface{}{"id": "D01", "user": "U01"},
+	})
+	if name, _ := g_NameCache.Get("D01"); name != "alice" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "alice", name)
+	}
+
+	g_Self = SlackUser{}
+}
+
+func TestImOpen(t *testing.T) {
+	g_Config = defaultConfig()
+	g_Config.General.AsyncNames = true
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "U02": "bob", "D01": "alice"})
+	g_Self = SlackUser{Id: "U02"}
+	g_Resolver = NewResolver()
+	defer func() {
+		g_Config.General.AsyncNames = false
+		g_Self = SlackUser{}
+	}()
+
+	// user is yourself, so the other is resolved by conversations.info
+	dispatchEvent(map[string]interface{}{"type": "im_open", "user": "U02", "channel": "D02"})
+	dispatchEvent(map[string]interface{}{"type": "im_open", "user": "U02", "channel": "D01"})
+	if _, exist := g_Resolver.pending["D02"]; !exist || len(g_Resolver.pending) != 1 {
+		t.Errorf("expected only D02 to be resolved, but %v\n", g_Resolver.pending)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import "log"
+import "os/exec"
+import "strings"
+
+//==============================
+// text-to-speech of highlights
+//==============================
+
+// messages waiting to be spoken, dropped while the queue is full
+const SPEECH_QUEUE_SIZE = 10
+
+var g_SpeechQueue chan string
+
+// whether message is a direct message (IM or multiparty IM)
+func isDirectMessage(message Message) bool {
+	return strings.HasPrefix(message.ChannelId, "D") || strings.HasPrefix(message.ChannelId, "G") && strings.HasPrefix(message.Channel, "DM: ")
+}
+
+// phrase to speak for message
+func speechText(message Message) string {
+	text := stripAnsi(message.Text)
+	if isDirectMessage(message) {
+		return message.User + " says: " + text
+	}
+	return message.User + " in " + message.Channel + ": " + text
+}
+
+// speak highlighted message or DM aloud if [notification] speak
+func speakMessage(message Message) {
+	if !g_Config.Notification.Speak || message.Subtype == "message_changed" {
+		return
+	}
+	if !message.Highlight && !isDirectMessage(message) {
+		return
+	}
+
+	if g_SpeechQueue == nil {
+		command := speechCommand()
+		if command == nil {
+			log.Print("no text-to-speech command is found")
+			g_Config.Notification.Speak = false
+			return
+		}
+		g_SpeechQueue = make(chan string, SPEECH_QUEUE_SIZE)
+		go speakRoutine(command, g_SpeechQueue)
+	}
+
+	select {
+	case g_SpeechQueue <- speechText(message):
+	default:
+		// not to talk for minutes after a burst of messages
+	}
+}
+
+// speak one by one not to overlap voices
+func speakRoutine(command []string, queue chan string) {
+	for text := range queue {
+		cmd := exec.Command(command[0], command[1:]...)
+		// through stdin not to interpret text as options
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			log.Printf("failed to speak: %v", err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestSpeechText(t *testing.T) {
+	actual := speechText(Message{ChannelId: "C01", Channel: "dev", User: "alice", Text: "\033[1mdeploy\033[0m failed"})
+	expected := "alice in dev: deploy failed"
+	if actual != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, actual)
+	}
+
+	actual = speechText(Message{ChannelId: "D01", Channel: "bob", User: "bob", Text: "lunch?"})
+	expected = "bob says: lunch?"
+	if actual != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, actual)
+	}
+}
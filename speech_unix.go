@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "os/exec"
+import "runtime"
+
+// command to speak text from stdin (nil if not available)
+func speechCommand() []string {
+	candidates := [][]string{{"espeak-ng", "--stdin"}, {"espeak", "--stdin"}}
+	if runtime.GOOS == "darwin" {
+		candidates = [][]string{{"say"}}
+	}
+	for _, command := range candidates {
+		if _, err := exec.LookPath(command[0]); err == nil {
+			return command
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package main
+
+import "os/exec"
+
+// SAPI through PowerShell, which reads text from stdin
+const SAPI_SCRIPT = "Add-Type -AssemblyName System.Speech; " +
+	"(New-Object System.Speech.Synthesis.SpeechSynthesizer).Speak([Console]::In.ReadToEnd())"
+
+// command to speak text from stdin (nil if not available)
+func speechCommand() []string {
+	if _, err := exec.LookPath("powershell"); err != nil {
+		return nil
+	}
+	return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", SAPI_SCRIPT}
+}
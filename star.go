@@ -0,0 +1,112 @@
+package main
+
+import "fmt"
+import "net/url"
+import "strconv"
+
+//==============================
+// stars (saved items)
+//==============================
+
+// @see https://api.slack.com/methods/stars.list
+type SlackStarsListResponse struct {
+	Ok    bool
+	Items []map[string]interface{}
+}
+
+const SAVED_COUNT = 20
+
+func init() {
+	g_EventHandlers.RegisterFunc("star_added", "", onStarAdded)
+	g_EventHandlers.RegisterFunc("star_removed", "", onStarRemoved)
+	g_Commands["saved"] = Command{"", "list my saved items", runSaved}
+}
+
+//==============================
+// type: "star_added", "star_removed"
+//==============================
+
+func onStarAdded(msg map[string]interface{}) {
+	printStatus(formatStarEvent(msg, "saved"))
+}
+
+func onStarRemoved(msg map[string]interface{}) {
+	printStatus(formatStarEvent(msg, "unsaved"))
+}
+
+// "@me saved a message from #dev: text"
+func formatStarEvent(msg map[string]interface{}, action string) string {
+	item, _ := msg["item"].(map[string]interface{})
+	return "@" + getUserByMessage(msg) + " " + action + " " + describeStarItem(item)
+}
+
+// what the saved item is
+func describeStarItem(item map[string]interface{}) string {
+	itemType, _ := item["type"].(string)
+	channel, _ := item["channel"].(string)
+	switch itemType {
+	case "message":
+		description := "a message from #" + getChannel(channel)
+		if message, exist := item["message"].(map[string]interface{}); exist {
+			if text := firstLine(unescape(getText(message))); len(text) > 0 {
+				description = description + ": " + truncateText(text, 40)
+			}
+		}
+		return description
+	case "file", "file_comment":
+		if file, exist := item["file"].(map[string]interface{}); exist {
+			if name, _ := file["name"].(string); len(name) > 0 {
+				return "a file: " + name
+			}
+		}
+		return "a file"
+	case "channel", "group", "im":
+		return "#" + getChannel(channel)
+	}
+	return "an item"
+}
+
+//==============================
+// /saved
+//==============================
+
+// @see https://api.slack.com/methods/stars.list
+func fetchStars(count int) ([]map[string]interface{}, error) {
+	query := url.Values{}
+	query.Set("count", strconv.Itoa(count))
+
+	starsResponse := SlackStarsListResponse{}
+	if err := callApi("stars.list", query, &starsResponse); err != nil {
+		return nil, err
+	}
+	return starsResponse.Items, nil
+}
+
+func runSaved(args []string) error {
+	items, err := fetchStars(SAVED_COUNT)
+	if err != nil {
+		return err
+	}
+
+	// display oldest first like live messages
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		msg, isMessage := item["message"].(map[string]interface{})
+		if itemType, _ := item["type"].(string); itemType != "message" || !isMessage {
+			printStatus("saved " + describeStarItem(item))
+			continue
+		}
+
+		if channel, exist := item["channel"].(string); exist {
+			msg["channel"] = channel
+		}
+		message := newMessage(msg)
+		message.User = getUserByMessage(msg)
+		message.Text = unescape(getText(msg))
+		renderMessage(message)
+		rememberMessage(message)
+	}
+
+	printStatus(fmt.Sprintf("%d saved items", len(items)))
+	return nil
+}
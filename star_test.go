@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestFormatStarEvent(t *testing.T) {
+	g_Config = defaultConfig()
+	g_NameCache = NewNameCache(map[string]string{"U01": "alice", "C01": "dev"})
+
+	msg := map[string]interface{}{
+		"type": "star_added",
+		"user": "U01",
+		"item": map[string]interface{}{
+			"type":    "message",
+			"channel": "C01",
+			"message": map[string]interface{}{"text": "release notes\nsecond line", "ts": "1600000000.000100"},
+		},
+	}
+	expected := "@alice saved a message from #dev: release notes"
+	if result := formatStarEvent(msg, "saved"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	msg["item"] = map[string]interface{}{"type": "file", "file": map[string]interface{}{"name": "spec.pdf"}}
+	expected = "@alice unsaved a file: spec.pdf"
+	if result := formatStarEvent(msg, "unsaved"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	msg["item"] = map[string]interface{}{"type": "channel", "channel": "C01"}
+	expected = "@alice saved #dev"
+	if result := formatStarEvent(msg, "saved"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
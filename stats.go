@@ -0,0 +1,157 @@
+package main
+
+import "encoding/json"
+import "flag"
+import "fmt"
+import "os"
+import "regexp"
+import "sort"
+import "strconv"
+import "text/tabwriter"
+
+//==============================
+// subcommand "stats"
+//==============================
+
+// number of messages of a channel, user, day, etc
+type StatCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+// activity in the archive
+type ArchiveStats struct {
+	Total      int         `json:"total"`
+	Channels   []StatCount `json:"channels"`
+	Users      []StatCount `json:"users"`
+	Days       []StatCount `json:"days"`       // in order of date
+	Hours      []StatCount `json:"hours"`      // busiest first
+	Highlights []StatCount `json:"highlights"` // notification patterns which matched
+}
+
+// counts keyed by name while reading the archive
+type statCounter map[string]int
+
+// sort by count (descending) or key, and keep top if positive
+func (c statCounter) sorted(byKey bool, top int) []StatCount {
+	counts := []StatCount{}
+	for key, count := range c {
+		counts = append(counts, StatCount{key, count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if byKey || counts[i].Count == counts[j].Count {
+			return counts[i].Key < counts[j].Key
+		}
+		return counts[i].Count > counts[j].Count
+	})
+	if top > 0 && len(counts) > top {
+		counts = counts[:top]
+	}
+	return counts
+}
+
+// aggregate messages matching query
+func collectStats(path string, query ArchiveQuery, patterns []*regexp.Regexp, top int) (ArchiveStats, error) {
+	channels := statCounter{}
+	users := statCounter{}
+	days := statCounter{}
+	hours := statCounter{}
+	highlights := statCounter{}
+	total := 0
+
+	err := readArchive(path, func(archived JsonlMessage) bool {
+		if !query.Match(archived) {
+			return true
+		}
+		total++
+		channels[archived.Channel]++
+		users[archived.User]++
+		time := parseTs(archived.Ts)
+		days[time.Format("2006-01-02")]++
+		hours[fmt.Sprintf("%02d:00", time.Hour())]++
+		for _, pattern := range patterns {
+			if pattern.MatchString(archived.Text) {
+				highlights[pattern.String()]++
+			}
+		}
+		return true
+	})
+
+	return ArchiveStats{
+		Total:      total,
+		Channels:   channels.sorted(false, top),
+		Users:      users.sorted(false, top),
+		Days:       days.sorted(true, 0),
+		Hours:      hours.sorted(false, 0),
+		Highlights: highlights.sorted(false, top),
+	}, err
+}
+
+// print stats as tables
+func printStats(stats ArchiveStats) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(writer, "total\t%d\t\n", stats.Total)
+
+	sections := []struct {
+		title  string
+		counts []StatCount
+	}{
+		{"channel", stats.Channels},
+		{"user", stats.Users},
+		{"day", stats.Days},
+		{"hour", stats.Hours},
+		{"highlight", stats.Highlights},
+	}
+	for _, section := range sections {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(writer, "\t\t\n%s\tmessages\t\n", section.title)
+		for _, count := range section.counts {
+			fmt.Fprintf(writer, "%s\t%s\t\n", count.Key, strconv.Itoa(count.Count))
+		}
+	}
+	writer.Flush()
+}
+
+func runStats(args []string) error {
+	query := ArchiveQuery{}
+	since := ""
+	asJson := false
+	top := 0
+
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	flags.StringVar(&query.Channel, "channel", "", "count only in the channel")
+	flags.StringVar(&query.User, "user", "", "count only messages from the user")
+	flags.StringVar(&since, "since", "", "count messages since date (2006-01-02) or duration (48h)")
+	flags.BoolVar(&asJson, "json", false, "print as JSON")
+	flags.IntVar(&top, "top", 10, "number of channels, users and highlights to list (0 for all)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: slackv stats [options]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if len(g_Config.Archive.Path) == 0 {
+		return fmt.Errorf("[archive] path is not configured")
+	}
+	if len(since) > 0 {
+		var err error
+		if query.Since, err = parseSince(since); err != nil {
+			return err
+		}
+	}
+
+	stats, err := collectStats(g_Config.Archive.Path, query, g_NotificationPatterns, top)
+	if err != nil {
+		return err
+	}
+
+	if asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+	printStats(stats)
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import "os"
+import "path/filepath"
+import "regexp"
+import "testing"
+
+func TestCollectStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	lines := `{"ts":"1600000000.000100","channel":"general","user":"alice","text":"deploy failed"}
+{"ts":"1600000100.000100","channel":"dev","user":"alice","text":"deploy done"}
+{"ts":"1600000200.000100","channel":"dev","user":"bob","text":"lunch?"}
+`
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patterns := []*regexp.Regexp{regexp.MustCompile("deploy"), regexp.MustCompile("failed")}
+	stats, err := collectStats(path, ArchiveQuery{}, patterns, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Total != 3 {
+		t.Errorf("expected 3, but %d\n", stats.Total)
+	}
+	expected := []StatCount{{"dev", 2}}
+	if len(stats.Channels) != 1 || stats.Channels[0] != expected[0] {
+		t.Errorf("expected %v, but %v\n", expected, stats.Channels)
+	}
+	if len(stats.Users) != 1 || stats.Users[0].Key != "alice" {
+		t.Errorf("unexpected users %v\n", stats.Users)
+	}
+	if len(stats.Highlights) != 1 || stats.Highlights[0] != (StatCount{"deploy", 2}) {
+		t.Errorf("unexpected highlights %v\n", stats.Highlights)
+	}
+}
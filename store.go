@@ -0,0 +1,193 @@
+package main
+
+//==============================
+// store of displayed messages
+//==============================
+
+type MessageKey struct {
+	ChannelId string
+	Ts        string
+}
+
+type Reaction struct {
+	Name  string
+	Users []string // user ids
+}
+
+// displayed message with later changes
+type StoredMessage struct {
+	Message
+	Reactions []Reaction
+	Edited    bool
+	Deleted   bool
+}
+
+// displayed messages addressable by channel and ts
+//
+// Frontends which can redraw messages (e.g. TUI) set OnUpdate to update them in place.
+type MessageStore struct {
+	Capacity int
+	OnUpdate func(stored *StoredMessage)
+
+	messages map[MessageKey]*StoredMessage
+	order    []MessageKey // oldest first
+}
+
+const MESSAGE_STORE_CAPACITY = 5000
+
+var g_MessageStore = NewMessageStore(MESSAGE_STORE_CAPACITY)
+
+func NewMessageStore(capacity int) *MessageStore {
+	return &MessageStore{
+		Capacity: capacity,
+		messages: map[MessageKey]*StoredMessage{},
+	}
+}
+
+func (m Message) Key() MessageKey {
+	return MessageKey{m.ChannelId, m.Ts}
+}
+
+func (s *MessageStore) Get(key MessageKey) (*StoredMessage, bool) {
+	stored, exist := s.messages[key]
+	return stored, exist
+}
+
+func (s *MessageStore) Len() int {
+	return len(s.order)
+}
+
+// latest n messages accepted by fn (oldest first)
+func (s *MessageStore) Latest(n int, fn func(stored *StoredMessage) bool) []*StoredMessage {
+	latest := []*StoredMessage{}
+	for i := len(s.order) - 1; i >= 0 && len(latest) < n; i-- {
+		if stored := s.messages[s.order[i]]; fn(stored) {
+			latest = append(latest, stored)
+		}
+	}
+
+	// reverse
+	for i, j := 0, len(latest)-1; i < j; i, j = i+1, j-1 {
+		latest[i], latest[j] = latest[j], latest[i]
+	}
+	return latest
+}
+
+// add displayed message
+//
+// The oldest message is removed if over capacity.
+func (s *MessageStore) Add(message Message) *StoredMessage {
+	key := message.Key()
+	if stored, exist := s.messages[key]; exist {
+		stored.Message = message
+		s.update(stored)
+		return stored
+	}
+
+	stored := &StoredMessage{Message: message}
+	s.messages[key] = stored
+	s.order = append(s.order, key)
+
+	if s.Capacity > 0 && len(s.order) > s.Capacity {
+		delete(s.messages, s.order[0])
+		s.order = s.order[1:]
+	}
+	return stored
+}
+
+func (s *MessageStore) Edit(key MessageKey, text string) {
+	if stored, exist := s.messages[key]; exist {
+		stored.Text = text
+		stored.Edited = true
+		s.update(stored)
+	}
+}
+
+func (s *MessageStore) Delete(key MessageKey) {
+	if stored, exist := s.messages[key]; exist {
+		stored.Deleted = true
+		s.update(stored)
+	}
+}
+
+func (s *MessageStore) AddReaction(key MessageKey, name string, user string) {
+	stored, exist := s.messages[key]
+	if !exist {
+		return
+	}
+
+	for i := range stored.Reactions {
+		if stored.Reactions[i].Name == name {
+			if !equalsAnyKeywords(user, stored.Reactions[i].Users) {
+				stored.Reactions[i].Users = append(stored.Reactions[i].Users, user)
+			}
+			s.update(stored)
+			return
+		}
+	}
+	stored.Reactions = append(stored.Reactions, Reaction{name, []string{user}})
+	s.update(stored)
+}
+
+func (s *MessageStore) RemoveReaction(key MessageKey, name string, user string) {
+	stored, exist := s.messages[key]
+	if !exist {
+		return
+	}
+
+	for i := range stored.Reactions {
+		if stored.Reactions[i].Name != name {
+			continue
+		}
+		users := []string{}
+		for _, u := range stored.Reactions[i].Users {
+			if u != user {
+				users = append(users, u)
+			}
+		}
+		if len(users) > 0 {
+			stored.Reactions[i].Users = users
+		} else {
+			stored.Reactions = append(stored.Reactions[:i], stored.Reactions[i+1:]...)
+		}
+		s.update(stored)
+		return
+	}
+}
+
+func (s *MessageStore) update(stored *StoredMessage) {
+	if s.OnUpdate != nil {
+		s.OnUpdate(stored)
+	}
+}
+
+//==============================
+// type: "reaction_added", "reaction_removed"
+//==============================
+
+// key of the message which the reaction is added to
+func getReactionItemKey(msg map[string]interface{}) (MessageKey, bool) {
+	item, exist := msg["item"].(map[string]interface{})
+	if !exist || item["type"] != "message" {
+		return MessageKey{}, false
+	}
+	channel, _ := item["channel"].(string)
+	ts, _ := item["ts"].(string)
+	return MessageKey{channel, ts}, true
+}
+
+func onReactionAdded(msg map[string]interface{}) {
+	if key, exist := getReactionItemKey(msg); exist {
+		reaction, _ := msg["reaction"].(string)
+		user, _ := msg["user"].(string)
+		g_MessageStore.AddReaction(key, reaction, user)
+	}
+}
+
+func onReactionRemoved(msg map[string]interface{}) {
+	if key, exist := getReactionItemKey(msg); exist {
+		reaction, _ := msg["reaction"].(string)
+		user, _ := msg["user"].(string)
+		g_MessageStore.RemoveReaction(key, reaction, user)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestMessageStore(t *testing.T) {
+	store := NewMessageStore(2)
+	updated := 0
+	store.OnUpdate = func(stored *StoredMessage) { updated++ }
+
+	first := store.Add(Message{ChannelId: "C01234", Ts: "1.000001", Text: "first"})
+	store.Add(Message{ChannelId: "C01234", Ts: "1.000002", Text: "second"})
+
+	store.Edit(first.Key(), "first!")
+	store.AddReaction(first.Key(), "+1", "U01234")
+	store.AddReaction(first.Key(), "+1", "U05678")
+	if first.Text != "first!" || !first.Edited || len(first.Reactions[0].Users) != 2 {
+		t.Errorf("unexpected %+v\n", first)
+	}
+	store.RemoveReaction(first.Key(), "+1", "U01234")
+	store.RemoveReaction(first.Key(), "+1", "U05678")
+	if len(first.Reactions) != 0 {
+		t.Errorf("expected no reactions, but %+v\n", first.Reactions)
+	}
+	if updated != 5 {
+		t.Errorf("expected 5 updates, but %d\n", updated)
+	}
+
+	// over capacity
+	store.Add(Message{ChannelId: "C01234", Ts: "1.000003", Text: "third"})
+	if _, exist := store.Get(first.Key()); exist || store.Len() != 2 {
+		t.Errorf("expected the oldest message to be removed\n")
+	}
+}
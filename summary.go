@@ -0,0 +1,144 @@
+package main
+
+import "fmt"
+import "log"
+import "strings"
+import "time"
+
+//==============================
+// daily summary
+//==============================
+
+// when the daily summary was printed
+type SummarySchedule struct {
+	Last string // date, or "" before the first check
+}
+
+var g_SummarySchedule = SummarySchedule{}
+
+// whether to print the summary at "15:04" now
+//
+// Not due on the day started after the time, because the session has little history.
+func (s *SummarySchedule) Due(now time.Time, at string) bool {
+	clock, err := time.ParseInLocation("15:04", at, now.Location())
+	if err != nil {
+		return false
+	}
+	today := now.Format("2006-01-02")
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+
+	first := len(s.Last) == 0
+	if first {
+		s.Last = "-"
+	}
+	if now.Before(scheduled) || s.Last == today {
+		return false
+	}
+	s.Last = today
+	return !first
+}
+
+// activity of the day compiled from displayed messages
+type DailySummary struct {
+	Channels   []StatCount
+	Unanswered []Message // DMs after your last reply in each DM
+	Highlights []Message
+}
+
+// your display name to find your messages
+func selfName() string {
+	if name := g_NameCache.Name(g_Self.Id); len(name) > 0 {
+		return name
+	}
+	return g_Self.Name
+}
+
+func compileDailySummary(messages []*StoredMessage, since time.Time, self string) DailySummary {
+	summary := DailySummary{}
+	channels := statCounter{}
+	unanswered := map[string][]Message{}
+	dmOrder := []string{}
+
+	for _, stored := range messages {
+		message := stored.Message
+		if stored.Deleted || parseTs(message.Ts).Before(since) {
+			continue
+		}
+		channels[message.Channel]++
+		if message.Highlight {
+			summary.Highlights = append(summary.Highlights, message)
+		}
+		if isDirectMessage(message) {
+			if _, exist := unanswered[message.ChannelId]; !exist {
+				dmOrder = append(dmOrder, message.ChannelId)
+			}
+			if message.User == self {
+				unanswered[message.ChannelId] = []Message{}
+			} else {
+				unanswered[message.ChannelId] = append(unanswered[message.ChannelId], message)
+			}
+		}
+	}
+
+	summary.Channels = channels.sorted(false, 0)
+	for _, channelId := range dmOrder {
+		summary.Unanswered = append(summary.Unanswered, unanswered[channelId]...)
+	}
+	return summary
+}
+
+func formatDailySummary(summary DailySummary, date time.Time) []string {
+	lines := []string{"Summary of " + date.Format("2006/01/02")}
+
+	counts := []string{}
+	for _, count := range summary.Channels {
+		counts = append(counts, fmt.Sprintf("#%s %d", count.Key, count.Count))
+	}
+	if len(counts) == 0 {
+		counts = append(counts, "no message")
+	}
+	lines = append(lines, "  messages: "+strings.Join(counts, ", "))
+
+	format := func(message Message) string {
+		text := strings.SplitN(stripAnsi(message.Text), "\n", 2)[0]
+		return fmt.Sprintf("    %s #%s @%s: %s", parseTs(message.Ts).Format("15:04"), message.Channel, message.User, text)
+	}
+	if len(summary.Unanswered) > 0 {
+		lines = append(lines, fmt.Sprintf("  unanswered DMs: %d", len(summary.Unanswered)))
+		for _, message := range summary.Unanswered {
+			lines = append(lines, format(message))
+		}
+	}
+	if len(summary.Highlights) > 0 {
+		lines = append(lines, fmt.Sprintf("  highlights: %d", len(summary.Highlights)))
+		for _, message := range summary.Highlights {
+			lines = append(lines, format(message))
+		}
+	}
+	return lines
+}
+
+// print summary of today at [notification] daily-summary
+func printDailySummaryIfDue(now time.Time) {
+	at := g_Config.Notification.DailySummary
+	if len(at) == 0 || !g_SummarySchedule.Due(now, at) {
+		return
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	messages := g_MessageStore.Latest(g_MessageStore.Len(), func(stored *StoredMessage) bool { return true })
+	summary := compileDailySummary(messages, midnight, selfName())
+	for _, line := range formatDailySummary(summary, now) {
+		printStatus(line)
+	}
+}
+
+// validate "15:04" in config
+func checkDailySummaryTime(at string) {
+	if len(at) == 0 {
+		return
+	}
+	if _, err := time.Parse("15:04", at); err != nil {
+		log.Printf("invalid daily-summary (expected \"15:04\"): %s", at)
+	}
+}
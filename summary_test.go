@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+import "time"
+
+func TestSummaryScheduleDue(t *testing.T) {
+	day := time.Date(2020, 9, 13, 0, 0, 0, 0, time.Local)
+
+	s := SummarySchedule{}
+	if s.Due(day.Add(17*time.Hour), "18:00") {
+		t.Errorf("expected not due before the time\n")
+	}
+	if !s.Due(day.Add(18*time.Hour), "18:00") {
+		t.Errorf("expected due at the time\n")
+	}
+	if s.Due(day.Add(19*time.Hour), "18:00") {
+		t.Errorf("expected due only once a day\n")
+	}
+
+	// started after the time
+	s = SummarySchedule{}
+	if s.Due(day.Add(20*time.Hour), "18:00") {
+		t.Errorf("expected not due on the first day\n")
+	}
+	if !s.Due(day.Add(42*time.Hour), "18:00") {
+		t.Errorf("expected due on the next day\n")
+	}
+}
+
+func TestCompileDailySummary(t *testing.T) {
+	since := parseTs("1600000000.000000")
+	messages := []*StoredMessage{
+		{Message: Message{Ts: "1599999999.000100", ChannelId: "C01", Channel: "dev", User: "bob", Highlight: true}},
+		{Message: Message{Ts: "1600000001.000100", ChannelId: "C01", Channel: "dev", User: "bob", Text: "deploy", Highlight: true}},
+		{Message: Message{Ts: "1600000002.000100", ChannelId: "D01", Channel: "bob", User: "bob", Text: "hi"}},
+		{Message: Message{Ts: "1600000003.000100", ChannelId: "D01", Channel: "bob", User: "me", Text: "hello"}},
+		{Message: Message{Ts: "1600000004.000100", ChannelId: "D02", Channel: "carol", User: "carol", Text: "lunch?"}},
+	}
+
+	summary := compileDailySummary(messages, since, "me")
+	if len(summary.Highlights) != 1 || summary.Highlights[0].Text != "deploy" {
+		t.Errorf("unexpected highlights %v\n", summary.Highlights)
+	}
+	if len(summary.Unanswered) != 1 || summary.Unanswered[0].User != "carol" {
+		t.Errorf("unexpected unanswered DMs %v\n", summary.Unanswered)
+	}
+	if len(summary.Channels) != 3 || summary.Channels[0] != (StatCount{"bob", 2}) {
+		t.Errorf("unexpected channels %v\n", summary.Channels)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import "log"
+import "net"
+import "os"
+import "strconv"
+import "time"
+
+//==============================
+// systemd notification
+//==============================
+
+// whether READY=1 is sent
+var g_SystemdReady bool
+
+// send state to systemd (no-op if not run by systemd with Type=notify)
+//
+// @see https://www.freedesktop.org/software/systemd/man/sd_notify.html
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if len(socket) == 0 {
+		return nil
+	}
+
+	// "@" for abstract namespace is handled by net package
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// called on "hello" event
+func notifySystemdReady() {
+	if g_SystemdReady {
+		return
+	}
+	g_SystemdReady = true
+	if err := sdNotify("READY=1\nSTATUS=Connected"); err != nil {
+		log.Print(err)
+	}
+}
+
+// interval of WATCHDOG=1 by WatchdogSec= of the unit (0 if disabled)
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); len(pid) > 0 && pid != strconv.Itoa(os.Getpid()) {
+		// for another process
+		return 0
+	}
+	// twice in the timeout as recommended
+	return time.Duration(usec) * time.Microsecond / 2
+}
+
+// send WATCHDOG=1 while websocket is alive, so that systemd restarts a dead session
+func startWatchdog() {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	maxEventAge := time.Duration(g_Config.Health.MaxEventAge) * time.Second
+	go func() {
+		for range time.Tick(interval) {
+			if g_Health.Report(time.Now(), maxEventAge).Status != "ok" {
+				continue
+			}
+			if err := sdNotify("WATCHDOG=1"); err != nil {
+				log.Print(err)
+			}
+		}
+	}()
+}
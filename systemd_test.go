@@ -0,0 +1,46 @@
+package main
+
+import "net"
+import "os"
+import "path/filepath"
+import "testing"
+import "time"
+
+func TestSdNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", path)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buffer[:n]) != "READY=1" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "READY=1", buffer[:n])
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	defer os.Unsetenv("WATCHDOG_USEC")
+	if interval := watchdogInterval(); interval != 15*time.Second {
+		t.Errorf("expected %s, but %s\n", 15*time.Second, interval)
+	}
+
+	os.Setenv("WATCHDOG_PID", "1")
+	defer os.Unsetenv("WATCHDOG_PID")
+	if interval := watchdogInterval(); interval != 0 {
+		t.Errorf("expected 0 for another process, but %s\n", interval)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import "fmt"
+import "os"
+
+import "slackv/console"
+
+//==============================
+// terminal title
+//==============================
+
+// counts of messages displayed since the last input
+var g_UnseenMessages = 0
+var g_UnseenHighlights = 0
+
+func countUnseen(message Message) {
+	if message.Subtype == "message_changed" {
+		return
+	}
+	g_UnseenMessages++
+	if message.Highlight {
+		g_UnseenHighlights++
+	}
+	updateTitle()
+}
+
+// reset counts when the user interacts
+func clearUnseen() {
+	if g_UnseenMessages == 0 && g_UnseenHighlights == 0 {
+		return
+	}
+	g_UnseenMessages = 0
+	g_UnseenHighlights = 0
+	updateTitle()
+}
+
+func formatTitle() string {
+	title := "slackv"
+	if len(g_Team.Name) > 0 {
+		title = title + " - " + g_Team.Name
+	}
+	if g_UnseenHighlights > 0 {
+		title = fmt.Sprintf("%s [%d!]", title, g_UnseenHighlights)
+	}
+	if g_UnseenMessages > 0 {
+		title = fmt.Sprintf("%s (%d)", title, g_UnseenMessages)
+	}
+	return title
+}
+
+func updateTitle() {
+	if !g_Config.Display.Title || g_OutputMode == OUTPUT_MODE_JSONL || !console.IsTTY() {
+		// not to write escape sequences into a file or pipe
+		return
+	}
+
+	title := formatTitle()
+	printConsole("\033]2;" + title + "\007")
+	if g_Config.Display.TmuxTitle && len(os.Getenv("TMUX")) > 0 {
+		printConsole("\033k" + title + "\033\\")
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "io/ioutil"
+import "testing"
+
+func TestFormatTitle(t *testing.T) {
+	g_Team = SlackTeam{Id: "T01", Name: "team"}
+	g_UnseenMessages = 0
+	g_UnseenHighlights = 0
+
+	countUnseen(Message{Text: "hello"})
+	countUnseen(Message{Text: "hi @alice", Highlight: true})
+	countUnseen(Message{Text: "hi @alice", Highlight: true, Subtype: "message_changed"})
+
+	expected := "slackv - team [1!] (2)"
+	if result := formatTitle(); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	clearUnseen()
+	expected = "slackv - team"
+	if result := formatTitle(); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
+
+func TestTeamRename(t *testing.T) {
+	savedTeam, savedOutput := g_Team, g_Output
+	defer func() { g_Team, g_Output = savedTeam, savedOutput }()
+	g_Config = defaultConfig()
+	g_Team = SlackTeam{Id: "T01", Name: "Acme", Domain: "acme"}
+	g_Output = ioutil.Discard
+	g_UnseenMessages, g_UnseenHighlights = 0, 0
+
+	dispatchEvent(map[string]interface{}{"type": "team_rename", "name": "Acme Corp"})
+	expected := "slackv - Acme Corp"
+	if result := formatTitle(); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+
+	dispatchEvent(map[string]interface{}{"type": "team_domain_change", "url": "https://acme-corp.slack.com/", "domain": "acme-corp"})
+	expected = "https://acme-corp.slack.com/archives/C01"
+	if result := channelUrl("C01"); result != expected {
+		t.Errorf("expected \"%s\", but \"%s\"\n", expected, result)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import "log"
+import "net/url"
+import "sort"
+import "strings"
+
+//==============================
+// user token and bot token
+//==============================
+
+// scopes of Web API methods (any of them is enough)
+//
+// @see https://api.slack.com/methods
+var g_MethodScopes = map[string][]string{
+	"bots.info":              {"users:read"},
+	"chat.postMessage":       {"chat:write"},
+	"conversations.create":   {"channels:manage", "groups:write", "channels:write"},
+	"conversations.history":  {"channels:history", "groups:history", "im:history", "mpim:history"},
+	"conversations.info":     {"channels:read", "groups:read", "im:read", "mpim:read"},
+	"conversations.invite":   {"channels:manage", "groups:write", "channels:write"},
+	"conversations.list":     {"channels:read", "groups:read", "im:read", "mpim:read"},
+	"conversations.mark":     {"channels:write", "groups:write", "im:write", "mpim:write"},
+	"conversations.members":  {"channels:read", "groups:read", "im:read", "mpim:read"},
+	"conversations.setTopic": {"channels:manage", "groups:write", "channels:write"},
+	"emoji.list":             {"emoji:read"},
+	"pins.add":               {"pins:write"},
+	"pins.list":              {"pins:read"},
+	"pins.remove":            {"pins:write"},
+	"reactions.add":          {"reactions:write"},
+	"search.messages":        {"search:read"},
+	"stars.list":             {"stars:read"},
+	"team.info":              {"team:read"},
+	"usergroups.list":        {"usergroups:read"},
+	"users.conversations":    {"channels:read", "groups:read", "im:read", "mpim:read"},
+	"users.info":             {"users:read"},
+	"users.list":             {"users:read"},
+}
+
+// scopes granted to tokens, detected by auth.test (nil if unknown)
+type TokenScopes struct {
+	User map[string]struct{}
+	Bot  map[string]struct{}
+}
+
+var g_TokenScopes = TokenScopes{}
+
+func hasAnyScope(granted map[string]struct{}, needed []string) bool {
+	for _, scope := range needed {
+		if _, exist := granted[scope]; exist {
+			return true
+		}
+	}
+	return false
+}
+
+// token to call method with
+//
+// The user token is used unless only the bot token has the scope of method.
+func tokenFor(method string, scopes TokenScopes, userToken string, botToken string) string {
+	if len(botToken) == 0 {
+		return userToken
+	}
+	needed, known := g_MethodScopes[method]
+	if len(userToken) == 0 || (known && !hasAnyScope(scopes.User, needed) && hasAnyScope(scopes.Bot, needed)) {
+		return botToken
+	}
+	return userToken
+}
+
+// the other token to retry with after missing_scope ("" if none)
+func otherToken(token string) string {
+	switch token {
+	case g_Config.General.Token:
+		return g_Config.General.BotToken
+	case g_Config.General.BotToken:
+		return g_Config.General.Token
+	}
+	return ""
+}
+
+// scopes of token from X-OAuth-Scopes of auth.test
+func fetchTokenScopes(token string) (map[string]struct{}, error) {
+	query := url.Values{}
+	query.Set("token", token)
+	header, err := requestApi("auth.test", query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := map[string]struct{}{}
+	for _, scope := range strings.Split(header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); len(scope) > 0 {
+			scopes[scope] = struct{}{}
+		}
+	}
+	return scopes, nil
+}
+
+// methods which neither token can call, with their scopes
+func missingScopes(scopes TokenScopes) map[string][]string {
+	missing := map[string][]string{}
+	for method, needed := range g_MethodScopes {
+		if !hasAnyScope(scopes.User, needed) && !hasAnyScope(scopes.Bot, needed) {
+			missing[method] = needed
+		}
+	}
+	return missing
+}
+
+// detect scopes of tokens, and report methods unavailable by them
+func detectTokenScopes() {
+	var err error
+	if len(g_Config.General.Token) > 0 {
+		if g_TokenScopes.User, err = fetchTokenScopes(g_Config.General.Token); err != nil {
+			log.Printf("auth.test of user token: %v", err)
+		}
+	}
+	if len(g_Config.General.BotToken) > 0 {
+		if g_TokenScopes.Bot, err = fetchTokenScopes(g_Config.General.BotToken); err != nil {
+			log.Printf("auth.test of bot token: %v", err)
+		}
+	}
+	if len(g_TokenScopes.User) == 0 && len(g_TokenScopes.Bot) == 0 {
+		// unknown (e.g. legacy tokens without X-OAuth-Scopes)
+		return
+	}
+
+	missing := missingScopes(g_TokenScopes)
+	methods := []string{}
+	for method := range missing {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		log.Printf("missing scope: %s needs %s", method, strings.Join(missing[method], " or "))
+	}
+}
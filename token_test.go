@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestTokenFor(t *testing.T) {
+	scopes := TokenScopes{
+		User: map[string]struct{}{"channels:history": {}},
+		Bot:  map[string]struct{}{"chat:write": {}, "channels:history": {}},
+	}
+
+	tests := []struct {
+		method   string
+		bot      string
+		expected string
+	}{
+		{"conversations.history", "xoxb", "xoxp"},
+		{"chat.postMessage", "xoxb", "xoxb"},
+		{"chat.postMessage", "", "xoxp"},
+		{"emoji.list", "xoxb", "xoxp"},
+		{"unknown.method", "xoxb", "xoxp"},
+	}
+	for _, test := range tests {
+		if result := tokenFor(test.method, scopes, "xoxp", test.bot); result != test.expected {
+			t.Errorf("%s: expected \"%s\", but \"%s\"\n", test.method, test.expected, result)
+		}
+	}
+}
+
+func TestMissingScopes(t *testing.T) {
+	scopes := TokenScopes{User: map[string]struct{}{"users:read": {}, "pins:write": {}}}
+	missing := missingScopes(scopes)
+	if _, exist := missing["users.info"]; exist {
+		t.Errorf("expected users.info available\n")
+	}
+	if _, exist := missing["chat.postMessage"]; !exist {
+		t.Errorf("expected chat.postMessage missing\n")
+	}
+}
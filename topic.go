@@ -0,0 +1,95 @@
+package main
+
+import "strings"
+
+//==============================
+// channel topic and purpose
+//==============================
+
+type ChannelTopic struct {
+	Topic   string
+	Purpose string
+}
+
+// topics keyed by channel id
+var g_ChannelTopics = map[string]ChannelTopic{}
+
+// channels already displayed in this session
+var g_IntroducedChannels = map[string]struct{}{}
+
+func rememberChannelTopic(channel SlackChannel) {
+	g_ChannelTopics[channel.Id] = ChannelTopic{
+		Topic:   channel.Topic.Value,
+		Purpose: channel.Purpose.Value,
+	}
+}
+
+// lines of topic and purpose (empty if none)
+func formatChannelIntro(topic ChannelTopic) string {
+	text := ""
+	if len(topic.Topic) > 0 {
+		text = text + "\033[90mtopic: " + unescape(topic.Topic) + "\033[0m\n"
+	}
+	if len(topic.Purpose) > 0 && topic.Purpose != topic.Topic {
+		text = text + "\033[90mpurpose: " + unescape(topic.Purpose) + "\033[0m\n"
+	}
+	return text
+}
+
+// display topic and purpose on the first message of channel
+//
+// Topics not cached are fetched in background, and displayed at the next header of channel.
+func printChannelIntro(channelId string) {
+	if _, introduced := g_IntroducedChannels[channelId]; introduced || len(channelId) == 0 {
+		return
+	}
+
+	topic, cached := g_ChannelTopics[channelId]
+	if !cached {
+		// not while rendering output of subcommands
+		key := "topic:" + channelId
+		if g_Resolver.Running() && !g_NameCache.IsFailing(key) {
+			g_Resolver.Request(key, func() (func(), error) {
+				info, err := fetchChannelInfo(channelId)
+				return func() { rememberChannelTopic(info) }, err
+			})
+		}
+		return
+	}
+	g_IntroducedChannels[channelId] = struct{}{}
+	printConsole(formatChannelIntro(topic))
+}
+
+//==============================
+// subtype: "channel_topic", "channel_purpose"
+//==============================
+
+func onMessageTopic(msg map[string]interface{}) {
+	message := newMessage(msg)
+	message.User = getUserByMessage(msg)
+
+	subtype, _ := msg["subtype"].(string)
+	topic := g_ChannelTopics[message.ChannelId]
+	kind := "topic"
+	value, _ := msg["topic"].(string)
+	if strings.HasSuffix(subtype, "_purpose") {
+		kind = "purpose"
+		value, _ = msg["purpose"].(string)
+		topic.Purpose = value
+	} else {
+		topic.Topic = value
+	}
+	if _, cached := g_ChannelTopics[message.ChannelId]; cached {
+		g_ChannelTopics[message.ChannelId] = topic
+	}
+
+	message.Text = formatTopicChange(kind, message.Channel, value)
+	printMessage(message)
+}
+
+func formatTopicChange(kind string, channel string, value string) string {
+	if len(value) == 0 {
+		return "\033[90mcleared the " + kind + " of #" + channel + "\033[0m"
+	}
+	return "\033[90mset the " + kind + " of #" + channel + " to:\033[0m " + value
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestFormatChannelIntro(t *testing.T) {
+	expected := "\033[90mtopic: release on Friday\033[0m\n\033[90mpurpose: development\033[0m\n"
+	result := formatChannelIntro(ChannelTopic{Topic: "release on Friday", Purpose: "development"})
+	if result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+
+	if result := formatChannelIntro(ChannelTopic{}); result != "" {
+		t.Errorf("expected \"\", but %q\n", result)
+	}
+}
+
+func TestFormatTopicChange(t *testing.T) {
+	expected := "\033[90mset the topic of #dev to:\033[0m release on Friday"
+	if result := formatTopicChange("topic", "dev", "release on Friday"); result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+	expected = "\033[90mcleared the purpose of #dev\033[0m"
+	if result := formatTopicChange("purpose", "dev", ""); result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
+
+func TestPrintChannelIntroNotCached(t *testing.T) {
+	savedResolver := g_Resolver
+	defer func() {
+		g_Resolver = savedResolver
+		g_ChannelTopics = map[string]ChannelTopic{}
+		g_IntroducedChannels = map[string]struct{}{}
+	}()
+	g_ChannelTopics = map[string]ChannelTopic{}
+	g_IntroducedChannels = map[string]struct{}{}
+	g_NameCache = NewNameCache(map[string]string{})
+
+	// no request in subcommands
+	g_Resolver = NewResolver()
+	printChannelIntro("C01")
+	if len(g_Resolver.pending) != 0 {
+		t.Errorf("expected no request, but %v\n", g_Resolver.pending)
+	}
+
+	// requested in background, and introduced after cached
+	g_Resolver.running = true
+	printChannelIntro("C01")
+	if _, exist := g_Resolver.pending["topic:C01"]; !exist {
+		t.Errorf("expected topic requested, but %v\n", g_Resolver.pending)
+	}
+	if _, introduced := g_IntroducedChannels["C01"]; introduced {
+		t.Errorf("expected not introduced before cached\n")
+	}
+}
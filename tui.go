@@ -0,0 +1,376 @@
+package main
+
+import "fmt"
+import "log"
+import "os"
+import "regexp"
+import "strconv"
+import "strings"
+import "time"
+
+import "slackv/console"
+
+//==============================
+// TUI frontend
+//==============================
+
+// full screen interface with channel list, message pane and input line
+//
+// Messages are drawn from g_MessageStore, so updates of stored messages are
+// reflected in place.
+type Tui struct {
+	Width  int
+	Height int
+
+	View        string         // channel to display ("" for all)
+	Channels    []string       // latest active first
+	Unread      map[string]int // count of messages not displayed keyed by channel
+	Highlighted map[string]bool
+	Status      string
+	Scroll      int // lines scrolled back from the bottom
+
+	Search      *regexp.Regexp // pattern to highlight (nil if not searching)
+	SearchMatch MessageKey     // message found by the last search
+
+	Input        []rune // input line typed by keys (raw input only)
+	Incremental  bool   // searching while "/pattern" is typed
+	SearchOrigin int    // scroll before incremental search
+}
+
+const TUI_SIDEBAR_WIDTH = 20
+
+var g_Tui *Tui
+
+// notified when the terminal is resized
+var g_ResizeSignals = make(chan struct{}, 1)
+
+func init() {
+	g_Commands["view"] = Command{"[#channel]", "display only the channel in TUI (without argument, all)", runView}
+	g_Commands["page-up"] = Command{"", "scroll back a page in TUI (shortcut: PageUp and Enter)", runPageUp}
+	g_Commands["page-down"] = Command{"", "scroll forward a page in TUI (shortcut: PageDown and Enter)", runPageDown}
+	g_Commands["bottom"] = Command{"", "scroll to the latest message in TUI", runBottom}
+
+	// escape sequences of PageUp and PageDown keys
+	g_KeyCommands["\033[5~"] = "page-up"
+	g_KeyCommands["\033[6~"] = "page-down"
+}
+
+func startTui() {
+	width, height := terminalSize()
+	g_Tui = &Tui{
+		Width:       width,
+		Height:      height,
+		Unread:      map[string]int{},
+		Highlighted: map[string]bool{},
+	}
+
+	// alternate screen, and scroll only the input line
+	fmt.Printf("\033[?1049h\033[2J\033[%d;%dr", height, height)
+	console.NotifyResize(g_ResizeSignals)
+	log.SetOutput(tuiLogWriter{})
+	g_MessageStore.OnUpdate = func(stored *StoredMessage) {
+		g_Tui.Redraw()
+	}
+	g_Renderer = newRenderer()
+
+	g_Tui.Redraw()
+	g_Tui.DrawPrompt()
+}
+
+func stopTui() {
+	if g_Tui == nil {
+		return
+	}
+	fmt.Print("\033[r\033[?1049l")
+	console.DisableRawInput()
+	log.SetOutput(os.Stderr)
+	g_Tui = nil
+	g_Renderer = newRenderer()
+}
+
+// get terminal size (columns and lines)
+func terminalSize() (int, int) {
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	height, _ := strconv.Atoi(os.Getenv("LINES"))
+	if width > 0 && height > 0 {
+		return width, height
+	}
+
+	width, height, err := console.Size()
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// adapt to the new terminal size
+func (t *Tui) Resize(width int, height int) {
+	if width == t.Width && height == t.Height {
+		return
+	}
+	t.Width = width
+	t.Height = height
+
+	printConsole(fmt.Sprintf("\033[2J\033[%d;%dr", height, height))
+	t.Redraw()
+	t.DrawPrompt()
+}
+
+// display new message
+func (t *Tui) Show(message Message) {
+	if message.Subtype == "message_changed" {
+		// stored message is updated in place
+		return
+	}
+	if _, exist := g_MessageStore.Get(message.Key()); !exist {
+		g_MessageStore.Add(message)
+	}
+
+	channels := []string{message.Channel}
+	for _, channel := range t.Channels {
+		if channel != message.Channel {
+			channels = append(channels, channel)
+		}
+	}
+	t.Channels = channels
+
+	if len(t.View) > 0 && t.View != message.Channel {
+		t.Unread[message.Channel]++
+		if message.Highlight {
+			t.Highlighted[message.Channel] = true
+		}
+	}
+
+	t.Redraw()
+}
+
+func (t *Tui) SetStatus(status string) {
+	t.Status = strings.Replace(stripAnsi(status), "\n", " ", -1)
+	t.Redraw()
+}
+
+func (t *Tui) SetView(channel string) {
+	t.View = channel
+	t.Scroll = 0
+	delete(t.Unread, channel)
+	delete(t.Highlighted, channel)
+	t.Redraw()
+}
+
+// draw all except the input line
+func (t *Tui) Redraw() {
+	paneWidth := t.Width - TUI_SIDEBAR_WIDTH - 1
+	paneHeight := t.Height - 2
+	if paneWidth < 1 || paneHeight < 1 {
+		return
+	}
+
+	sidebar := t.sidebarLines(paneHeight)
+	pane := t.paneLines(paneWidth, paneHeight)
+
+	screen := strings.Builder{}
+	screen.WriteString("\0337") // save cursor in the input line
+	for row := 0; row < paneHeight; row++ {
+		fmt.Fprintf(&screen, "\033[%d;1H", row+1)
+		screen.WriteString(sidebar[row])
+		screen.WriteString("\033[0m│")
+		screen.WriteString(pane[row])
+		screen.WriteString("\033[0m\033[K")
+	}
+
+	status := time.Now().Format("15:04") + " " + t.Status
+	if t.Scroll > 0 {
+		status = fmt.Sprintf("%s [scrolled %d lines]", status, t.Scroll)
+	}
+	fmt.Fprintf(&screen, "\033[%d;1H\033[7m%s\033[0m", t.Height-1, fitText(status, t.Width))
+	screen.WriteString("\0338")
+
+	printConsole(screen.String())
+}
+
+// draw prompt (and the input line by keys)
+func (t *Tui) DrawPrompt() {
+	printConsole(fmt.Sprintf("\033[%d;1H\033[K> %s", t.Height, string(t.Input)))
+}
+
+func (t *Tui) sidebarLines(height int) []string {
+	lines := []string{}
+
+	item := func(name string, label string) string {
+		marker := " "
+		if name == t.View {
+			marker = ">"
+		}
+		count := ""
+		if t.Unread[name] > 0 {
+			count = strconv.Itoa(t.Unread[name])
+		}
+		style := ""
+		if t.Highlighted[name] {
+			style = "\033[95m"
+		} else if t.Unread[name] > 0 {
+			style = "\033[1m"
+		}
+		labelWidth := TUI_SIDEBAR_WIDTH - 1 - len(count)
+		return style + marker + fitText(label, labelWidth) + count
+	}
+
+	lines = append(lines, item("", "[all]"))
+	for _, channel := range t.Channels {
+		lines = append(lines, item(channel, "#"+channel))
+	}
+
+	for len(lines) < height {
+		lines = append(lines, strings.Repeat(" ", TUI_SIDEBAR_WIDTH))
+	}
+	return lines[:height]
+}
+
+// lines of messages to fill the pane
+func (t *Tui) paneLines(width int, height int) []string {
+	// each message has at least a line
+	messages := g_MessageStore.Latest(height+t.Scroll, func(stored *StoredMessage) bool {
+		return len(t.View) == 0 || stored.Channel == t.View
+	})
+
+	lines := []string{}
+	for i, stored := range messages {
+		lines = append(lines, t.formatMessage(stored, width, needsHeader(messages, i))...)
+	}
+
+	if t.Scroll > len(lines)-height {
+		t.Scroll = len(lines) - height
+	}
+	if t.Scroll < 0 {
+		t.Scroll = 0
+	}
+	lines = lines[:len(lines)-t.Scroll]
+
+	if len(lines) > height {
+		lines = lines[len(lines)-height:]
+	}
+	for len(lines) < height {
+		lines = append([]string{""}, lines...)
+	}
+	return lines
+}
+
+// whether messages[i] is from different channel, user or thread than the previous
+func needsHeader(messages []*StoredMessage, i int) bool {
+	return i == 0 ||
+		messages[i].Channel != messages[i-1].Channel ||
+		messages[i].User != messages[i-1].User ||
+		messages[i].ThreadTs != messages[i-1].ThreadTs
+}
+
+// format stored message into styled lines of width
+func (t *Tui) formatMessage(stored *StoredMessage, width int, showHeader bool) []string {
+	lines := []string{}
+
+	if showHeader {
+		header := "@" + stored.UserType + stored.User
+		if len(t.View) == 0 {
+			header = header + " #" + stored.Channel + sharedMarker(stored.ChannelId)
+		}
+		header = header + " " + parseTs(stored.Ts).Format("15:04:05")
+		if len(stored.ThreadTs) > 0 {
+			header = header + " [at " + parseTs(stored.ThreadTs).Format("15:04:05") + "]"
+		}
+		lines = append(lines, "\033[93m"+fitText(header, width))
+	}
+
+	text := truncateText(stripAnsi(stored.Text), g_Config.Display.MaxMessageLength)
+	text = shortenUrls(text, g_Config.Display.MaxUrlLength)
+	style := ""
+	switch {
+	case stored.Deleted:
+		style = "\033[9;90m"
+	case stored.Highlight:
+		style = "\033[95m"
+	}
+	if stored.Edited {
+		text = text + " (edited)"
+	}
+	for _, line := range wrapText(text, width) {
+		lines = append(lines, style+t.highlightSearch(fitText(line, width), style))
+	}
+
+	if len(stored.Reactions) > 0 {
+		reactions := []string{}
+		for _, reaction := range stored.Reactions {
+			reactions = append(reactions, fmt.Sprintf(":%s: %d", reaction.Name, len(reaction.Users)))
+		}
+		for _, line := range wrapText(strings.Join(reactions, "  "), width) {
+			lines = append(lines, "\033[90m"+fitText(line, width))
+		}
+	}
+
+	return lines
+}
+
+// display log in the status line
+type tuiLogWriter struct{}
+
+func (w tuiLogWriter) Write(data []byte) (int, error) {
+	if g_Tui != nil {
+		g_Tui.SetStatus(strings.TrimSpace(string(data)))
+	}
+	return len(data), nil
+}
+
+//==============================
+// /view
+//==============================
+
+func runView(args []string) error {
+	if g_Tui == nil {
+		return fmt.Errorf("/view is available in TUI (--tui)")
+	}
+	g_Tui.SetView(strings.TrimPrefix(firstArg(args), "#"))
+	return nil
+}
+
+//==============================
+// /page-up, /page-down, /bottom
+//==============================
+
+func (t *Tui) ScrollBy(lines int) {
+	t.Scroll += lines
+	if t.Scroll < 0 {
+		t.Scroll = 0
+	}
+	t.Redraw()
+}
+
+func (t *Tui) pageHeight() int {
+	// keep a line of the previous page
+	if t.Height > 4 {
+		return t.Height - 3
+	}
+	return 1
+}
+
+func runPageUp(args []string) error {
+	if g_Tui == nil {
+		return fmt.Errorf("/page-up is available in TUI (--tui)")
+	}
+	g_Tui.ScrollBy(g_Tui.pageHeight())
+	return nil
+}
+
+func runPageDown(args []string) error {
+	if g_Tui == nil {
+		return fmt.Errorf("/page-down is available in TUI (--tui)")
+	}
+	g_Tui.ScrollBy(-g_Tui.pageHeight())
+	return nil
+}
+
+func runBottom(args []string) error {
+	if g_Tui == nil {
+		return fmt.Errorf("/bottom is available in TUI (--tui)")
+	}
+	g_Tui.Scroll = 0
+	g_Tui.Redraw()
+	return nil
+}
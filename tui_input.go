@@ -0,0 +1,75 @@
+package main
+
+import "strings"
+
+//==============================
+// input line of TUI by key presses
+//==============================
+
+// edit the input line by key, and return the line when entered
+//
+// Special keys in g_KeyCommands are returned as lines at once, keeping the
+// input line.
+func (t *Tui) HandleKey(key string) (string, bool) {
+	switch {
+	case key == "\r" || key == "\n":
+		line := string(t.Input)
+		t.Input = nil
+		t.Incremental = false
+		return line, true
+	case key == "\x7f" || key == "\b":
+		if len(t.Input) > 0 {
+			t.Input = t.Input[:len(t.Input)-1]
+		}
+	case key == "\x15":
+		// Ctrl-U
+		t.Input = nil
+	case key == "\033":
+		// cancel input (and incremental search)
+		t.Input = nil
+	case strings.HasPrefix(key, "\033"):
+		if _, exist := g_KeyCommands[key]; exist {
+			return key, true
+		}
+		return "", false
+	default:
+		r := []rune(key)[0]
+		if r < 0x20 {
+			// other control keys
+			return "", false
+		}
+		t.Input = append(t.Input, r)
+	}
+
+	t.updateIncrementalSearch()
+	t.DrawPrompt()
+	return "", false
+}
+
+// search while "/pattern" is typed, and back to the previous scroll if cancelled
+func (t *Tui) updateIncrementalSearch() {
+	line := string(t.Input)
+	if !isSearchLine(line) && !strings.HasPrefix(line, "//") {
+		if t.Incremental {
+			t.Incremental = false
+			t.Search = nil
+			t.SearchMatch = MessageKey{}
+			t.Scroll = t.SearchOrigin
+			t.Redraw()
+		}
+		return
+	}
+
+	if !t.Incremental {
+		t.Incremental = true
+		t.SearchOrigin = t.Scroll
+	}
+	if err := t.setSearch(strings.TrimLeft(line, "/")); err != nil {
+		// incomplete while typing
+		return
+	}
+	if !t.FindNext(true) {
+		t.Scroll = t.SearchOrigin
+	}
+	t.Redraw()
+}
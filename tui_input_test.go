@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestTuiHandleKey(t *testing.T) {
+	g_MessageStore = NewMessageStore(10)
+	g_MessageStore.Add(Message{ChannelId: "C01", Channel: "dev", User: "alice", Ts: "1.000001", Text: "hello"})
+	g_MessageStore.Add(Message{ChannelId: "C01", Channel: "dev", User: "bob", Ts: "1.000002", Text: "world"})
+	g_Tui = &Tui{Width: 40, Height: 10}
+	defer func() { g_Tui = nil }()
+
+	for _, key := range []string{"/", "h", "e"} {
+		if _, entered := g_Tui.HandleKey(key); entered {
+			t.Errorf("unexpected entered by %q\n", key)
+		}
+	}
+	if g_Tui.Search == nil || g_Tui.SearchMatch.Ts != "1.000001" {
+		t.Errorf("expected incremental search to find \"hello\"\n")
+	}
+
+	// cancelled
+	g_Tui.HandleKey("\033")
+	if g_Tui.Search != nil || len(g_Tui.Input) != 0 {
+		t.Errorf("expected search cancelled\n")
+	}
+
+	if line, entered := g_Tui.HandleKey("\033[5~"); !entered || line != "\033[5~" {
+		t.Errorf("expected PageUp entered at once, but %q\n", line)
+	}
+
+	for _, key := range []string{"h", "i", "x", "\x7f"} {
+		g_Tui.HandleKey(key)
+	}
+	if line, entered := g_Tui.HandleKey("\r"); !entered || line != "hi" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "hi", line)
+	}
+}
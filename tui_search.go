@@ -0,0 +1,131 @@
+package main
+
+import "fmt"
+import "regexp"
+import "strings"
+
+//==============================
+// search in TUI scrollback
+//==============================
+
+func init() {
+	g_Commands["search-back"] = Command{"<regexp>", "search scrollback in TUI (shortcut: /regexp, then n or N)", runSearchBack}
+	g_Commands["search-next"] = Command{"", "find the next older match in TUI (shortcut: n)", runSearchNext}
+	g_Commands["search-prev"] = Command{"", "find the next newer match in TUI (shortcut: N)", runSearchPrev}
+	g_KeyCommands["n"] = "search-next"
+	g_KeyCommands["N"] = "search-prev"
+}
+
+// find message matching the search pattern, and scroll to it
+//
+// Call Redraw to display the result.
+//
+// Searches older messages than the current match if older is true, newer otherwise.
+func (t *Tui) FindNext(older bool) bool {
+	if t.Search == nil {
+		return false
+	}
+
+	messages := g_MessageStore.Latest(g_MessageStore.Len(), func(stored *StoredMessage) bool {
+		return len(t.View) == 0 || stored.Channel == t.View
+	})
+
+	// start from the current match, or the bottom
+	current := len(messages)
+	for i, stored := range messages {
+		if stored.Key() == t.SearchMatch {
+			current = i
+			break
+		}
+	}
+
+	step := -1
+	if !older {
+		step = 1
+	}
+	for i := current + step; 0 <= i && i < len(messages); i += step {
+		if t.Search.MatchString(stripAnsi(messages[i].Text)) {
+			t.SearchMatch = messages[i].Key()
+			t.scrollTo(messages, i)
+			return true
+		}
+	}
+	return false
+}
+
+// set pattern to search (case insensitive)
+func (t *Tui) setSearch(pattern string) error {
+	search, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return err
+	}
+	t.Search = search
+	t.SearchMatch = MessageKey{}
+	return nil
+}
+
+// scroll to show messages[index] at the bottom of the pane
+func (t *Tui) scrollTo(messages []*StoredMessage, index int) {
+	width := t.Width - TUI_SIDEBAR_WIDTH - 1
+	lines := 0
+	for i := index + 1; i < len(messages); i++ {
+		lines += len(t.formatMessage(messages[i], width, needsHeader(messages, i)))
+	}
+	t.Scroll = lines
+}
+
+// surround matches of the search pattern with reverse video
+//
+// style is restored after matches.
+func (t *Tui) highlightSearch(line string, style string) string {
+	if t.Search == nil {
+		return line
+	}
+	return t.Search.ReplaceAllStringFunc(line, func(match string) string {
+		return "\033[7m" + match + "\033[27m" + style
+	})
+}
+
+func runSearchBack(args []string) error {
+	if g_Tui == nil {
+		return fmt.Errorf("/search-back is available in TUI (--tui)")
+	}
+	if len(args) == 0 {
+		g_Tui.Search = nil
+		g_Tui.SearchMatch = MessageKey{}
+		g_Tui.Redraw()
+		return nil
+	}
+
+	if err := g_Tui.setSearch(strings.Join(args, " ")); err != nil {
+		return err
+	}
+	found := g_Tui.FindNext(true)
+	g_Tui.Redraw()
+	if !found {
+		return fmt.Errorf("pattern not found")
+	}
+	return nil
+}
+
+func runSearchNext(args []string) error {
+	if g_Tui == nil || g_Tui.Search == nil {
+		return fmt.Errorf("no search pattern")
+	}
+	if !g_Tui.FindNext(true) {
+		return fmt.Errorf("no more older matches")
+	}
+	g_Tui.Redraw()
+	return nil
+}
+
+func runSearchPrev(args []string) error {
+	if g_Tui == nil || g_Tui.Search == nil {
+		return fmt.Errorf("no search pattern")
+	}
+	if !g_Tui.FindNext(false) {
+		return fmt.Errorf("no more newer matches")
+	}
+	g_Tui.Redraw()
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import "strings"
+import "testing"
+
+func TestTuiPaneLines(t *testing.T) {
+	g_MessageStore = NewMessageStore(10)
+	g_MessageStore.Add(Message{ChannelId: "C01", Channel: "dev", User: "alice", Ts: "1600000000.000100", Text: "hello"})
+	g_MessageStore.Add(Message{ChannelId: "C02", Channel: "random", User: "bob", Ts: "1600000001.000100", Text: "hi"})
+	tui := &Tui{Width: 40, Height: 10, View: "dev"}
+
+	// empty line, header and body
+	lines := tui.paneLines(10, 3)
+	if len(lines) != 3 || lines[0] != "" || !strings.HasPrefix(lines[1], "\033[93m@alice ") || lines[2] != "hello     " {
+		t.Errorf("unexpected %q\n", lines)
+	}
+}
+
+func TestTuiScroll(t *testing.T) {
+	g_MessageStore = NewMessageStore(10)
+	for _, ts := range []string{"1.000001", "1.000002", "1.000003"} {
+		g_MessageStore.Add(Message{ChannelId: "C01", Channel: "dev", User: "alice", Ts: ts, Text: ts})
+	}
+	tui := &Tui{Width: 40, Height: 10, View: "dev", Scroll: 1}
+
+	lines := tui.paneLines(8, 2)
+	if lines[0] != "1.000001" || lines[1] != "1.000002" {
+		t.Errorf("unexpected %q\n", lines)
+	}
+
+	// can't scroll over the first line
+	tui.Scroll = 100
+	lines = tui.paneLines(8, 2)
+	if tui.Scroll != 2 || !strings.HasPrefix(lines[0], "\033[93m@alice ") {
+		t.Errorf("unexpected %q (scroll %d)\n", lines, tui.Scroll)
+	}
+}
+
+func TestTuiFindNext(t *testing.T) {
+	g_MessageStore = NewMessageStore(10)
+	for _, text := range []string{"deploy started", "lunch?", "deploy failed", "ok"} {
+		ts := "1.00000" + string(rune('0'+g_MessageStore.Len()))
+		g_MessageStore.Add(Message{ChannelId: "C01", Channel: "dev", User: "alice", Ts: ts, Text: text})
+	}
+	tui := &Tui{Width: 40, Height: 10}
+
+	if err := tui.setSearch("DEPLOY"); err != nil {
+		t.Fatal(err)
+	}
+	if !tui.FindNext(true) || tui.SearchMatch.Ts != "1.000002" || tui.Scroll != 1 {
+		t.Errorf("unexpected match %+v (scroll %d)\n", tui.SearchMatch, tui.Scroll)
+	}
+	if !tui.FindNext(true) || tui.SearchMatch.Ts != "1.000000" {
+		t.Errorf("unexpected match %+v\n", tui.SearchMatch)
+	}
+	if tui.FindNext(true) {
+		t.Errorf("expected no more matches\n")
+	}
+	if !tui.FindNext(false) || tui.SearchMatch.Ts != "1.000002" {
+		t.Errorf("unexpected match %+v\n", tui.SearchMatch)
+	}
+
+	expected := "a \033[7mdeploy\033[27m\033[95m b"
+	result := tui.highlightSearch("a deploy b", "\033[95m")
+	if result != expected {
+		t.Errorf("expected %q, but %q\n", expected, result)
+	}
+}
+
+func TestTuiResize(t *testing.T) {
+	g_MessageStore = NewMessageStore(10)
+	g_MessageStore.Add(Message{ChannelId: "C01", Channel: "dev", User: "alice", Ts: "1.000001", Text: "0123456789"})
+	tui := &Tui{Width: 40, Height: 10, View: "dev"}
+
+	tui.Resize(TUI_SIDEBAR_WIDTH+1+5, 6)
+	if tui.Width != TUI_SIDEBAR_WIDTH+1+5 || tui.Height != 6 {
+		t.Errorf("unexpected size %dx%d\n", tui.Width, tui.Height)
+	}
+
+	// wrapped at the new pane width
+	lines := tui.paneLines(tui.Width-TUI_SIDEBAR_WIDTH-1, 2)
+	if lines[0] != "01234" || lines[1] != "56789" {
+		t.Errorf("unexpected %q\n", lines)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import "fmt"
+import "log"
+import "net/url"
+import "strings"
+import "sync"
+
+//==============================
+// unread summary
+//==============================
+
+// @see https://api.slack.com/methods/users.conversations
+type SlackUsersConversationsResponse struct {
+	Ok               bool
+	Channels         []SlackChannel
+	ResponseMetadata SlackResponseMetadata `json:"response_metadata"`
+}
+
+// summary is displayed only on the first connection
+var g_UnreadSummaryDone = false
+
+func init() {
+	g_Commands["unread"] = Command{"", "display unread messages", runUnread}
+}
+
+// list channels which I'm a member of
+func listMyChannels() ([]SlackChannel, error) {
+	channels := []SlackChannel{}
+	cursor := ""
+	for {
+		query := url.Values{}
+		query.Set("limit", "1000")
+		query.Set("cursor", cursor)
+		query.Set("types", "public_channel,private_channel,mpim,im")
+		query.Set("exclude_archived", "true")
+
+		conversationsResponse := SlackUsersConversationsResponse{}
+		if err := callApi("users.conversations", query, &conversationsResponse); err != nil {
+			return nil, err
+		}
+		channels = append(channels, conversationsResponse.Channels...)
+
+		cursor = conversationsResponse.ResponseMetadata.NextCursor
+		if len(cursor) == 0 {
+			return channels, nil
+		}
+	}
+}
+
+// get channels having unread messages
+//
+// users.conversations has no unread counts, so conversations.info is called per channel
+// by RESOLVER_WORKERS in parallel.
+func listUnreadChannels() ([]SlackChannel, error) {
+	channels, err := listMyChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]SlackChannel, len(channels))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < RESOLVER_WORKERS; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				query := url.Values{}
+				query.Set("channel", channels[index].Id)
+
+				conversationResponse := SlackConversationsInfoResponse{}
+				if err := callApi("conversations.info", query, &conversationResponse); err != nil {
+					log.Printf("%s: %s", channels[index].Id, err)
+					continue
+				}
+				infos[index] = conversationResponse.Channel
+			}
+		}()
+	}
+	for index := range channels {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	// in the order of users.conversations
+	unreadChannels := []SlackChannel{}
+	for _, info := range infos {
+		if info.UnreadCountDisplay > 0 {
+			unreadChannels = append(unreadChannels, info)
+		}
+	}
+	return unreadChannels, nil
+}
+
+func formatUnreadSummary(channels []SlackChannel) string {
+	summaries := []string{}
+	for _, channel := range channels {
+		summaries = append(summaries, fmt.Sprintf(
+			"#%s: %d unread",
+			getChannel(channel.Id),
+			channel.UnreadCountDisplay,
+		))
+	}
+	return strings.Join(summaries, ", ")
+}
+
+// unread messages of channels (oldest first)
+func fetchUnreadMessages(channels []SlackChannel) []map[string]interface{} {
+	unreadMessages := []map[string]interface{}{}
+	for _, channel := range channels {
+		messages, err := fetchHistory(channel.Id, channel.LastRead, CATCH_UP_LIMIT)
+		if err != nil {
+			log.Printf("%s: %s", channel.Id, err)
+			continue
+		}
+
+		for i := len(messages) - 1; i >= 0; i-- {
+			msg := messages[i]
+			msg["channel"] = channel.Id
+			unreadMessages = append(unreadMessages, msg)
+		}
+	}
+	return unreadMessages
+}
+
+// display summary (and messages if fetch) of unread channels
+func printUnread(channels []SlackChannel, messages []map[string]interface{}) {
+	if len(channels) == 0 {
+		printStatus("No unread messages")
+		return
+	}
+
+	printStatus(formatUnreadSummary(channels))
+	for _, msg := range messages {
+		onMessage(msg)
+	}
+}
+
+// summary on connected, fetched in background not to block the receiving loop
+func showUnreadSummary() {
+	if !g_Config.General.UnreadSummary && !g_Config.General.FetchUnread {
+		return
+	}
+
+	fetch := g_Config.General.FetchUnread
+	go func() {
+		channels, err := listUnreadChannels()
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		messages := []map[string]interface{}{}
+		if fetch {
+			messages = fetchUnreadMessages(channels)
+		}
+
+		// caches are updated only by the receiving loop
+		g_Resolver.Resolved <- func() {
+			printUnread(channels, messages)
+		}
+	}()
+}
+
+//==============================
+// /unread
+//==============================
+
+func runUnread(args []string) error {
+	channels, err := listUnreadChannels()
+	if err != nil {
+		return err
+	}
+
+	printUnread(channels, fetchUnreadMessages(channels))
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import "net/http"
+import "net/url"
+import "testing"
+
+func TestListUnreadChannels(t *testing.T) {
+	savedClient := g_HttpClient
+	defer func() { g_HttpClient = savedClient }()
+
+	unreadCounts := map[string]string{"C01": "0", "C02": "3", "C03": "1", "C04": "0", "C05": "2"}
+	g_HttpClient = &http.Client{Transport: apiTransport(func(method string, query url.Values) string {
+		switch method {
+		case "users.conversations":
+			return `{"ok":true,"channels":[{"id":"C01"},{"id":"C02"},{"id":"C03"},{"id":"C04"},{"id":"C05"},{"id":"C06"}]}`
+		case "conversations.info":
+			id := query.Get("channel")
+			if count, exist := unreadCounts[id]; exist {
+				return `{"ok":true,"channel":{"id":"` + id + `","unread_count_display":` + count + `}}`
+			}
+		}
+		return `{"ok":false,"error":"channel_not_found"}`
+	})}
+
+	channels, err := listUnreadChannels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := ""
+	for _, channel := range channels {
+		ids = ids + channel.Id + " "
+	}
+	if ids != "C02 C03 C05 " {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "C02 C03 C05 ", ids)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import "embed"
+import "encoding/json"
+import "log"
+import "net"
+import "net/http"
+import "net/url"
+import "strings"
+import "sync"
+
+import "golang.org/x/net/websocket"
+
+//==============================
+// embedded web UI
+//==============================
+
+// messages buffered for each browser, which is disconnected if it falls behind
+const WEB_CLIENT_BUFFER = 256
+
+// max messages returned by /search (newest ones)
+const WEB_SEARCH_LIMIT = 200
+
+// token required to listen other than loopback
+const WEB_TOKEN_ENV = "SLACKV_WEB_TOKEN"
+
+// cookie of the token set by ?token=
+const WEB_TOKEN_COOKIE = "slackv_token"
+
+//go:embed web
+var g_WebAssets embed.FS
+
+var g_WebServer *WebServer
+
+type webClient struct {
+	messages chan JsonlMessage
+	channel  string // only messages in the channel if not empty
+}
+
+// local HTTP server of the web UI
+type WebServer struct {
+	listener     net.Listener
+	mux          *http.ServeMux
+	listenHost   string // host name of [web] listen, also accepted in Host header
+	token        string // no authentication if empty
+	archivePath  string
+	feedChannels []string
+	mutex        sync.Mutex
+	clients      map[*webClient]struct{}
+}
+
+func newWebServer(archivePath string, feedChannels []string) *WebServer {
+	s := &WebServer{mux: http.NewServeMux(), archivePath: archivePath, clients: map[*webClient]struct{}{}}
+	for _, channel := range feedChannels {
+		s.feedChannels = append(s.feedChannels, strings.TrimPrefix(channel, "#"))
+	}
+	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.Handle("/stream", websocket.Server{Handshake: checkSameOrigin, Handler: s.stream})
+	s.mux.HandleFunc("/search", s.handleSearch)
+	s.mux.HandleFunc("/feed/", s.handleFeed)
+	return s
+}
+
+// serve the web UI at address in background
+func startWebServer(address string, token string) (*WebServer, error) {
+	address, err := localListenAddress(address, token, WEB_TOKEN_ENV)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newWebServer(g_Config.Archive.Path, g_Config.Web.FeedChannels)
+	s.listener = listener
+	s.listenHost, _, _ = net.SplitHostPort(address)
+	s.token = token
+	go func() {
+		if err := http.Serve(listener, s); err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
+			log.Print(err)
+		}
+	}()
+	return s, nil
+}
+
+func (s *WebServer) Close() {
+	s.listener.Close()
+}
+
+// reject requests for other host names or without token in every handler
+func (s *WebServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.isAllowedHost(r.Host) {
+		http.Error(w, "invalid host", http.StatusMisdirectedRequest)
+		return
+	}
+	if !s.isAuthorized(w, r) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// whether r has token by "Authorization: Bearer <token>", the cookie or ?token=
+//
+// ?token= sets the cookie, so that the page can open /stream and /search.
+func (s *WebServer) isAuthorized(w http.ResponseWriter, r *http.Request) bool {
+	if len(s.token) == 0 || equalsToken(r.Header.Get("Authorization"), "Bearer "+s.token) {
+		return true
+	}
+	if cookie, err := r.Cookie(WEB_TOKEN_COOKIE); err == nil && equalsToken(cookie.Value, s.token) {
+		return true
+	}
+	if equalsToken(r.URL.Query().Get("token"), s.token) {
+		http.SetCookie(w, &http.Cookie{Name: WEB_TOKEN_COOKIE, Value: s.token, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		return true
+	}
+	return false
+}
+
+// whether host is loopback, an IP address or of [web] listen
+//
+// Names of other sites are rejected against DNS rebinding, in which a page
+// makes its own name resolve to 127.0.0.1 and passes the same origin checks.
+func (s *WebServer) isAllowedHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if host == "localhost" || net.ParseIP(host) != nil {
+		return true
+	}
+	return len(s.listenHost) > 0 && host == strings.ToLower(s.listenHost)
+}
+
+func (s *WebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	page, err := g_WebAssets.ReadFile("web/index.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// reject websocket from other sites not to leak messages to any page opened in the browser
+func checkSameOrigin(config *websocket.Config, r *http.Request) error {
+	origin, err := url.Parse(r.Header.Get("Origin"))
+	if err != nil || origin.Host != r.Host {
+		return websocket.ErrBadWebSocketOrigin
+	}
+	config.Origin = origin
+	return nil
+}
+
+// send messages to a browser as JSON until disconnected
+func (s *WebServer) stream(ws *websocket.Conn) {
+	defer ws.Close()
+
+	client := &webClient{
+		messages: make(chan JsonlMessage, WEB_CLIENT_BUFFER),
+		channel:  strings.TrimPrefix(ws.Request().URL.Query().Get("channel"), "#"),
+	}
+	s.mutex.Lock()
+	s.clients[client] = struct{}{}
+	s.mutex.Unlock()
+	defer s.remove(client)
+
+	// detect closing by the browser
+	closed := make(chan struct{})
+	go func() {
+		var discarded string
+		for websocket.Message.Receive(ws, &discarded) == nil {
+		}
+		close(closed)
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.messages:
+			if !ok {
+				// too slow
+				return
+			}
+			if err := websocket.JSON.Send(ws, message); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (s *WebServer) remove(client *webClient) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exist := s.clients[client]; exist {
+		delete(s.clients, client)
+		close(client.messages)
+	}
+}
+
+// send message to browsers
+func (s *WebServer) Broadcast(message Message) {
+	jsonl := newJsonlMessage(message)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for client := range s.clients {
+		if len(client.channel) > 0 && client.channel != jsonl.Channel {
+			continue
+		}
+		select {
+		case client.messages <- jsonl:
+		default:
+			// not to block the receiving loop by a slow browser
+			delete(s.clients, client)
+			close(client.messages)
+		}
+	}
+}
+
+// search the archive by ?q=words&channel=&user=&since=
+func (s *WebServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if len(s.archivePath) == 0 {
+		http.Error(w, "[archive] path is not configured", http.StatusNotFound)
+		return
+	}
+
+	params := r.URL.Query()
+	query := ArchiveQuery{
+		Words:   strings.Fields(params.Get("q")),
+		Channel: params.Get("channel"),
+		User:    params.Get("user"),
+	}
+	if since := params.Get("since"); len(since) > 0 {
+		var err error
+		if query.Since, err = parseSince(since); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	matches := []JsonlMessage{}
+	err := readArchive(s.archivePath, func(archived JsonlMessage) bool {
+		if query.Match(archived) {
+			matches = append(matches, archived)
+			if len(matches) > WEB_SEARCH_LIMIT {
+				matches = matches[1:]
+			}
+		}
+		return true
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(matches)
+}
@@ -0,0 +1,167 @@
+package main
+
+import "encoding/json"
+import "net/http"
+import "net/http/httptest"
+import "os"
+import "path/filepath"
+import "strings"
+import "testing"
+import "time"
+
+import "golang.org/x/net/websocket"
+
+func TestWebServerStream(t *testing.T) {
+	s := newWebServer("", nil)
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http") + "/stream?channel=dev"
+	ws, err := websocket.Dial(wsUrl, "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	// wait for the client registered
+	for i := 0; i < 100; i++ {
+		s.mutex.Lock()
+		count := len(s.clients)
+		s.mutex.Unlock()
+		if count > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.Broadcast(Message{Channel: "general", User: "bob", Ts: "1600000000.000100", Text: "filtered"})
+	s.Broadcast(Message{Channel: "dev", User: "alice", Ts: "1600000000.000200", Text: "hello"})
+
+	received := JsonlMessage{}
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if err := websocket.JSON.Receive(ws, &received); err != nil {
+		t.Fatal(err)
+	}
+	if received.Text != "hello" {
+		t.Errorf("expected \"%s\", but \"%s\"\n", "hello", received.Text)
+	}
+}
+
+func TestWebServerRejectsOtherOrigin(t *testing.T) {
+	server := httptest.NewServer(newWebServer("", nil))
+	defer server.Close()
+
+	wsUrl := "ws" + strings.TrimPrefix(server.URL, "http") + "/stream"
+	if ws, err := websocket.Dial(wsUrl, "", "http://evil.example.com"); err == nil {
+		ws.Close()
+		t.Errorf("expected error for other origin\n")
+	}
+}
+
+func TestWebServerRejectsOtherHost(t *testing.T) {
+	s := newWebServer("", nil)
+	s.listenHost = "slackv.local"
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	cases := []struct {
+		host     string
+		expected bool // rejected
+	}{
+		{"evil.example.com", true},
+		{"evil.example.com:8978", true},
+		{"localhost:8978", false},
+		{"[::1]:8978", false},
+		{"slackv.local:8978", false},
+	}
+	for _, c := range cases {
+		for _, path := range []string{"/", "/search?q=deploy"} {
+			request, _ := http.NewRequest("GET", server.URL+path, nil)
+			request.Host = c.host
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if rejected := response.StatusCode == http.StatusMisdirectedRequest; rejected != c.expected {
+				t.Errorf("%s%s: expected rejected %v, but status %d\n", c.host, path, c.expected, response.StatusCode)
+			}
+		}
+	}
+}
+
+func TestWebServerSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.jsonl")
+	lines := `{"ts":"1600000000.000100","channel":"general","user":"alice","text":"Deploy failed"}
+{"ts":"1600000000.000200","channel":"dev","user":"bob","text":"deploy done"}
+`
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(newWebServer(path, nil))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/search?q=deploy&channel=dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	matches := []JsonlMessage{}
+	if err := json.NewDecoder(response.Body).Decode(&matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].User != "bob" {
+		t.Errorf("unexpected matches %v\n", matches)
+	}
+}
+
+func TestWebServerToken(t *testing.T) {
+	s := newWebServer("", nil)
+	s.token = "secret"
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	cases := []struct {
+		path     string
+		header   string
+		expected int
+	}{
+		{"/", "", http.StatusUnauthorized},
+		{"/?token=wrong", "", http.StatusUnauthorized},
+		{"/", "Bearer wrong", http.StatusUnauthorized},
+		{"/", "Bearer secret", http.StatusOK},
+		{"/?token=secret", "", http.StatusOK},
+	}
+	for _, c := range cases {
+		request, _ := http.NewRequest("GET", server.URL+c.path, nil)
+		if len(c.header) > 0 {
+			request.Header.Set("Authorization", c.header)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != c.expected {
+			t.Errorf("%s %s: expected status %d, but %d\n", c.path, c.header, c.expected, response.StatusCode)
+		}
+
+		// the cookie by ?token= authorizes later requests
+		if c.expected == http.StatusOK && len(c.header) == 0 {
+			request, _ = http.NewRequest("GET", server.URL+"/", nil)
+			for _, cookie := range response.Cookies() {
+				request.AddCookie(cookie)
+			}
+			response, err = http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("expected status %d by cookie, but %d\n", http.StatusOK, response.StatusCode)
+			}
+		}
+	}
+}